@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myproject/secrets"
+	"os"
+	"strings"
+	"time"
+)
+
+// keyStaleAfter is how long a validated key is trusted before it is reported as stale.
+const keyStaleAfter = 30 * 24 * time.Hour
+
+// keyCheckTimeout bounds the provider request used to validate a key.
+const keyCheckTimeout = 30 * time.Second
+
+// Key states reported by GetProviderStatus.
+const (
+	KeyStateMissing    = "missing"
+	KeyStateLocked     = "locked"
+	KeyStateUnverified = "unverified"
+	KeyStateValid      = "valid"
+	KeyStateStale      = "stale"
+	KeyStateBroken     = "broken"
+)
+
+// ProviderKeyStatus is the persisted outcome of the latest key checks for a provider.
+type ProviderKeyStatus struct {
+	ValidatedAt *time.Time `json:"validated_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// ProviderStatus describes the saved key of a key-based provider.
+type ProviderStatus struct {
+	Provider    string     `json:"provider"`
+	HasKey      bool       `json:"has_key"`
+	State       string     `json:"state"`
+	ValidatedAt *time.Time `json:"validated_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// GetProviderStatus reports, for every provider that needs an API key,
+// whether a key is saved and whether it is valid, stale or broken.
+func (a *App) GetProviderStatus() []ProviderStatus {
+	statuses := []ProviderStatus{}
+	for _, info := range a.registry.List() {
+		if info.Capabilities.RequiresAPIKey {
+			statuses = append(statuses, a.providerStatus(info.Name))
+		}
+	}
+	return statuses
+}
+
+// ValidateAPIKey re-checks a provider's saved key against its API.
+func (a *App) ValidateAPIKey(provider string) (ProviderStatus, error) {
+	apiKey, err := a.GetAPIKey(provider)
+	if err != nil {
+		return a.providerStatus(provider), err
+	}
+	err = a.checkAPIKey(provider, apiKey)
+	a.recordKeyCheck(provider, err)
+	return a.providerStatus(provider), err
+}
+
+// storeAPIKey is the single path through which API keys are saved. It
+// validates the key against the provider when validate is set, persists it in
+// the secret store, reads it back to verify the round trip and records the
+// outcome. An empty key removes the saved key.
+func (a *App) storeAPIKey(provider string, apiKey string, validate bool) error {
+	info, ok := a.registry.Info(provider)
+	if !ok || !info.Capabilities.RequiresAPIKey {
+		return fmt.Errorf("unsupported provider: %s", provider)
+	}
+	store, err := a.secretStore()
+	if err != nil {
+		return err
+	}
+
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		if err := store.Delete(apiKeySecret(provider)); err != nil {
+			return fmt.Errorf("failed to remove API key for %s: %w", provider, err)
+		}
+		a.configMutex.Lock()
+		delete(a.cloudAPIKeys, provider)
+		delete(a.keyStatus, provider)
+		a.configMutex.Unlock()
+		a.modelCache.Invalidate(provider)
+		return a.saveConfig()
+	}
+
+	if validate {
+		if err := a.checkAPIKey(provider, apiKey); err != nil {
+			a.recordKeyCheck(provider, err)
+			return err
+		}
+	}
+
+	previous, _ := store.Get(apiKeySecret(provider))
+	if err := store.Set(apiKeySecret(provider), apiKey); err != nil {
+		err = fmt.Errorf("failed to save API key for %s: %w", provider, err)
+		a.recordKeyCheck(provider, err)
+		return err
+	}
+	if stored, err := store.Get(apiKeySecret(provider)); err != nil || stored != apiKey {
+		err = fmt.Errorf("API key for %s could not be read back after saving", provider)
+		a.recordKeyCheck(provider, err)
+		return err
+	}
+
+	a.configMutex.Lock()
+	// Drop any copy still waiting in config.json from before the secret store.
+	delete(a.cloudAPIKeys, provider)
+	if validate {
+		now := time.Now()
+		a.keyStatus[provider] = ProviderKeyStatus{ValidatedAt: &now}
+	} else if previous != apiKey {
+		delete(a.keyStatus, provider)
+	}
+	a.configMutex.Unlock()
+	if previous != apiKey {
+		a.modelCache.Invalidate(provider)
+	}
+	return a.saveConfig()
+}
+
+// checkAPIKey runs the provider's health check with the given key.
+func (a *App) checkAPIKey(provider string, apiKey string) error {
+	p, err := a.newCloudProvider(provider, apiKey)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keyCheckTimeout)
+	defer cancel()
+	if err := p.HealthCheck(ctx); err != nil {
+		return fmt.Errorf("could not verify the API key for %s: %v", provider, err)
+	}
+	return nil
+}
+
+// recordKeyCheck saves the outcome of a key check for GetProviderStatus.
+func (a *App) recordKeyCheck(provider string, checkErr error) {
+	now := time.Now()
+	a.configMutex.Lock()
+	status := a.keyStatus[provider]
+	if checkErr == nil {
+		status.ValidatedAt = &now
+		status.LastError = ""
+		status.LastErrorAt = nil
+	} else {
+		status.LastError = checkErr.Error()
+		status.LastErrorAt = &now
+	}
+	a.keyStatus[provider] = status
+	a.configMutex.Unlock()
+
+	if err := a.saveConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving key status for %s: %v\n", provider, err)
+	}
+}
+
+// providerStatus combines the saved key and its recorded checks into a state.
+func (a *App) providerStatus(provider string) ProviderStatus {
+	a.configMutex.RLock()
+	recorded := a.keyStatus[provider]
+	legacy := a.cloudAPIKeys[provider]
+	a.configMutex.RUnlock()
+
+	status := ProviderStatus{
+		Provider:    provider,
+		ValidatedAt: recorded.ValidatedAt,
+		LastError:   recorded.LastError,
+		LastErrorAt: recorded.LastErrorAt,
+	}
+	_, err := a.getSecret(apiKeySecret(provider), legacy)
+	status.HasKey = err == nil
+
+	switch {
+	case errors.Is(err, secrets.ErrLocked):
+		status.State = KeyStateLocked
+	case err != nil:
+		status.State = KeyStateMissing
+	case recorded.LastErrorAt != nil && (recorded.ValidatedAt == nil || recorded.LastErrorAt.After(*recorded.ValidatedAt)):
+		status.State = KeyStateBroken
+	case recorded.ValidatedAt == nil:
+		status.State = KeyStateUnverified
+	case time.Since(*recorded.ValidatedAt) > keyStaleAfter:
+		status.State = KeyStateStale
+	default:
+		status.State = KeyStateValid
+	}
+	return status
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myproject/apiserver"
+	"myproject/connectors"
+	"myproject/secrets"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultAPIServerPort is used when the API server is started without a port.
+const DefaultAPIServerPort = 5271
+
+// APIServerConfig is the persisted state of the OpenAI-compatible API server.
+// The token lives in the secret store; Token only holds a legacy encrypted
+// copy until it has been migrated.
+type APIServerConfig struct {
+	Enabled bool   `json:"enabled"`
+	Port    int    `json:"port,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// APIServerStatus describes the API server for the settings UI.
+type APIServerStatus struct {
+	Enabled bool   `json:"enabled"`
+	Running bool   `json:"running"`
+	Port    int    `json:"port"`
+	BaseURL string `json:"base_url,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// GetAPIServerStatus reports whether the API server is running and how to reach it.
+func (a *App) GetAPIServerStatus() (APIServerStatus, error) {
+	a.configMutex.RLock()
+	config := a.apiServerConfig
+	a.configMutex.RUnlock()
+
+	status := APIServerStatus{
+		Enabled: config.Enabled,
+		Port:    config.Port,
+	}
+	if status.Port == 0 {
+		status.Port = DefaultAPIServerPort
+	}
+	token, err := a.getSecret(apiServerTokenSecret, config.Token)
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return status, fmt.Errorf("failed to read API server token: %v", err)
+	}
+	status.Token = token
+
+	a.apiServerMutex.Lock()
+	defer a.apiServerMutex.Unlock()
+	if a.apiServer != nil && a.apiServer.Addr() != "" {
+		status.Running = true
+		status.BaseURL = "http://" + a.apiServer.Addr() + "/v1"
+	}
+	return status, nil
+}
+
+// StartAPIServer enables the OpenAI-compatible API server on localhost and
+// remembers the choice. A port of 0 keeps the saved or default port.
+func (a *App) StartAPIServer(port int) (APIServerStatus, error) {
+	token, err := a.apiServerToken()
+	if err != nil {
+		return APIServerStatus{}, err
+	}
+
+	a.configMutex.Lock()
+	if port == 0 {
+		port = a.apiServerConfig.Port
+	}
+	if port == 0 {
+		port = DefaultAPIServerPort
+	}
+	a.configMutex.Unlock()
+
+	a.apiServerMutex.Lock()
+	if a.apiServer != nil {
+		a.apiServer.Stop()
+	}
+	a.apiServer = apiserver.New(apiBackend{app: a}, token)
+	err = a.apiServer.Start(port)
+	a.apiServerMutex.Unlock()
+	if err != nil {
+		return APIServerStatus{}, err
+	}
+
+	a.configMutex.Lock()
+	a.apiServerConfig.Enabled = true
+	a.apiServerConfig.Port = port
+	a.configMutex.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return APIServerStatus{}, err
+	}
+	return a.GetAPIServerStatus()
+}
+
+// StopAPIServer stops the API server and keeps it off on the next launch.
+func (a *App) StopAPIServer() error {
+	a.apiServerMutex.Lock()
+	var err error
+	if a.apiServer != nil {
+		err = a.apiServer.Stop()
+		a.apiServer = nil
+	}
+	a.apiServerMutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to stop API server: %w", err)
+	}
+
+	a.configMutex.Lock()
+	a.apiServerConfig.Enabled = false
+	a.configMutex.Unlock()
+	return a.saveConfig()
+}
+
+// RegenerateAPIServerToken replaces the API server token, invalidating the old one.
+func (a *App) RegenerateAPIServerToken() (APIServerStatus, error) {
+	token, err := apiserver.NewToken()
+	if err != nil {
+		return APIServerStatus{}, err
+	}
+	store, err := a.secretStore()
+	if err != nil {
+		return APIServerStatus{}, err
+	}
+	if err := store.Set(apiServerTokenSecret, token); err != nil {
+		return APIServerStatus{}, fmt.Errorf("failed to save API server token: %w", err)
+	}
+
+	a.configMutex.Lock()
+	legacy := a.apiServerConfig.Token != ""
+	a.apiServerConfig.Token = ""
+	a.configMutex.Unlock()
+	if legacy {
+		if err := a.saveConfig(); err != nil {
+			return APIServerStatus{}, err
+		}
+	}
+
+	a.apiServerMutex.Lock()
+	if a.apiServer != nil {
+		a.apiServer.SetToken(token)
+	}
+	a.apiServerMutex.Unlock()
+	return a.GetAPIServerStatus()
+}
+
+// startAPIServerIfEnabled restores the API server on launch.
+func (a *App) startAPIServerIfEnabled() {
+	a.configMutex.RLock()
+	enabled := a.apiServerConfig.Enabled
+	a.configMutex.RUnlock()
+	if !enabled {
+		return
+	}
+	if _, err := a.StartAPIServer(0); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not start API server: %v\n", err)
+	}
+}
+
+// apiServerToken returns the saved API server token, generating one on first use.
+func (a *App) apiServerToken() (string, error) {
+	a.configMutex.RLock()
+	legacy := a.apiServerConfig.Token
+	a.configMutex.RUnlock()
+
+	token, err := a.getSecret(apiServerTokenSecret, legacy)
+	if errors.Is(err, secrets.ErrNotFound) {
+		status, err := a.RegenerateAPIServerToken()
+		if err != nil {
+			return "", err
+		}
+		return status.Token, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read API server token: %v", err)
+	}
+	return token, nil
+}
+
+// apiBackend routes API server requests through the App's providers, keys
+// and model configs.
+type apiBackend struct {
+	app *App
+}
+
+// Models lists the models of every chat-capable provider that is reachable
+// and, for cloud providers, has a saved key, using cached lists while they
+// are fresh. Unavailable providers are skipped.
+func (b apiBackend) Models(ctx context.Context) ([]apiserver.Model, error) {
+	var providers []string
+	for _, info := range b.app.registry.List() {
+		if info.Capabilities.Chat {
+			providers = append(providers, info.Name)
+		}
+	}
+
+	results := make([][]apiserver.Model, len(providers))
+	var wg sync.WaitGroup
+	for i, name := range providers {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			models, err := b.app.listModels(listCtx, name, false)
+			if err != nil {
+				fmt.Printf("API server: skipping %s models: %v\n", name, err)
+				return
+			}
+			for _, model := range models {
+				results[i] = append(results[i], apiserver.Model{ID: name + "/" + model.Name, Provider: name})
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	var all []apiserver.Model
+	for _, models := range results {
+		all = append(all, models...)
+	}
+	return all, nil
+}
+
+// Chat runs a completion with the model's preset and saved config, the latter
+// overridden by any parameters set on the request. Providers that cannot stream deliver the
+// whole response as a single delta. Errors in the request itself and unknown
+// providers are marked for the server to answer with 4xx.
+func (b apiBackend) Chat(ctx context.Context, request apiserver.ChatRequest, onDelta connectors.StreamHandler) (string, error) {
+	chat := ChatRequest{
+		Provider: request.Provider,
+		Model:    request.Model,
+		Messages: request.Messages,
+	}
+	if err := validateChatRequest(chat); err != nil {
+		return "", apiserver.InvalidRequest(err)
+	}
+	info, err := b.checkProvider(chat)
+	if err != nil {
+		return "", err
+	}
+	chat, err = b.app.applySystemPrompt(chat)
+	if err != nil {
+		return "", err
+	}
+	if chat.Messages, err = connectors.PrepareImages(chat.Messages); err != nil {
+		return "", apiserver.InvalidRequest(err)
+	}
+
+	// Check the parameters before the provider is contacted
+	saved, err := b.app.GetModelConfig(chat.Provider, chat.Model)
+	if err != nil {
+		return "", err
+	}
+	config := saved.generationConfig()
+	if request.Overrides.Temperature != nil {
+		config.Temperature = *request.Overrides.Temperature
+	}
+	if request.Overrides.TopP != nil {
+		config.TopP = *request.Overrides.TopP
+	}
+	if request.Overrides.Stop != nil {
+		config.Stop = request.Overrides.Stop
+	}
+	if request.Overrides.MaxOutputTokens != nil {
+		config.MaxOutputTokens = *request.Overrides.MaxOutputTokens
+	}
+	if request.Overrides.Seed != nil {
+		config.Seed = request.Overrides.Seed
+	}
+	if request.Overrides.PresencePenalty != nil {
+		config.PresencePenalty = *request.Overrides.PresencePenalty
+	}
+	if request.Overrides.FrequencyPenalty != nil {
+		config.FrequencyPenalty = *request.Overrides.FrequencyPenalty
+	}
+	if err := connectors.ValidateConfig(info.Parameters, config); err != nil {
+		return "", apiserver.InvalidRequest(err)
+	}
+
+	p, _, err := b.app.resolveChat(ctx, chat)
+	if err != nil {
+		return "", err
+	}
+	options := p.MapConfig(config)
+
+	if onDelta != nil && p.Capabilities().Streaming {
+		return p.StreamChat(ctx, chat.Model, chat.Messages, options, onDelta)
+	}
+	response, err := p.Chat(ctx, chat.Model, chat.Messages, options)
+	if err != nil {
+		return "", err
+	}
+	if onDelta != nil {
+		onDelta(response)
+	}
+	return response, nil
+}
+
+// checkProvider rejects providers that are unknown or cannot take the request.
+func (b apiBackend) checkProvider(chat ChatRequest) (connectors.ProviderInfo, error) {
+	info, ok := b.app.registry.Info(chat.Provider)
+	if !ok {
+		return info, apiserver.NotFound(fmt.Errorf("unknown provider %s", chat.Provider))
+	}
+	if !info.Capabilities.Chat {
+		return info, apiserver.InvalidRequest(fmt.Errorf("chat is not supported for %s", chat.Provider))
+	}
+	if !info.Capabilities.Images && hasImages(chat.Messages) {
+		return info, apiserver.InvalidRequest(fmt.Errorf("%s cannot receive images", chat.Provider))
+	}
+	return info, nil
+}
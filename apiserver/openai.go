@@ -0,0 +1,317 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"myproject/connectors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRequestBytes bounds the size of a chat completion request body.
+const maxRequestBytes = 32 << 20
+
+type modelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type modelList struct {
+	Object string        `json:"object"`
+	Data   []modelObject `json:"data"`
+}
+
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+// messageContent accepts either a plain string or an array of text and
+// image_url parts. Images must be inline data: URLs.
+type messageContent struct {
+	text   string
+	images []connectors.ImagePart
+}
+
+func (c *messageContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		c.text = text
+		return nil
+	}
+	var parts []contentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content must be a string or an array of parts")
+	}
+	var texts []string
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			texts = append(texts, part.Text)
+		case "image_url":
+			if part.ImageURL == nil || !strings.HasPrefix(part.ImageURL.URL, "data:") {
+				return fmt.Errorf("image_url parts must hold a base64 data: URL")
+			}
+			image, err := connectors.ImagePartFromDataURL(part.ImageURL.URL)
+			if err != nil {
+				return err
+			}
+			c.images = append(c.images, image)
+		default:
+			return fmt.Errorf("unsupported content part type %q", part.Type)
+		}
+	}
+	c.text = strings.Join(texts, "\n")
+	return nil
+}
+
+// stopSequences accepts either a single string or an array of strings.
+type stopSequences []string
+
+func (s *stopSequences) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = stopSequences{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("stop must be a string or an array of strings")
+	}
+	*s = list
+	return nil
+}
+
+type chatCompletionMessage struct {
+	Role    string         `json:"role"`
+	Content messageContent `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model            string                  `json:"model"`
+	Messages         []chatCompletionMessage `json:"messages"`
+	Stream           bool                    `json:"stream"`
+	Temperature      *float64                `json:"temperature"`
+	TopP             *float64                `json:"top_p"`
+	Stop             stopSequences           `json:"stop"`
+	MaxTokens        *int                    `json:"max_tokens"`
+	MaxCompletion    *int                    `json:"max_completion_tokens"`
+	Seed             *int                    `json:"seed"`
+	PresencePenalty  *float64                `json:"presence_penalty"`
+	FrequencyPenalty *float64                `json:"frequency_penalty"`
+}
+
+type responseMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type choice struct {
+	Index        int              `json:"index"`
+	Message      *responseMessage `json:"message,omitempty"`
+	Delta        *responseMessage `json:"delta,omitempty"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+type chatCompletion struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+}
+
+type apiError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+
+	models, err := s.backend.Models(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "api_error", err.Error())
+		return
+	}
+
+	list := modelList{Object: "list", Data: make([]modelObject, 0, len(models))}
+	created := time.Now().Unix()
+	for _, model := range models {
+		list.Data = append(list.Data, modelObject{
+			ID:      model.ID,
+			Object:  "model",
+			Created: created,
+			OwnedBy: model.Provider,
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+
+	var body chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	provider, model, ok := strings.Cut(body.Model, "/")
+	if !ok || provider == "" || model == "" {
+		writeError(w, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("model %q must have the form provider/model", body.Model))
+		return
+	}
+	request := ChatRequest{
+		Provider: provider,
+		Model:    model,
+		Messages: make([]connectors.ChatMessage, 0, len(body.Messages)),
+		Overrides: GenerationOverrides{
+			Temperature:      body.Temperature,
+			TopP:             body.TopP,
+			Stop:             body.Stop,
+			MaxOutputTokens:  body.MaxTokens,
+			Seed:             body.Seed,
+			PresencePenalty:  body.PresencePenalty,
+			FrequencyPenalty: body.FrequencyPenalty,
+		},
+	}
+	// Newer clients send max_completion_tokens in place of the deprecated max_tokens
+	if body.MaxCompletion != nil {
+		request.Overrides.MaxOutputTokens = body.MaxCompletion
+	}
+	for _, msg := range body.Messages {
+		request.Messages = append(request.Messages, connectors.ChatMessage{Role: msg.Role, Content: msg.Content.text, Images: msg.Content.images})
+	}
+
+	id, err := newCompletionID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	created := time.Now().Unix()
+
+	if body.Stream {
+		s.streamCompletion(w, r, request, chatCompletion{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   body.Model,
+		})
+		return
+	}
+
+	content, err := s.backend.Chat(r.Context(), request, nil)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	stop := "stop"
+	writeJSON(w, http.StatusOK, chatCompletion{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   body.Model,
+		Choices: []choice{{
+			Message:      &responseMessage{Role: connectors.RoleAssistant, Content: content},
+			FinishReason: &stop,
+		}},
+	})
+}
+
+// streamCompletion relays deltas as server-sent chat.completion.chunk events,
+// ending with a finish chunk and [DONE]. Errors after the stream has started
+// are sent as an error event since the status code is already written.
+func (s *Server) streamCompletion(w http.ResponseWriter, r *http.Request, request ChatRequest, chunk chatCompletion) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "api_error", "streaming is not supported")
+		return
+	}
+
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		chunk.Choices = []choice{{Delta: &responseMessage{Role: connectors.RoleAssistant}}}
+		writeEvent(w, chunk)
+	}
+
+	_, err := s.backend.Chat(r.Context(), request, func(delta string) {
+		start()
+		chunk.Choices = []choice{{Delta: &responseMessage{Content: delta}}}
+		writeEvent(w, chunk)
+		flusher.Flush()
+	})
+	if err != nil {
+		if !started {
+			writeBackendError(w, err)
+			return
+		}
+		var event apiError
+		event.Error.Message = err.Error()
+		event.Error.Type = "api_error"
+		writeEvent(w, event)
+		flusher.Flush()
+		return
+	}
+
+	start()
+	stop := "stop"
+	chunk.Choices = []choice{{Delta: &responseMessage{}, FinishReason: &stop}}
+	writeEvent(w, chunk)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+func writeEvent(w http.ResponseWriter, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// writeBackendError answers with the status of a BackendError, or 502 for
+// errors of the provider behind the backend.
+func writeBackendError(w http.ResponseWriter, err error) {
+	var backendErr *BackendError
+	if errors.As(err, &backendErr) {
+		writeError(w, backendErr.Status, "invalid_request_error", err.Error())
+		return
+	}
+	writeError(w, http.StatusBadGateway, "api_error", err.Error())
+}
+
+func writeError(w http.ResponseWriter, status int, errType string, message string) {
+	var payload apiError
+	payload.Error.Message = message
+	payload.Error.Type = errType
+	writeJSON(w, status, payload)
+}
@@ -0,0 +1,209 @@
+// Package apiserver exposes Lumen's providers through an OpenAI-compatible
+// HTTP API on localhost, so editors and scripts can use Lumen as a gateway.
+package apiserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"myproject/connectors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Model is a model the gateway can route to. ID has the form "provider/model".
+type Model struct {
+	ID       string
+	Provider string
+}
+
+// GenerationOverrides are per-request parameters that take precedence over the
+// saved model config. Nil fields keep the saved value.
+type GenerationOverrides struct {
+	Temperature      *float64
+	TopP             *float64
+	Stop             []string
+	MaxOutputTokens  *int
+	Seed             *int
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+}
+
+// ChatRequest is a provider-neutral chat completion request.
+type ChatRequest struct {
+	Provider  string
+	Model     string
+	Messages  []connectors.ChatMessage
+	Overrides GenerationOverrides
+}
+
+// BackendError marks an error a Backend returns for a request it cannot
+// serve as asked, as opposed to a failure of the provider behind it, which
+// is answered with 502.
+type BackendError struct {
+	Status int
+	Err    error
+}
+
+func (e *BackendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidRequest wraps an error caused by the request's contents, answered with 400.
+func InvalidRequest(err error) error {
+	return &BackendError{Status: http.StatusBadRequest, Err: err}
+}
+
+// NotFound wraps an error for an unknown provider or model, answered with 404.
+func NotFound(err error) error {
+	return &BackendError{Status: http.StatusNotFound, Err: err}
+}
+
+// Backend resolves models and runs chats. The App implements it using its
+// registry, stored keys and model configs.
+type Backend interface {
+	Models(ctx context.Context) ([]Model, error)
+	// Chat runs a completion. When onDelta is non-nil the response is streamed
+	// through it; the full text is returned either way.
+	Chat(ctx context.Context, request ChatRequest, onDelta connectors.StreamHandler) (string, error)
+}
+
+// Server is an OpenAI-compatible HTTP server bound to the loopback interface.
+// Every request must carry the configured bearer token.
+type Server struct {
+	backend Backend
+	token   string
+
+	mu       sync.Mutex
+	server   *http.Server
+	listener net.Listener
+}
+
+// New creates a stopped server that authenticates requests with token.
+func New(backend Backend, token string) *Server {
+	return &Server{backend: backend, token: token}
+}
+
+// Start listens on 127.0.0.1:port and serves in the background.
+func (s *Server) Start(port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server != nil {
+		return fmt.Errorf("API server is already running on %s", s.listener.Addr())
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid port %d", port)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", s.handleModels)
+	mux.HandleFunc("/v1/chat/completions", s.handleChatCompletions)
+
+	server := &http.Server{
+		Handler:           s.guard(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.server = server
+	s.listener = listener
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("API server stopped: %v\n", err)
+		}
+	}()
+	fmt.Printf("API server listening on http://%s\n", listener.Addr())
+	return nil
+}
+
+// Stop shuts the server down, waiting briefly for in-flight requests.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	server := s.server
+	s.server = nil
+	s.listener = nil
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return server.Close()
+	}
+	return nil
+}
+
+// SetToken replaces the bearer token required by subsequent requests.
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+}
+
+// Addr returns the listening address, or "" when the server is stopped.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// guard rejects requests that are not addressed to localhost, which blocks
+// DNS rebinding from web pages, and requests without the bearer token.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host != "localhost" && host != "127.0.0.1" {
+			writeError(w, http.StatusForbidden, "permission_error", "requests must be addressed to localhost")
+			return
+		}
+
+		s.mu.Lock()
+		expected := s.token
+		s.mu.Unlock()
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			writeError(w, http.StatusUnauthorized, "authentication_error", "invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewToken returns a random bearer token for clients of the server.
+func NewToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	return "lumen-" + hex.EncodeToString(buf), nil
+}
+
+func newCompletionID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate completion ID: %w", err)
+	}
+	return "chatcmpl-" + hex.EncodeToString(buf), nil
+}
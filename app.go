@@ -4,11 +4,19 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"myproject/apiserver"
 	"myproject/connectors"
+	"myproject/conversations"
+	"myproject/internal/atomicfile"
+	"myproject/rag"
+	"myproject/secrets"
+	"myproject/workspace"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -53,9 +61,20 @@ type AppInfo struct {
 
 // AppConfig defines the structure of our configuration file.
 type AppConfig struct {
-	AppDetails   AppInfo              `json:"app_details"`
-	CloudAPIKeys map[string]string    `json:"cloud_api_keys"`
-	ModelConfigs map[string]ModelConfig `json:"model_configs"`
+	SchemaVersion     int                             `json:"schema_version"`
+	AppDetails        AppInfo                         `json:"app_details"`
+	CloudAPIKeys      map[string]string               `json:"cloud_api_keys"`
+	ModelConfigs      map[string]ModelConfig          `json:"model_configs"`
+	MCPServers        map[string]MCPServerConfig      `json:"mcp_servers,omitempty"`
+	CustomProviders   map[string]CustomProviderConfig `json:"custom_providers,omitempty"`
+	ProviderEndpoints map[string]string               `json:"provider_endpoints,omitempty"`
+	APIServer         APIServerConfig                 `json:"api_server"`
+	Presets           map[string]Preset               `json:"presets,omitempty"`
+	ModelPresets      map[string]string               `json:"model_presets,omitempty"`
+	SecretStore       string                          `json:"secret_store,omitempty"`
+	KeyStatus         map[string]ProviderKeyStatus    `json:"key_status,omitempty"`
+	Knowledge         KnowledgeConfig                 `json:"knowledge"`
+	Workspace         WorkspaceConfig                 `json:"workspace"`
 }
 
 type App struct {
@@ -65,41 +84,81 @@ type App struct {
 	encryptionKey string
 
 	// In-memory representation of the config
-	appInfo      AppInfo
-	cloudAPIKeys map[string]string
-	modelConfigs map[string]ModelConfig
-}
-
-type ProviderConfig struct {
-	Endpoint string
-	APIPath  string
-}
-
-var providerConfigs = map[string]ProviderConfig{
-	"ollama": {
-		Endpoint: "http://localhost:11434",
-		APIPath:  "/api/tags",
-	},
-	"lmstudio": {
-		Endpoint: "http://localhost:1234",
-		APIPath:  "/v1/models",
-	},
-	"huggingface": {
-		Endpoint: "http://localhost:8000",
-		APIPath:  "/models",
-	},
+	schemaVersion         int
+	appInfo               AppInfo
+	cloudAPIKeys          map[string]string
+	modelConfigs          map[string]ModelConfig
+	mcpServerConfigs      map[string]MCPServerConfig
+	customProviderConfigs map[string]CustomProviderConfig
+	providerEndpoints     map[string]string
+	apiServerConfig       APIServerConfig
+	presets               map[string]Preset
+	modelPresets          map[string]string
+	knowledgeConfig       KnowledgeConfig
+	workspaceConfig       WorkspaceConfig
+
+	// Where API keys and tokens are kept; nil when there is no home directory
+	secretStoreBackend string
+	secrets            secrets.Store
+	keyStatus          map[string]ProviderKeyStatus
+
+	// Registered model providers and their recently listed models
+	registry   *connectors.Registry
+	modelCache *connectors.ModelCache
+
+	// Saved conversations, nil when there is no home directory
+	conversationStore *conversations.Store
+
+	// Embedding indexes of knowledge folders, nil when there is no home
+	// directory, and the background indexing jobs by folder
+	knowledgeStore *rag.Store
+	knowledgeMutex sync.Mutex
+	knowledgeJobs  map[string]string
+
+	// Tools that models may call in chats with UseTools set, and the subset
+	// offered by MCP servers, which ChatWithModel offers on its own
+	tools    *connectors.ToolSet
+	mcpTools *connectors.ToolSet
+
+	// The open workspace, nil when none is, and writes awaiting confirmation
+	// by confirm ID
+	workspaceMutex    sync.Mutex
+	workspace         *workspace.Workspace
+	workspaceConfirms map[string]chan bool
+
+	// Connections to the enabled MCP servers, by server name
+	mcpMutex   sync.Mutex
+	mcpServers map[string]*mcpConnection
+
+	// In-flight generations, keyed by generation ID
+	generationsMutex sync.Mutex
+	generations      map[string]context.CancelFunc
+
+	// OpenAI-compatible API server, nil until started
+	apiServerMutex sync.Mutex
+	apiServer      *apiserver.Server
 }
 
 func NewApp() *App {
     // Get encryption key. It MUST be 32 bytes for AES-256.
     encryptionKey := getEncryptionKey()
+    // It is only needed to migrate API keys saved before the secret store.
     if len(encryptionKey) != 32 {
-        log.Fatalf("Fatal: ENCRYPTION_KEY must be 32 bytes long, but got %d bytes. Ensure it's set in .env for local dev or as a repository secret for builds.", len(encryptionKey))
+        log.Printf("Warning: ENCRYPTION_KEY must be 32 bytes long, but got %d bytes. API keys saved by older versions cannot be migrated.", len(encryptionKey))
     }
 
     app := &App{
-        encryptionKey: encryptionKey,
+        encryptionKey:     encryptionKey,
+        registry:          connectors.NewDefaultRegistry(),
+        modelCache:        connectors.NewModelCache(modelCacheTTL),
+        generations:       make(map[string]context.CancelFunc),
+        knowledgeJobs:     make(map[string]string),
+        tools:             connectors.NewToolSet(),
+        mcpTools:          connectors.NewToolSet(),
+        workspaceConfirms: make(map[string]chan bool),
+        mcpServers:        make(map[string]*mcpConnection),
     }
+    app.registerBuiltinTools()
 
     // Determine config path
     home, err := os.UserHomeDir()
@@ -107,6 +166,8 @@ func NewApp() *App {
         fmt.Fprintf(os.Stderr, "Warning: could not find user home directory: %v. Config will not be saved.\n", err)
     } else {
         app.configPath = filepath.Join(home, ".lumen", "config.json")
+        app.conversationStore = conversations.NewStore(filepath.Join(home, ".lumen", "conversations"))
+        app.knowledgeStore = rag.NewStore(filepath.Join(home, ".lumen", "rag"))
     }
 
     return app
@@ -117,6 +178,17 @@ func (a *App) startup(ctx context.Context) {
 	if err := a.loadConfig(); err != nil {
 		fmt.Fprintf(os.Stderr, "Fatal error managing config: %v\n", err)
 	}
+	a.startAPIServerIfEnabled()
+	go a.connectMCPServers()
+}
+
+func (a *App) shutdown(ctx context.Context) {
+	a.closeMCPServers()
+	a.apiServerMutex.Lock()
+	defer a.apiServerMutex.Unlock()
+	if a.apiServer != nil {
+		a.apiServer.Stop()
+	}
 }
 
 // loadConfig loads the configuration from disk or creates a new one on first launch.
@@ -128,8 +200,9 @@ func (a *App) loadConfig() error {
 	}
 
 	if a.configPath == "" {
-		a.cloudAPIKeys = make(map[string]string)
-		a.modelConfigs = make(map[string]ModelConfig)
+		a.applyConfig(AppConfig{})
+		a.openSecretStore()
+		a.schemaVersion = currentVersionInfo.BuildNumber
 		a.appInfo = AppInfo{
 			Version:        currentVersionInfo.Version,
 			BuildNumber:    currentVersionInfo.BuildNumber,
@@ -144,8 +217,9 @@ func (a *App) loadConfig() error {
 	// Handle new installation (config file doesn't exist)
 	if os.IsNotExist(err) {
 		fmt.Println("No config file found, creating a new one at:", a.configPath)
-		a.cloudAPIKeys = make(map[string]string)
-		a.modelConfigs = make(map[string]ModelConfig)
+		a.applyConfig(AppConfig{})
+		a.openSecretStore()
+		a.schemaVersion = currentVersionInfo.BuildNumber
 		a.appInfo = AppInfo{
 			Version:        currentVersionInfo.Version,
 			BuildNumber:    currentVersionInfo.BuildNumber,
@@ -160,11 +234,20 @@ func (a *App) loadConfig() error {
 	}
 
 	// Handle existing installation (config file exists)
+	migrated, schemaVersion, err := migrateConfig(data, currentVersionInfo.BuildNumber)
 	var config AppConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not parse config file, creating a fresh one: %v\n", err)
-		a.cloudAPIKeys = make(map[string]string)
-		a.modelConfigs = make(map[string]ModelConfig)
+	if err == nil {
+		err = json.Unmarshal(migrated, &config)
+	}
+	if err != nil {
+		backup, backupErr := a.backupCorruptConfig()
+		if backupErr != nil {
+			return fmt.Errorf("config file is unreadable (%v) and could not be backed up: %w", err, backupErr)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: could not read config file, moved it to %s and created a fresh one: %v\n", backup, err)
+		a.applyConfig(AppConfig{})
+		a.openSecretStore()
+		a.schemaVersion = currentVersionInfo.BuildNumber
 		a.appInfo = AppInfo{
 			Version:        currentVersionInfo.Version,
 			BuildNumber:    currentVersionInfo.BuildNumber,
@@ -175,18 +258,31 @@ func (a *App) loadConfig() error {
 		return a.saveConfig()
 	}
 
-	// Load existing config into memory
-	a.configMutex.Lock()
-	a.appInfo = config.AppDetails
-	a.cloudAPIKeys = config.CloudAPIKeys
-	a.modelConfigs = config.ModelConfigs
-	if a.cloudAPIKeys == nil {
-		a.cloudAPIKeys = make(map[string]string)
+	// Keep a copy of the file as it was before migrating it, or before an
+	// older build drops settings it does not know about.
+	needsSave := false
+	switch {
+	case schemaVersion < currentVersionInfo.BuildNumber:
+		if backup, err := a.backupConfig(fmt.Sprintf("build-%d.bak", schemaVersion)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not back up config before migrating it: %v\n", err)
+		} else {
+			fmt.Println("Backed up config to", backup)
+		}
+		needsSave = true
+	case schemaVersion > currentVersionInfo.BuildNumber:
+		fmt.Fprintf(os.Stderr, "Warning: config was written by a newer build (%d); settings this build does not know will be lost\n", schemaVersion)
+		if _, err := a.backupConfig(fmt.Sprintf("build-%d.bak", schemaVersion)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not back up config: %v\n", err)
+		}
+		config.SchemaVersion = currentVersionInfo.BuildNumber
 	}
-	if a.modelConfigs == nil {
-		a.modelConfigs = make(map[string]ModelConfig)
+
+	// Load existing config into memory
+	a.applyConfig(config)
+	a.openSecretStore()
+	if err := a.migrateLegacySecrets(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not migrate saved API keys: %v\n", err)
 	}
-	a.configMutex.Unlock()
 
 	// Check if the app has been updated by comparing build numbers
 	if a.appInfo.BuildNumber < currentVersionInfo.BuildNumber {
@@ -195,6 +291,9 @@ func (a *App) loadConfig() error {
 		a.appInfo.BuildNumber = currentVersionInfo.BuildNumber
 		a.appInfo.ReleaseDate = currentVersionInfo.ReleaseDate
 		a.appInfo.LastUpdateDate = time.Now()
+		needsSave = true
+	}
+	if needsSave {
 		if err := a.saveConfig(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error saving updated config: %v\n", err)
 		}
@@ -204,6 +303,61 @@ func (a *App) loadConfig() error {
 	return nil
 }
 
+// secretsDir is where the local secret store backends keep their files.
+func (a *App) secretsDir() string {
+	return filepath.Dir(a.configPath)
+}
+
+// applyConfig replaces the in-memory configuration, making sure every map
+// is usable, then opens the configured workspace and registers the custom
+// providers.
+func (a *App) applyConfig(config AppConfig) {
+	a.configMutex.Lock()
+	a.schemaVersion = config.SchemaVersion
+	a.appInfo = config.AppDetails
+	a.cloudAPIKeys = config.CloudAPIKeys
+	a.modelConfigs = config.ModelConfigs
+	a.mcpServerConfigs = config.MCPServers
+	previousProviders := a.customProviderConfigs
+	a.customProviderConfigs = config.CustomProviders
+	a.providerEndpoints = config.ProviderEndpoints
+	a.apiServerConfig = config.APIServer
+	a.presets = config.Presets
+	a.modelPresets = config.ModelPresets
+	a.secretStoreBackend = config.SecretStore
+	a.keyStatus = config.KeyStatus
+	a.knowledgeConfig = config.Knowledge
+	a.workspaceConfig = config.Workspace
+	if a.cloudAPIKeys == nil {
+		a.cloudAPIKeys = make(map[string]string)
+	}
+	if a.modelConfigs == nil {
+		a.modelConfigs = make(map[string]ModelConfig)
+	}
+	if a.mcpServerConfigs == nil {
+		a.mcpServerConfigs = make(map[string]MCPServerConfig)
+	}
+	if a.customProviderConfigs == nil {
+		a.customProviderConfigs = make(map[string]CustomProviderConfig)
+	}
+	if a.providerEndpoints == nil {
+		a.providerEndpoints = make(map[string]string)
+	}
+	if a.presets == nil {
+		a.presets = make(map[string]Preset)
+	}
+	if a.modelPresets == nil {
+		a.modelPresets = make(map[string]string)
+	}
+	if a.keyStatus == nil {
+		a.keyStatus = make(map[string]ProviderKeyStatus)
+	}
+	a.configMutex.Unlock()
+
+	a.openConfiguredWorkspace(config.Workspace.Root)
+	a.registerCustomProviders(previousProviders)
+}
+
 // saveConfig saves the current in-memory configuration to a file on disk.
 func (a *App) saveConfig() error {
 	if a.configPath == "" {
@@ -216,9 +370,20 @@ func (a *App) saveConfig() error {
 	a.appInfo.LastUpdateDate = time.Now()
 
 	config := AppConfig{
-		AppDetails:   a.appInfo,
-		CloudAPIKeys: a.cloudAPIKeys,
-		ModelConfigs: a.modelConfigs,
+		SchemaVersion:     a.schemaVersion,
+		AppDetails:        a.appInfo,
+		CloudAPIKeys:      a.cloudAPIKeys,
+		ModelConfigs:      a.modelConfigs,
+		MCPServers:        a.mcpServerConfigs,
+		CustomProviders:   a.customProviderConfigs,
+		ProviderEndpoints: a.providerEndpoints,
+		APIServer:         a.apiServerConfig,
+		Presets:           a.presets,
+		ModelPresets:      a.modelPresets,
+		SecretStore:       a.secretStoreBackend,
+		KeyStatus:         a.keyStatus,
+		Knowledge:         a.knowledgeConfig,
+		Workspace:         a.workspaceConfig,
 	}
 
 	data, err := json.MarshalIndent(config, "", "  ")
@@ -227,11 +392,11 @@ func (a *App) saveConfig() error {
 	}
 
 	dir := filepath.Dir(a.configPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := os.WriteFile(a.configPath, data, 0644); err != nil {
+	if err := atomicfile.WriteFile(a.configPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -239,53 +404,28 @@ func (a *App) saveConfig() error {
 	return nil
 }
 
-// SaveAPIKey encrypts and saves a cloud provider's API key.
-func (a *App) SaveAPIKey(provider string, apiKey string) {
-	if apiKey == "" {
-		a.configMutex.Lock()
-		delete(a.cloudAPIKeys, provider)
-		a.configMutex.Unlock()
-	} else {
-		encryptedKey, err := EncryptString(apiKey, a.encryptionKey)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error encrypting API key for %s: %v\n", provider, err)
-			return
-		}
-		a.configMutex.Lock()
-		a.cloudAPIKeys[provider] = encryptedKey
-		a.configMutex.Unlock()
-	}
-
-	if err := a.saveConfig(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving config after updating API key: %v\n", err)
-	}
-}
-
-// GetAPIKey retrieves and decrypts a cloud provider's API key.
+// GetAPIKey retrieves a cloud provider's API key from the secret store.
 func (a *App) GetAPIKey(provider string) (string, error) {
 	a.configMutex.RLock()
-	encryptedKey, ok := a.cloudAPIKeys[provider]
+	legacy := a.cloudAPIKeys[provider]
 	a.configMutex.RUnlock()
 
-	if !ok {
+	apiKey, err := a.getSecret(apiKeySecret(provider), legacy)
+	switch {
+	case errors.Is(err, secrets.ErrNotFound):
 		return "", fmt.Errorf("API key for %s not found", provider)
+	case errors.Is(err, secrets.ErrLocked):
+		return "", fmt.Errorf("secret store is locked; unlock it to use %s", provider)
+	case err != nil:
+		return "", fmt.Errorf("failed to read API key for %s: %v", provider, err)
 	}
-	if encryptedKey == "" {
-		return "", nil
-	}
-
-	decryptedKey, err := DecryptString(encryptedKey, a.encryptionKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to decrypt API key for %s", provider)
-	}
-
-	return decryptedKey, nil
+	return apiKey, nil
 }
 
 // Model scanning logic
 func (a *App) ScanLocalModels(provider string) connectors.ScanResult {
-	config, exists := providerConfigs[provider]
-	if !exists {
+	info, exists := a.registry.Info(provider)
+	if !exists || !a.hasEndpoint(info) {
 		return connectors.ScanResult{
 			Models:  []connectors.Model{},
 			Error:   "Unsupported provider",
@@ -293,37 +433,32 @@ func (a *App) ScanLocalModels(provider string) connectors.ScanResult {
 		}
 	}
 
-	switch provider {
-	case "ollama":
-		return connectors.NewOllamaConnector(config.Endpoint).ScanModels()
-	case "lmstudio":
-		return connectors.NewLMStudioConnector(config.Endpoint).ScanModels()
-	case "huggingface":
-		return connectors.NewHuggingFaceConnector(config.Endpoint).ScanModels()
-	default:
+	models, err := a.listModels(context.Background(), provider, true)
+	if err != nil {
 		return connectors.ScanResult{
 			Models:  []connectors.Model{},
-			Error:   "Unsupported provider",
+			Error:   err.Error(),
 			Success: false,
 		}
 	}
+	return connectors.ScanResult{
+		Models:  models,
+		Success: true,
+	}
 }
 
-// ConnectCloudModel tests and saves the API key for a cloud provider.
+// ConnectCloudModel validates a cloud provider's API key, saves it in the
+// secret store and verifies it reads back intact.
 func (a *App) ConnectCloudModel(provider string, apiKey string) error {
-	connector := connectors.NewCloudConnector(provider, apiKey)
-	if err := connector.TestConnection(); err != nil {
-		return err
+	if strings.TrimSpace(apiKey) == "" {
+		return fmt.Errorf("API key is required")
 	}
-
-	a.configMutex.Lock()
-	a.cloudAPIKeys[provider] = apiKey
-	a.configMutex.Unlock()
-
-	return nil
+	return a.storeAPIKey(provider, apiKey, true)
 }
 
-// ModelConfig defines model parameters
+// ModelConfig defines model parameters. Zero values of the optional fields
+// leave the parameter to the provider; each provider only receives the
+// parameters it declares in its schema.
 type ModelConfig struct {
 	Temperature    float64  `json:"temperature"`
 	TopP           float64  `json:"top_p"`
@@ -331,9 +466,21 @@ type ModelConfig struct {
 	RepeatPenalty  float64  `json:"repeat_penalty"`
 	NumCtx         int      `json:"num_ctx"`
 	Stop           []string `json:"stop"`
+
+	MaxOutputTokens  int     `json:"max_output_tokens,omitempty"`
+	Seed             *int    `json:"seed,omitempty"`
+	PresencePenalty  float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float64 `json:"frequency_penalty,omitempty"`
+	MinP             float64 `json:"min_p,omitempty"`
 }
 
+// SaveModelConfig validates a model config against its provider's parameter
+// schema and saves it. An invalid config is not saved.
 func (a *App) SaveModelConfig(provider string, model string, config ModelConfig) string {
+	if err := a.validateModelConfig(provider, model, config); err != nil {
+		return fmt.Sprintf("Config not saved: %v", err)
+	}
+
 	key := fmt.Sprintf("%s/%s", provider, model)
 	a.configMutex.Lock()
 	a.modelConfigs[key] = config
@@ -373,94 +520,186 @@ func (a *App) GetModelConfig(provider string, model string) (ModelConfig, error)
 	return config, nil
 }
 
+// ChatRequest is an ordered, role-tagged conversation sent to a model.
+// GenerationID is optional; when set it lets CancelGeneration abort the request.
+// PresetID picks a system-prompt preset explicitly; otherwise the preset
+// attached to ConversationID, then the one attached to the model, is used.
+type ChatRequest struct {
+	Provider       string                   `json:"provider"`
+	Model          string                   `json:"model"`
+	Messages       []connectors.ChatMessage `json:"messages"`
+	GenerationID   string                   `json:"generation_id,omitempty"`
+	ConversationID string                   `json:"conversation_id,omitempty"`
+	PresetID       string                   `json:"preset_id,omitempty"`
+	// UseKnowledge adds the most relevant excerpts of the knowledge folders
+	// to the prompt.
+	UseKnowledge bool `json:"use_knowledge,omitempty"`
+	// UseTools lets the model call the registered tools. Each round with the
+	// model is generated in full, so a streamed reply arrives in one delta.
+	UseTools bool `json:"use_tools,omitempty"`
+
+	// onSources receives the knowledge excerpts used, instead of ChatSourcesEvent
+	onSources func([]rag.Hit)
+	// onToolCall receives each tool call made, instead of ChatToolCallEvent
+	onToolCall func(ChatToolCall)
+	// confirmWrite approves workspace writes, instead of WorkspaceWriteEvent
+	confirmWrite writeConfirmer
+	// toolSet replaces the registered tools offered with UseTools
+	toolSet *connectors.ToolSet
+}
+
+// ChatWithModel sends a single user message with no prior history. The
+// tools of connected MCP servers are offered when the provider supports
+// tools; workspace and built-in tools need ChatRequest.UseTools.
 func (a *App) ChatWithModel(provider string, model string, message string) (string, error) {
-	if provider == "" || model == "" || message == "" {
+	if message == "" {
 		return "", fmt.Errorf("missing input")
 	}
+	request := ChatRequest{
+		Provider: provider,
+		Model:    model,
+		Messages: []connectors.ChatMessage{{Role: connectors.RoleUser, Content: message}},
+	}
+	if info, _ := a.registry.Info(provider); info.Capabilities.Tools && len(a.mcpTools.Tools()) > 0 {
+		request.UseTools, request.toolSet = true, a.mcpTools
+	}
+	return a.ChatWithHistory(request)
+}
 
-	config, err := a.GetModelConfig(provider, model)
-	if err != nil {
+// ChatWithHistory sends the full conversation so the model keeps context across turns.
+func (a *App) ChatWithHistory(request ChatRequest) (string, error) {
+	if err := validateChatRequest(request); err != nil {
 		return "", err
 	}
 
-	switch provider {
-	case "ollama":
-		return a.chatWithOllama(model, message, config)
-	case "lmstudio":
-		return a.chatWithLMStudio(model, message, config)
-	case "openai", "anthropic", "google":
-		apiKey, err := a.GetAPIKey(provider)
+	ctx := context.Background()
+	if request.GenerationID != "" {
+		var finish func()
+		var err error
+		ctx, finish, err = a.beginGeneration(request.GenerationID)
 		if err != nil {
 			return "", err
 		}
-		connector := connectors.NewCloudConnector(provider, apiKey)
-
-		configMap := map[string]interface{}{
-			"temperature": config.Temperature,
-			"top_p":       config.TopP,
-			"top_k":       config.TopK,
-			"max_tokens":  config.NumCtx,
-			"stop":        config.Stop,
-		}
-
-		return connector.Chat(model, message, configMap)
-	default:
-		return "", fmt.Errorf("unsupported provider: %s", provider)
+		defer finish()
 	}
+	return a.chatWithHistory(ctx, request)
 }
 
-// ListCloudModels lists models for a given cloud provider.
-func (a *App) ListCloudModels(provider string, apiKey string) ([]connectors.Model, error) {
-    if provider == "" || apiKey == "" {
-        return nil, fmt.Errorf("provider and API key are required")
-    }
-    connector := connectors.NewCloudConnector(provider, apiKey)
-    return connector.ListModels()
-}
+// chatWithHistory sends a validated request and waits for the full response.
+func (a *App) chatWithHistory(ctx context.Context, request ChatRequest) (string, error) {
+	request, err := a.applySystemPrompt(request)
+	if err != nil {
+		return "", err
+	}
+	request, sources, err := a.applyKnowledge(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	a.emitChatSources(request, sources)
+	if request.Messages, err = connectors.PrepareImages(request.Messages); err != nil {
+		return "", err
+	}
+	p, options, err := a.prepareChat(ctx, request)
+	if err != nil {
+		return "", err
+	}
 
-func (a *App) testOllamaConfig(model string, config ModelConfig) error {
-	fmt.Printf("Config for %s will be applied in future API calls\n", model)
-	return nil
+	if request.UseTools {
+		return a.chatWithTools(ctx, p, request, options)
+	}
+
+	start := time.Now()
+	response, err := p.Chat(ctx, request.Model, request.Messages, options)
+	if err != nil {
+		return "", fmt.Errorf("%s chat failed after %v: %v", request.Provider, time.Since(start), err)
+	}
+	return response, nil
 }
 
-func (a *App) chatWithOllama(model string, message string, config ModelConfig) (string, error) {
-	connector := connectors.NewOllamaConnector("http://localhost:11434")
-	if err := connector.QuickHealthCheck(); err != nil {
-		return "", fmt.Errorf("ollama unavailable: %v", err)
+// prepareChat resolves the request's provider, checks that it can chat and
+// maps the saved model config to the provider's options.
+func (a *App) prepareChat(ctx context.Context, request ChatRequest) (connectors.Provider, map[string]interface{}, error) {
+	p, config, err := a.resolveChat(ctx, request)
+	if err != nil {
+		return nil, nil, err
 	}
+	return p, p.MapConfig(config), nil
+}
 
-	ollamaConfig := map[string]interface{}{
-		"temperature":     config.Temperature,
-		"top_p":           config.TopP,
-		"top_k":           config.TopK,
-		"repeat_penalty":  config.RepeatPenalty,
-		"num_ctx":         config.NumCtx,
+// resolveChat returns the request's provider, once it is known to be able to
+// chat, together with the saved generation parameters for the model.
+func (a *App) resolveChat(ctx context.Context, request ChatRequest) (connectors.Provider, connectors.GenerationConfig, error) {
+	p, err := a.newProvider(request.Provider)
+	if err != nil {
+		return nil, connectors.GenerationConfig{}, err
 	}
-	if len(config.Stop) > 0 {
-		ollamaConfig["stop"] = config.Stop
+	capabilities := p.Capabilities()
+	if !capabilities.Chat {
+		return nil, connectors.GenerationConfig{}, fmt.Errorf("chat is not supported for %s", request.Provider)
+	}
+	if !capabilities.Images && hasImages(request.Messages) {
+		return nil, connectors.GenerationConfig{}, fmt.Errorf("%s cannot receive images", request.Provider)
+	}
+	if capabilities.Local {
+		if err := p.HealthCheck(ctx); err != nil {
+			return nil, connectors.GenerationConfig{}, fmt.Errorf("%s unavailable: %v", request.Provider, err)
+		}
 	}
 
-	start := time.Now()
-	response, err := connector.ChatWithOllama(model, message, ollamaConfig)
-	duration := time.Since(start)
-
+	config, err := a.GetModelConfig(request.Provider, request.Model)
 	if err != nil {
-		return "", fmt.Errorf("ollama chat failed after %v: %v", duration, err)
+		return nil, connectors.GenerationConfig{}, err
 	}
-	return response, nil
+	return p, config.generationConfig(), nil
 }
 
-func (a *App) chatWithLMStudio(model string, message string, config ModelConfig) (string, error) {
-	connector := connectors.NewLMStudioConnector("http://localhost:1234")
-	lmStudioConfig := map[string]interface{}{
-		"temperature": config.Temperature,
-		"top_p":       config.TopP,
+// validateChatRequest rejects incomplete requests, unknown roles and
+// conversations without a user turn.
+func validateChatRequest(request ChatRequest) error {
+	if request.Provider == "" || request.Model == "" || len(request.Messages) == 0 {
+		return fmt.Errorf("missing input")
+	}
+	hasUser := false
+	for i, msg := range request.Messages {
+		switch msg.Role {
+		case connectors.RoleUser:
+			hasUser = true
+		case connectors.RoleSystem, connectors.RoleAssistant:
+			if len(msg.Images) > 0 {
+				return fmt.Errorf("message %d: only user messages can have images", i)
+			}
+		default:
+			return fmt.Errorf("message %d has unsupported role %q", i, msg.Role)
+		}
 	}
-	if config.NumCtx > 0 {
-		lmStudioConfig["max_tokens"] = config.NumCtx
+	if !hasUser {
+		return fmt.Errorf("conversation must contain at least one user message")
 	}
-	if len(config.Stop) > 0 {
-		lmStudioConfig["stop"] = config.Stop
+	return nil
+}
+
+// ListCloudModels lists models for a given cloud provider.
+func (a *App) ListCloudModels(provider string, apiKey string) ([]connectors.Model, error) {
+	if provider == "" || apiKey == "" {
+		return nil, fmt.Errorf("provider and API key are required")
+	}
+	p, err := a.newCloudProvider(provider, apiKey)
+	if err != nil {
+		return nil, err
 	}
-	return connector.ChatWithLMStudio(model, message, lmStudioConfig)
+	models, err := p.ListModels(context.Background())
+
+	// Listing models doubles as a key check when it used the saved key.
+	if saved, savedErr := a.GetAPIKey(provider); savedErr == nil && saved == strings.TrimSpace(apiKey) {
+		a.recordKeyCheck(provider, err)
+		if err == nil {
+			a.modelCache.Put(provider, models)
+		}
+	}
+	return models, err
+}
+
+func (a *App) testOllamaConfig(model string, config ModelConfig) error {
+	fmt.Printf("Config for %s will be applied in future API calls\n", model)
+	return nil
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"myproject/connectors"
+	"os"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Events emitted to the frontend while a streamed generation is running.
+const (
+	ChatDeltaEvent = "chat:delta"
+	ChatDoneEvent  = "chat:done"
+)
+
+// ChatDelta carries a chunk of newly generated text.
+type ChatDelta struct {
+	GenerationID string `json:"generation_id"`
+	Delta        string `json:"delta"`
+}
+
+// ChatDone is emitted once per generation with the final response or error.
+// A cancelled generation keeps whatever content was produced before the cancel.
+type ChatDone struct {
+	GenerationID string `json:"generation_id"`
+	Content      string `json:"content"`
+	Error        string `json:"error,omitempty"`
+	Cancelled    bool   `json:"cancelled,omitempty"`
+}
+
+// StreamChat starts a streamed generation and returns its ID immediately.
+// Text arrives through ChatDeltaEvent and the result through ChatDoneEvent,
+// both tagged with the returned generation ID. Callers may pick the ID
+// themselves via request.GenerationID to subscribe before the first delta.
+func (a *App) StreamChat(request ChatRequest) (string, error) {
+	if err := validateChatRequest(request); err != nil {
+		return "", err
+	}
+
+	generationID := request.GenerationID
+	if generationID == "" {
+		var err error
+		if generationID, err = newGenerationID(); err != nil {
+			return "", err
+		}
+	}
+
+	ctx, finish, err := a.beginGeneration(generationID)
+	if err != nil {
+		return "", err
+	}
+	request.GenerationID = generationID
+
+	go func() {
+		defer finish()
+		content, err := a.streamChatWithHistory(ctx, request, func(delta string) {
+			runtime.EventsEmit(a.ctx, ChatDeltaEvent, ChatDelta{GenerationID: generationID, Delta: delta})
+		})
+
+		done := ChatDone{GenerationID: generationID, Content: content}
+		if ctx.Err() != nil {
+			done.Cancelled = true
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Streamed chat %s failed: %v\n", generationID, err)
+			done.Error = err.Error()
+		}
+		runtime.EventsEmit(a.ctx, ChatDoneEvent, done)
+	}()
+
+	return generationID, nil
+}
+
+// CancelGeneration aborts an in-flight generation, model pull or folder
+// indexing job. The HTTP request is cancelled through its context; streamed
+// text received so far is kept.
+func (a *App) CancelGeneration(generationID string) error {
+	a.generationsMutex.Lock()
+	cancel, ok := a.generations[generationID]
+	a.generationsMutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no active generation with ID %s", generationID)
+	}
+	cancel()
+	return nil
+}
+
+// beginGeneration registers a cancellable context under the given ID. The
+// returned finish func must be called once the generation completes.
+func (a *App) beginGeneration(generationID string) (context.Context, func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	a.generationsMutex.Lock()
+	defer a.generationsMutex.Unlock()
+	if _, exists := a.generations[generationID]; exists {
+		cancel()
+		return nil, nil, fmt.Errorf("generation %s is already running", generationID)
+	}
+	a.generations[generationID] = cancel
+
+	finish := func() {
+		a.generationsMutex.Lock()
+		delete(a.generations, generationID)
+		a.generationsMutex.Unlock()
+		cancel()
+	}
+	return ctx, finish, nil
+}
+
+// streamChatWithHistory dispatches a streamed chat to the request's provider.
+func (a *App) streamChatWithHistory(ctx context.Context, request ChatRequest, onDelta connectors.StreamHandler) (string, error) {
+	request, err := a.applySystemPrompt(request)
+	if err != nil {
+		return "", err
+	}
+	request, sources, err := a.applyKnowledge(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	a.emitChatSources(request, sources)
+	if request.Messages, err = connectors.PrepareImages(request.Messages); err != nil {
+		return "", err
+	}
+	p, options, err := a.prepareChat(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	if request.UseTools {
+		response, err := a.chatWithTools(ctx, p, request, options)
+		if response != "" {
+			onDelta(response)
+		}
+		return response, err
+	}
+	if !p.Capabilities().Streaming {
+		return "", fmt.Errorf("streaming is not supported for %s", request.Provider)
+	}
+	return p.StreamChat(ctx, request.Model, request.Messages, options, onDelta)
+}
+
+// newGenerationID returns a random identifier for a streamed generation.
+func newGenerationID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to create generation ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,1079 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"myproject/connectors"
+	"myproject/ingest"
+	"myproject/rag"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cliCommand is a headless subcommand of the lumen binary.
+type cliCommand struct {
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var cliCommands = map[string]cliCommand{
+	"chat": {
+		usage: "chat --provider NAME --model NAME [--system TEXT | --preset NAME] [--image FILE]... [--file FILE]... [--knowledge] [--tools] [--no-stream] [PROMPT...]",
+		run:   (*cli).chat,
+	},
+	"knowledge": {
+		usage: "knowledge ls | knowledge add|rm PATH | knowledge index [PATH] | knowledge search QUERY...\n" +
+			"  lumen knowledge model PROVIDER MODEL [TOP_K]",
+		run: (*cli).knowledge,
+	},
+	"mcp": {
+		usage: "mcp ls | mcp rm|enable|disable|inspect NAME\n" +
+			"  lumen mcp add NAME [--env KEY=VALUE]... [--dir DIR] COMMAND [ARG...]\n" +
+			"  lumen mcp add NAME [--header NAME=VALUE]... URL",
+		run: (*cli).mcp,
+	},
+	"models": {
+		usage: "models scan [PROVIDER...] | models pull|rm|show MODEL | models cp SOURCE DEST",
+		run:   (*cli).models,
+	},
+	"providers": {
+		usage: "providers ls | providers rm NAME\n" +
+			"  lumen providers add NAME [--key KEY] [--header NAME=VALUE]... [--models LIST] BASE_URL",
+		run: (*cli).providers,
+	},
+	"config": {
+		usage: "config get [KEY] | config set KEY VALUE | config unset KEY\n" +
+			"  lumen config export [--sections LIST] [--keys] FILE\n" +
+			"  lumen config import [--overwrite LIST] [--skip LIST] [--dry-run] FILE",
+		run: (*cli).config,
+	},
+}
+
+// errUsage marks errors caused by bad arguments; they exit with status 2.
+var errUsage = errors.New("usage error")
+
+// isCLICommand reports whether the binary was invoked with a CLI subcommand
+// rather than to launch the desktop app.
+func isCLICommand(args []string) bool {
+	_, args = splitVerbose(args)
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == "help" {
+		return true
+	}
+	_, ok := cliCommands[args[0]]
+	return ok
+}
+
+// splitVerbose strips a leading --verbose (or -v) flag from args.
+func splitVerbose(args []string) (bool, []string) {
+	if len(args) > 0 && (args[0] == "-v" || args[0] == "--verbose") {
+		return true, args[1:]
+	}
+	return false, args
+}
+
+// cli runs subcommands against an App without the Wails runtime. Results go
+// to out; the App's own logging is sent to stderr with --verbose and
+// discarded otherwise so that stdout stays clean for pipes.
+type cli struct {
+	app *App
+	out io.Writer
+	in  *os.File
+}
+
+// runCLI executes a subcommand and returns the process exit code.
+func runCLI(args []string) int {
+	verbose, args := splitVerbose(args)
+
+	if args[0] == "help" {
+		printCLIUsage(os.Stdout)
+		return 0
+	}
+
+	c := &cli{out: os.Stdout, in: os.Stdin}
+	if verbose {
+		os.Stdout = os.Stderr
+	} else if devNull, err := os.Open(os.DevNull); err == nil {
+		os.Stdout = devNull
+	}
+
+	c.app = NewApp()
+	if err := c.app.loadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "lumen: %v\n", err)
+		return 1
+	}
+	// A passphrase-protected secret store can be unlocked through the environment.
+	if passphrase := os.Getenv("LUMEN_PASSPHRASE"); passphrase != "" && c.app.GetSecretStoreStatus().Locked {
+		if err := c.app.UnlockSecretStore(passphrase); err != nil {
+			fmt.Fprintf(os.Stderr, "lumen: %v\n", err)
+			return 1
+		}
+	}
+
+	command := cliCommands[args[0]]
+	err := command.run(c, args[1:])
+	if err == nil {
+		return 0
+	}
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "usage: lumen %s\n", command.usage)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "lumen: %v\n", err)
+	return 1
+}
+
+func printCLIUsage(w io.Writer) {
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: lumen [--verbose] COMMAND [ARGS...]")
+	fmt.Fprintln(w, "Run without a command to open the desktop app.")
+	fmt.Fprintln(w)
+	for _, name := range names {
+		fmt.Fprintf(w, "  lumen %s\n", cliCommands[name].usage)
+	}
+}
+
+// chat sends a single prompt built from the arguments and/or stdin and
+// writes the response to stdout, streaming when the provider supports it.
+func (c *cli) chat(args []string) error {
+	flags := flag.NewFlagSet("chat", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	provider := flags.String("provider", "", "provider ID, e.g. ollama")
+	model := flags.String("model", "", "model name")
+	system := flags.String("system", "", "system prompt")
+	presetName := flags.String("preset", "", "system-prompt preset name or ID")
+	noStream := flags.Bool("no-stream", false, "print the response only once it is complete")
+	useKnowledge := flags.Bool("knowledge", false, "add excerpts from the knowledge folders to the prompt")
+	useTools := flags.Bool("tools", false, "let the model call Lumen's tools")
+	var imagePaths repeatedFlag
+	flags.Var(&imagePaths, "image", "image file to attach; may be repeated")
+	var filePaths repeatedFlag
+	flags.Var(&filePaths, "file", "document or source archive to include in the prompt; may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if *provider == "" || *model == "" {
+		return errUsage
+	}
+
+	prompt := strings.Join(flags.Args(), " ")
+	if prompt == "-" || c.stdinIsPiped() {
+		input, err := io.ReadAll(c.in)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		if prompt == "-" {
+			prompt = ""
+		}
+		prompt = strings.TrimSpace(strings.Join([]string{prompt, string(input)}, "\n\n"))
+	}
+	if prompt == "" {
+		return fmt.Errorf("no prompt given; pass it as an argument or on stdin")
+	}
+	if len(filePaths) > 0 {
+		budget := c.app.documentTokenBudget(*provider, *model) - ingest.EstimateTokens(prompt+*system)
+		documents, err := c.app.ingestFiles(filePaths, *provider, *model, budget)
+		if err != nil {
+			return err
+		}
+		parts := make([]string, 0, len(documents)+1)
+		for _, doc := range documents {
+			if doc.Truncated {
+				fmt.Fprintf(os.Stderr, "Warning: %s was cut to %d of %d tokens to fit the context window\n", doc.Name, doc.IncludedTokens, doc.TotalTokens)
+			}
+			parts = append(parts, documentPrompt(doc))
+		}
+		prompt = strings.Join(append(parts, prompt), "\n\n")
+	}
+
+	request := ChatRequest{Provider: *provider, Model: *model, UseKnowledge: *useKnowledge, UseTools: *useTools}
+	var sources []rag.Hit
+	request.onSources = func(hits []rag.Hit) { sources = hits }
+	request.onToolCall = func(event ChatToolCall) {
+		fmt.Fprintf(os.Stderr, "Tool: %s %s\n", event.Call.Name, event.Call.Arguments)
+		if event.Error != "" {
+			fmt.Fprintf(os.Stderr, "Tool error: %s\n", event.Error)
+		}
+	}
+	request.confirmWrite = c.confirmWrite
+	if *presetName != "" {
+		preset, ok := c.app.findPreset(*presetName)
+		if !ok {
+			return fmt.Errorf("preset %q not found", *presetName)
+		}
+		request.PresetID = preset.ID
+	}
+	if *system != "" {
+		request.Messages = append(request.Messages, connectors.ChatMessage{Role: connectors.RoleSystem, Content: *system})
+	}
+	message := connectors.ChatMessage{Role: connectors.RoleUser, Content: prompt}
+	for _, path := range imagePaths {
+		image, err := loadImage(path)
+		if err != nil {
+			return err
+		}
+		message.Images = append(message.Images, image)
+	}
+	request.Messages = append(request.Messages, message)
+	if err := validateChatRequest(request); err != nil {
+		return err
+	}
+
+	if *useTools {
+		c.app.connectMCPServers()
+		defer c.app.closeMCPServers()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var response string
+	var err error
+	info, _ := c.app.registry.Info(*provider)
+	if *noStream || !info.Capabilities.Streaming {
+		response, err = c.app.chatWithHistory(ctx, request)
+		fmt.Fprint(c.out, response)
+	} else {
+		response, err = c.app.streamChatWithHistory(ctx, request, func(delta string) {
+			fmt.Fprint(c.out, delta)
+		})
+	}
+	if response != "" && !strings.HasSuffix(response, "\n") {
+		fmt.Fprintln(c.out)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("cancelled")
+	}
+	if err == nil && len(sources) > 0 {
+		fmt.Fprintln(os.Stderr, "Sources:")
+		for i, hit := range sources {
+			fmt.Fprintf(os.Stderr, "  [%d] %s\n", i+1, filepath.Join(hit.Root, hit.Citation))
+		}
+	}
+	return err
+}
+
+// knowledge manages the knowledge folders and searches them.
+func (c *cli) knowledge(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch {
+	case args[0] == "ls" && len(args) == 1:
+		return c.listKnowledge()
+	case args[0] == "add" && len(args) == 2:
+		root, err := c.app.addKnowledgeFolder(args[1])
+		if err != nil {
+			return err
+		}
+		return c.indexKnowledge(root)
+	case args[0] == "rm" && len(args) == 2:
+		return c.app.RemoveKnowledgeFolder(args[1])
+	case args[0] == "index" && len(args) <= 2:
+		roots := c.app.GetKnowledgeConfig().Folders
+		if len(args) == 2 {
+			root, err := c.app.knowledgeFolder(args[1])
+			if err != nil {
+				return err
+			}
+			roots = []string{root}
+		}
+		for _, root := range roots {
+			if err := c.indexKnowledge(root); err != nil {
+				return err
+			}
+		}
+		return nil
+	case args[0] == "model" && (len(args) == 3 || len(args) == 4):
+		topK := 0
+		if len(args) == 4 {
+			n, err := strconv.Atoi(args[3])
+			if err != nil {
+				return fmt.Errorf("%w: TOP_K must be a number", errUsage)
+			}
+			topK = n
+		}
+		return c.app.SetKnowledgeSettings(args[1], args[2], topK)
+	case args[0] == "search" && len(args) >= 2:
+		hits, err := c.app.SearchKnowledge(strings.Join(args[1:], " "), 0)
+		if err != nil {
+			return err
+		}
+		for _, hit := range hits {
+			fmt.Fprintf(c.out, "%.3f\t%s\n", hit.Score, filepath.Join(hit.Root, hit.Citation))
+		}
+		return nil
+	}
+	return errUsage
+}
+
+// mcp manages MCP servers. Commands that connect to a server stop it again
+// before returning.
+func (c *cli) mcp(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	defer c.app.closeMCPServers()
+	switch {
+	case args[0] == "ls" && len(args) == 1:
+		for _, server := range c.app.GetMCPServers() {
+			target := server.Config.URL
+			if server.Config.Transport == MCPTransportStdio {
+				target = strings.Join(append([]string{server.Config.Command}, server.Config.Args...), " ")
+			}
+			enabled := "disabled"
+			if server.Config.Enabled {
+				enabled = "enabled"
+			}
+			fmt.Fprintf(c.out, "%s\t%s\t%s\t%s\n", server.Name, server.Config.Transport, enabled, target)
+		}
+		return nil
+	case args[0] == "add" && len(args) >= 2:
+		return c.addMCPServer(args[1], args[2:])
+	case args[0] == "rm" && len(args) == 2:
+		return c.app.RemoveMCPServer(args[1])
+	case (args[0] == "enable" || args[0] == "disable") && len(args) == 2:
+		status, err := c.app.SetMCPServerEnabled(args[1], args[0] == "enable")
+		if err != nil {
+			return err
+		}
+		return c.printMCPStatus(status)
+	case args[0] == "inspect" && len(args) == 2:
+		if status := c.app.connectMCPServer(args[1]); status.State == MCPStateDisabled {
+			return fmt.Errorf("MCP server %s is disabled", args[1])
+		}
+		details, err := c.app.InspectMCPServer(args[1])
+		if err != nil {
+			return err
+		}
+		if err := c.printMCPStatus(details.MCPServerStatus); err != nil {
+			return err
+		}
+		for _, tool := range details.Tools {
+			fmt.Fprintf(c.out, "tool\t%s\t%s\n", tool.Name, firstLine(tool.Description))
+		}
+		for _, resource := range details.Resources {
+			fmt.Fprintf(c.out, "resource\t%s\t%s\n", resource.URI, resource.Name)
+		}
+		for _, prompt := range details.Prompts {
+			fmt.Fprintf(c.out, "prompt\t%s\t%s\n", prompt.Name, firstLine(prompt.Description))
+		}
+		return nil
+	}
+	return errUsage
+}
+
+// addMCPServer adds a stdio server from a command line, or an HTTP server
+// from a URL, and reports whether it connects.
+func (c *cli) addMCPServer(name string, args []string) error {
+	flags := flag.NewFlagSet("mcp add", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	dir := flags.String("dir", "", "working directory of the server command")
+	var env, headers repeatedFlag
+	flags.Var(&env, "env", "environment variable of the server command; may be repeated")
+	flags.Var(&headers, "header", "HTTP header sent to the server; may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+
+	config := MCPServerConfig{Enabled: true}
+	target := flags.Arg(0)
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		if flags.NArg() > 1 || len(env) > 0 || *dir != "" {
+			return fmt.Errorf("%w: an HTTP server takes only a URL and headers", errUsage)
+		}
+		config.Transport = MCPTransportHTTP
+		config.URL = target
+	} else {
+		if len(headers) > 0 {
+			return fmt.Errorf("%w: headers are only sent to HTTP servers", errUsage)
+		}
+		config.Transport = MCPTransportStdio
+		config.Command = target
+		config.Args = flags.Args()[1:]
+		config.Dir = *dir
+	}
+	var err error
+	if config.Env, err = parseKeyValues(env); err != nil {
+		return err
+	}
+	if config.Headers, err = parseKeyValues(headers); err != nil {
+		return err
+	}
+
+	status, err := c.app.AddMCPServer(name, config)
+	if err != nil {
+		return err
+	}
+	return c.printMCPStatus(status)
+}
+
+// printMCPStatus prints a server's connection state, failing for errors.
+func (c *cli) printMCPStatus(status MCPServerStatus) error {
+	switch status.State {
+	case MCPStateError:
+		return fmt.Errorf("MCP server %s failed: %s", status.Name, status.Error)
+	case MCPStateConnected:
+		fmt.Fprintf(c.out, "%s: connected to %s %s, %d tools\n", status.Name, status.Server.Name, status.Server.Version, len(status.ToolNames))
+	default:
+		fmt.Fprintf(c.out, "%s: %s\n", status.Name, status.State)
+	}
+	return nil
+}
+
+// providers manages custom OpenAI-compatible providers, which then work
+// with models scan, chat and config like the built-in ones.
+func (c *cli) providers(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch {
+	case args[0] == "ls" && len(args) == 1:
+		for _, provider := range c.app.GetCustomProviders() {
+			models := provider.Config.ModelSource
+			if models == CustomModelsFromList {
+				models = strings.Join(provider.Config.Models, ",")
+			}
+			key := "no key"
+			if provider.Config.HasAPIKey {
+				key = "key"
+			}
+			fmt.Fprintf(c.out, "%s\t%s\t%s\t%s\n", provider.Name, provider.Config.BaseURL, models, key)
+		}
+		return nil
+	case args[0] == "add" && len(args) >= 2:
+		return c.addCustomProvider(args[1], args[2:])
+	case args[0] == "rm" && len(args) == 2:
+		return c.app.RemoveCustomProvider(args[1])
+	}
+	return errUsage
+}
+
+// addCustomProvider parses the arguments of providers add. --models takes a
+// comma-separated list that replaces the server's own.
+func (c *cli) addCustomProvider(name string, args []string) error {
+	flags := flag.NewFlagSet("providers add", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	key := flags.String("key", "", "API key sent as a bearer token")
+	models := flags.String("models", "", "comma-separated models to offer instead of the server's list")
+	var headers repeatedFlag
+	flags.Var(&headers, "header", "HTTP header sent with every request; may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	config := CustomProviderConfig{BaseURL: flags.Arg(0), ModelSource: CustomModelsFromServer}
+	if *models != "" {
+		config.ModelSource = CustomModelsFromList
+		config.Models = strings.Split(*models, ",")
+	}
+	var err error
+	if config.Headers, err = parseKeyValues(headers); err != nil {
+		return err
+	}
+
+	provider, err := c.app.AddCustomProvider(name, config, *key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%s: added %s\n", provider.Name, provider.Config.BaseURL)
+	return nil
+}
+
+// parseKeyValues parses KEY=VALUE arguments.
+func parseKeyValues(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%w: expected KEY=VALUE, got %q", errUsage, value)
+		}
+		parsed[key] = val
+	}
+	return parsed, nil
+}
+
+// firstLine returns the first line of a description.
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+// listKnowledge prints the embedding model and the knowledge folders.
+func (c *cli) listKnowledge() error {
+	config := c.app.GetKnowledgeConfig()
+	if config.Provider != "" {
+		fmt.Fprintf(c.out, "model\t%s/%s\n", config.Provider, config.Model)
+	}
+	folders, err := c.app.GetKnowledgeFolders()
+	if err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		state := "not indexed"
+		switch {
+		case folder.Stale:
+			state = "indexed with " + folder.Provider + "/" + folder.Model
+		case !folder.UpdatedAt.IsZero():
+			state = "updated " + folder.UpdatedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(c.out, "%s\t%d files\t%d chunks\t%s\n", folder.Root, folder.Files, folder.Chunks, state)
+	}
+	return nil
+}
+
+// indexKnowledge brings a knowledge folder's index up to date, writing
+// progress and skipped files to stderr.
+func (c *cli) indexKnowledge(root string) error {
+	embedding, err := c.app.knowledgeEmbedding()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := c.app.knowledgeStore.Update(ctx, root, embedding, func(p rag.Progress) {
+		fmt.Fprintf(os.Stderr, "\r\033[K%s %d/%d %s", root, p.Done, p.Total, p.File)
+	})
+	fmt.Fprint(os.Stderr, "\r\033[K")
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %s\n", skipped)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("cancelled; %d files indexed so far", result.Files)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%s: %d added, %d updated, %d removed, %d unchanged (%d files, %d chunks)\n",
+		root, result.Added, result.Updated, result.Removed, result.Unchanged, result.Files, result.Chunks)
+	return nil
+}
+
+// models scans providers for models or manages Ollama's local models.
+func (c *cli) models(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch {
+	case args[0] == "scan":
+		return c.scanModels(args[1:])
+	case args[0] == "pull" && len(args) == 2:
+		return c.pullModel(args[1])
+	case args[0] == "rm" && len(args) == 2:
+		return c.app.DeleteOllamaModel(args[1])
+	case args[0] == "cp" && len(args) == 3:
+		return c.app.CopyOllamaModel(args[1], args[2])
+	case args[0] == "show" && len(args) == 2:
+		info, err := c.app.ShowOllamaModel(args[1])
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out, string(data))
+		return nil
+	}
+	return errUsage
+}
+
+// pullModel downloads an Ollama model, writing progress to stderr.
+func (c *cli) pullModel(model string) error {
+	ollama, err := c.app.ollamaConnector()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = c.app.pullOllamaModel(ctx, ollama, model, func(p OllamaPullProgress) {
+		if p.Percent >= 0 {
+			fmt.Fprintf(os.Stderr, "\r\033[K%s %5.1f%%", p.Status, p.Percent)
+		} else {
+			fmt.Fprintf(os.Stderr, "\r\033[K%s", p.Status)
+		}
+	})
+	fmt.Fprintln(os.Stderr)
+	return err
+}
+
+// scanModels lists the models available from local and custom providers, or
+// from the providers named on the command line.
+func (c *cli) scanModels(providers []string) error {
+	if len(providers) == 0 {
+		for _, info := range c.app.registry.List() {
+			if c.app.hasEndpoint(info) {
+				providers = append(providers, info.Name)
+			}
+		}
+	}
+
+	failed := 0
+	for _, name := range providers {
+		models, err := c.app.listModels(context.Background(), name, true)
+		if err == nil {
+			for _, model := range models {
+				fmt.Fprintf(c.out, "%s\t%s\t%s\n", name, model.Name, model.Size)
+			}
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "lumen: %s: %v\n", name, err)
+		failed++
+	}
+	if failed == len(providers) && failed > 0 {
+		return fmt.Errorf("no provider could be scanned")
+	}
+	return nil
+}
+
+// config reads and writes settings in ~/.lumen/config.json. Keys are:
+//
+//	endpoint.PROVIDER                 local or custom provider endpoint
+//	api_key.PROVIDER                  cloud API key (get reports only its state, e.g. valid)
+//	model.PROVIDER/MODEL.PARAM        generation parameter, e.g. model.ollama/llama3.temperature
+//	api_server.enabled|port           OpenAI-compatible API server
+//	workspace                         folder the workspace tools of chat --tools may read and change
+func (c *cli) config(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch {
+	case args[0] == "get" && len(args) == 1:
+		return c.printConfig()
+	case args[0] == "get" && len(args) == 2:
+		value, err := c.getConfig(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out, value)
+		return nil
+	case args[0] == "set" && len(args) == 3:
+		return c.setConfig(args[1], args[2])
+	case args[0] == "unset" && len(args) == 2:
+		return c.unsetConfig(args[1])
+	case args[0] == "export":
+		return c.exportConfig(args[1:])
+	case args[0] == "import":
+		return c.importConfig(args[1:])
+	}
+	return errUsage
+}
+
+// exportConfig writes a config bundle to FILE, or stdout for "-". With --keys
+// the API keys are included, encrypted with LUMEN_BUNDLE_PASSPHRASE.
+func (c *cli) exportConfig(args []string) error {
+	flags := flag.NewFlagSet("config export", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	sections := flags.String("sections", "", "comma-separated sections, e.g. model_configs,presets")
+	keys := flags.Bool("keys", false, "include API keys")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	options := ExportOptions{Passphrase: os.Getenv("LUMEN_BUNDLE_PASSPHRASE")}
+	if *sections != "" {
+		options.Sections = strings.Split(*sections, ",")
+	}
+	if *keys {
+		if options.Passphrase == "" {
+			return fmt.Errorf("set LUMEN_BUNDLE_PASSPHRASE to encrypt the exported API keys")
+		}
+		if len(options.Sections) == 0 {
+			options.Sections = slices.Clone(defaultExportSections)
+		}
+		options.Sections = append(options.Sections, BundleSectionAPIKeys)
+	}
+
+	if path := flags.Arg(0); path != "-" {
+		_, err := c.app.ExportConfig(path, options)
+		return err
+	}
+	data, err := c.app.exportConfig(options)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.out, string(data))
+	return err
+}
+
+// importConfig applies a config bundle from FILE, or stdin for "-", and prints
+// what changed. Sections are merged unless listed in --overwrite or --skip.
+// API keys in the bundle are decrypted with LUMEN_BUNDLE_PASSPHRASE.
+func (c *cli) importConfig(args []string) error {
+	flags := flag.NewFlagSet("config import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	overwrite := flags.String("overwrite", "", "comma-separated sections where the bundle wins conflicts")
+	skip := flags.String("skip", "", "comma-separated sections to leave alone")
+	dryRun := flags.Bool("dry-run", false, "report changes without applying them")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	options := ImportOptions{
+		Modes:      make(map[string]string),
+		Passphrase: os.Getenv("LUMEN_BUNDLE_PASSPHRASE"),
+		DryRun:     *dryRun,
+	}
+	for mode, list := range map[string]string{ImportOverwrite: *overwrite, ImportSkip: *skip} {
+		if list == "" {
+			continue
+		}
+		for _, section := range strings.Split(list, ",") {
+			options.Modes[section] = mode
+		}
+	}
+
+	var data []byte
+	var err error
+	if path := flags.Arg(0); path == "-" {
+		data, err = io.ReadAll(c.in)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	report, err := c.app.importConfig(data, options)
+	for _, section := range report.Sections {
+		if section.Mode == ImportSkip {
+			fmt.Fprintf(c.out, "%s: skipped\n", section.Section)
+			continue
+		}
+		fmt.Fprintf(c.out, "%s (%s): %d added, %d conflicts, %d unchanged\n",
+			section.Section, section.Mode, len(section.Added), len(section.Conflicts), section.Unchanged)
+		for _, key := range section.Added {
+			fmt.Fprintf(c.out, "  + %s\n", key)
+		}
+		resolution := "kept local"
+		if section.Mode == ImportOverwrite {
+			resolution = "overwritten"
+		}
+		for _, key := range section.Conflicts {
+			fmt.Fprintf(c.out, "  ! %s (%s)\n", key, resolution)
+		}
+		for _, reason := range section.Skipped {
+			fmt.Fprintf(c.out, "  - %s\n", reason)
+		}
+	}
+	if err == nil && report.DryRun {
+		fmt.Fprintln(c.out, "Dry run: nothing was changed.")
+	}
+	return err
+}
+
+func (c *cli) printConfig() error {
+	var keys []string
+	for _, info := range c.app.registry.List() {
+		if c.app.hasEndpoint(info) {
+			keys = append(keys, "endpoint."+info.Name)
+		}
+		if info.Capabilities.RequiresAPIKey {
+			keys = append(keys, "api_key."+info.Name)
+		}
+	}
+
+	c.app.configMutex.RLock()
+	var modelKeys []string
+	for key := range c.app.modelConfigs {
+		for _, param := range modelParams {
+			modelKeys = append(modelKeys, "model."+key+"."+param)
+		}
+	}
+	c.app.configMutex.RUnlock()
+	sort.Strings(modelKeys)
+	keys = append(keys, modelKeys...)
+	keys = append(keys, "api_server.enabled", "api_server.port", "workspace")
+
+	for _, key := range keys {
+		value, err := c.getConfig(key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "%s=%s\n", key, value)
+	}
+	return nil
+}
+
+func (c *cli) getConfig(key string) (string, error) {
+	section, name, _ := strings.Cut(key, ".")
+	switch section {
+	case "endpoint":
+		if _, ok := c.app.registry.Info(name); !ok {
+			return "", fmt.Errorf("unsupported provider: %s", name)
+		}
+		return c.app.providerEndpoint(name), nil
+	case "api_key":
+		if info, ok := c.app.registry.Info(name); !ok || !info.Capabilities.RequiresAPIKey {
+			return "", fmt.Errorf("unsupported provider: %s", name)
+		}
+		return "(" + c.app.providerStatus(name).State + ")", nil
+	case "model":
+		provider, model, param, err := parseModelKey(name)
+		if err != nil {
+			return "", err
+		}
+		config, err := c.app.GetModelConfig(provider, model)
+		if err != nil {
+			return "", err
+		}
+		return getModelParam(config, param)
+	case "api_server":
+		status, err := c.app.GetAPIServerStatus()
+		if err != nil {
+			return "", err
+		}
+		switch name {
+		case "enabled":
+			return strconv.FormatBool(status.Enabled), nil
+		case "port":
+			return strconv.Itoa(status.Port), nil
+		}
+	case "workspace":
+		if name == "" {
+			return c.app.GetWorkspace(), nil
+		}
+	}
+	return "", fmt.Errorf("unknown config key %q", key)
+}
+
+func (c *cli) setConfig(key string, value string) error {
+	section, name, _ := strings.Cut(key, ".")
+	switch section {
+	case "endpoint":
+		return c.app.SetProviderEndpoint(name, value)
+	case "api_key":
+		return c.app.ConnectCloudModel(name, value)
+	case "model":
+		provider, model, param, err := parseModelKey(name)
+		if err != nil {
+			return err
+		}
+		config, err := c.app.GetModelConfig(provider, model)
+		if err != nil {
+			return err
+		}
+		if err := setModelParam(&config, param, value); err != nil {
+			return err
+		}
+		if err := c.app.validateModelConfig(provider, model, config); err != nil {
+			return err
+		}
+		c.app.configMutex.Lock()
+		c.app.modelConfigs[provider+"/"+model] = config
+		c.app.configMutex.Unlock()
+		return c.app.saveConfig()
+	case "api_server":
+		switch name {
+		case "enabled":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %q", key, value)
+			}
+			c.app.configMutex.Lock()
+			c.app.apiServerConfig.Enabled = enabled
+			c.app.configMutex.Unlock()
+			return c.app.saveConfig()
+		case "port":
+			port, err := strconv.Atoi(value)
+			if err != nil || port <= 0 || port > 65535 {
+				return fmt.Errorf("invalid value for %s: %q", key, value)
+			}
+			c.app.configMutex.Lock()
+			c.app.apiServerConfig.Port = port
+			c.app.configMutex.Unlock()
+			return c.app.saveConfig()
+		}
+	case "workspace":
+		if name == "" {
+			_, err := c.app.SetWorkspace(value)
+			return err
+		}
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+func (c *cli) unsetConfig(key string) error {
+	section, name, _ := strings.Cut(key, ".")
+	switch section {
+	case "endpoint":
+		return c.app.ResetProviderEndpoint(name)
+	case "api_key":
+		return c.app.storeAPIKey(name, "", false)
+	case "model":
+		provider, model, _, err := parseModelKey(name + ".")
+		if err != nil {
+			return err
+		}
+		c.app.configMutex.Lock()
+		delete(c.app.modelConfigs, provider+"/"+model)
+		c.app.configMutex.Unlock()
+		return c.app.saveConfig()
+	case "workspace":
+		if name == "" {
+			return c.app.CloseWorkspace()
+		}
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+// confirmWrite shows the changes a model wants to make to the workspace
+// and asks whether to write them. Without a terminal on stdin they are declined.
+func (c *cli) confirmWrite(ctx context.Context, write WorkspaceWrite) (bool, error) {
+	for _, change := range write.Changes {
+		fmt.Fprintf(os.Stderr, "%s\n", change.Diff)
+	}
+	if c.stdinIsPiped() {
+		fmt.Fprintln(os.Stderr, "Declined the changes: stdin is not a terminal to confirm them on.")
+		return false, nil
+	}
+	fmt.Fprintf(os.Stderr, "Apply these changes to %s? [y/N] ", write.Root)
+	answer, err := bufio.NewReader(c.in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, nil
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// repeatedFlag collects every value of a flag that may be given more than once.
+type repeatedFlag []string
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *repeatedFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a terminal.
+func (c *cli) stdinIsPiped() bool {
+	stat, err := c.in.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice == 0
+}
+
+var modelParams = []string{"temperature", "top_p", "top_k", "repeat_penalty", "num_ctx", "stop",
+	"max_output_tokens", "seed", "presence_penalty", "frequency_penalty", "min_p"}
+
+// parseModelKey splits "PROVIDER/MODEL.PARAM". Model names may contain dots
+// and slashes, so the parameter is taken from after the last dot.
+func parseModelKey(key string) (string, string, string, error) {
+	dot := strings.LastIndex(key, ".")
+	if dot < 0 {
+		return "", "", "", fmt.Errorf("model keys have the form model.PROVIDER/MODEL.PARAM")
+	}
+	provider, model, ok := strings.Cut(key[:dot], "/")
+	if !ok || provider == "" || model == "" {
+		return "", "", "", fmt.Errorf("model keys have the form model.PROVIDER/MODEL.PARAM")
+	}
+	return provider, model, key[dot+1:], nil
+}
+
+func getModelParam(config ModelConfig, param string) (string, error) {
+	switch param {
+	case "temperature":
+		return strconv.FormatFloat(config.Temperature, 'g', -1, 64), nil
+	case "top_p":
+		return strconv.FormatFloat(config.TopP, 'g', -1, 64), nil
+	case "top_k":
+		return strconv.Itoa(config.TopK), nil
+	case "repeat_penalty":
+		return strconv.FormatFloat(config.RepeatPenalty, 'g', -1, 64), nil
+	case "num_ctx":
+		return strconv.Itoa(config.NumCtx), nil
+	case "stop":
+		return strings.Join(config.Stop, ","), nil
+	case "max_output_tokens":
+		return strconv.Itoa(config.MaxOutputTokens), nil
+	case "seed":
+		if config.Seed == nil {
+			return "", nil
+		}
+		return strconv.Itoa(*config.Seed), nil
+	case "presence_penalty":
+		return strconv.FormatFloat(config.PresencePenalty, 'g', -1, 64), nil
+	case "frequency_penalty":
+		return strconv.FormatFloat(config.FrequencyPenalty, 'g', -1, 64), nil
+	case "min_p":
+		return strconv.FormatFloat(config.MinP, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("unknown model parameter %q", param)
+}
+
+func setModelParam(config *ModelConfig, param string, value string) error {
+	var err error
+	switch param {
+	case "temperature":
+		config.Temperature, err = strconv.ParseFloat(value, 64)
+	case "top_p":
+		config.TopP, err = strconv.ParseFloat(value, 64)
+	case "top_k":
+		config.TopK, err = strconv.Atoi(value)
+	case "repeat_penalty":
+		config.RepeatPenalty, err = strconv.ParseFloat(value, 64)
+	case "num_ctx":
+		config.NumCtx, err = strconv.Atoi(value)
+	case "stop":
+		config.Stop = []string{}
+		if value != "" {
+			config.Stop = strings.Split(value, ",")
+		}
+	case "max_output_tokens":
+		config.MaxOutputTokens, err = strconv.Atoi(value)
+	case "seed":
+		// An empty value clears the seed so sampling is random again
+		config.Seed = nil
+		if value != "" {
+			var seed int
+			seed, err = strconv.Atoi(value)
+			config.Seed = &seed
+		}
+	case "presence_penalty":
+		config.PresencePenalty, err = strconv.ParseFloat(value, 64)
+	case "frequency_penalty":
+		config.FrequencyPenalty, err = strconv.ParseFloat(value, 64)
+	case "min_p":
+		config.MinP, err = strconv.ParseFloat(value, 64)
+	default:
+		return fmt.Errorf("unknown model parameter %q", param)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %q", param, value)
+	}
+	return nil
+}
@@ -0,0 +1,637 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"myproject/connectors"
+	"myproject/internal/atomicfile"
+	"myproject/secrets"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Sections of a config bundle.
+const (
+	BundleSectionCustomProviders = "custom_providers"
+	BundleSectionEndpoints       = "endpoints"
+	BundleSectionModelConfigs    = "model_configs"
+	BundleSectionPresets         = "presets"
+	BundleSectionModelPresets    = "model_presets"
+	BundleSectionAPIKeys         = "api_keys"
+)
+
+// bundleSections lists every section in import order; custom providers and
+// presets come before the entries that refer to them.
+var bundleSections = []string{
+	BundleSectionCustomProviders,
+	BundleSectionEndpoints,
+	BundleSectionModelConfigs,
+	BundleSectionPresets,
+	BundleSectionModelPresets,
+	BundleSectionAPIKeys,
+}
+
+// defaultExportSections are exported when no sections are given: every
+// section except API keys.
+var defaultExportSections = []string{
+	BundleSectionCustomProviders,
+	BundleSectionEndpoints,
+	BundleSectionModelConfigs,
+	BundleSectionPresets,
+	BundleSectionModelPresets,
+}
+
+// Import modes for a bundle section. Merge adds new entries and keeps local
+// values on conflict; overwrite lets the bundle win. Neither removes local
+// entries missing from the bundle.
+const (
+	ImportMerge     = "merge"
+	ImportOverwrite = "overwrite"
+	ImportSkip      = "skip"
+)
+
+const (
+	configBundleFormat  = "lumen-config-bundle"
+	configBundleVersion = 1
+)
+
+// ConfigBundle is a portable export of the settings a team may want to share.
+type ConfigBundle struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	AppVersion string    `json:"app_version,omitempty"`
+	// CustomProviders are keyed by name. Their API keys and header values
+	// are left out; whoever imports them adds their own.
+	CustomProviders map[string]CustomProviderConfig `json:"custom_providers,omitempty"`
+	Endpoints       map[string]string               `json:"endpoints,omitempty"`
+	ModelConfigs    map[string]ModelConfig          `json:"model_configs,omitempty"`
+	// Presets maps preset names to system prompts; names are unique, IDs are per install.
+	Presets map[string]string `json:"presets,omitempty"`
+	// ModelPresets attaches presets by name, keyed by "provider/model".
+	ModelPresets map[string]string `json:"model_presets,omitempty"`
+	// APIKeys are keyed by provider and encrypted with the export passphrase.
+	APIKeys *secrets.Sealed `json:"api_keys,omitempty"`
+}
+
+// ExportOptions selects what goes into a config bundle.
+type ExportOptions struct {
+	// Sections to export. Empty exports everything except API keys.
+	Sections []string `json:"sections"`
+	// Passphrase encrypts the API keys; required when exporting them.
+	Passphrase string `json:"passphrase"`
+}
+
+// ImportOptions controls how a config bundle is applied.
+type ImportOptions struct {
+	// Modes maps a section to merge, overwrite or skip. Unlisted sections are merged.
+	Modes map[string]string `json:"modes"`
+	// Passphrase decrypts the bundle's API keys.
+	Passphrase string `json:"passphrase"`
+	// DryRun reports what would change without changing anything.
+	DryRun bool `json:"dry_run"`
+}
+
+// ImportReport describes the outcome of importing a config bundle.
+type ImportReport struct {
+	DryRun   bool                  `json:"dry_run"`
+	Sections []ImportSectionReport `json:"sections"`
+}
+
+// ImportSectionReport lists what happened to each entry of one section.
+// Conflicts are entries whose local value differs from the bundle's; under
+// merge they kept the local value, under overwrite they took the bundle's.
+type ImportSectionReport struct {
+	Section   string   `json:"section"`
+	Mode      string   `json:"mode"`
+	Added     []string `json:"added"`
+	Conflicts []string `json:"conflicts"`
+	Unchanged int      `json:"unchanged"`
+	// Skipped lists entries that cannot be used here, with the reason.
+	Skipped []string `json:"skipped"`
+}
+
+// ExportConfig writes a config bundle to path, asking for a file when path
+// is empty. It returns the path written, or "" if the dialog was cancelled.
+func (a *App) ExportConfig(path string, options ExportOptions) (string, error) {
+	if path == "" {
+		if a.ctx == nil {
+			return "", fmt.Errorf("an export path is required")
+		}
+		var err error
+		path, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "Export Lumen configuration",
+			DefaultFilename: "lumen-config.json",
+			Filters:         []runtime.FileFilter{{DisplayName: "Lumen config bundle (*.json)", Pattern: "*.json"}},
+		})
+		if err != nil || path == "" {
+			return "", err
+		}
+	}
+
+	data, err := a.exportConfig(options)
+	if err != nil {
+		return "", err
+	}
+	if err := atomicfile.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return path, nil
+}
+
+// ImportConfig applies a config bundle read from path, asking for a file
+// when path is empty.
+func (a *App) ImportConfig(path string, options ImportOptions) (ImportReport, error) {
+	if path == "" {
+		if a.ctx == nil {
+			return ImportReport{}, fmt.Errorf("an import path is required")
+		}
+		var err error
+		path, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+			Title:   "Import Lumen configuration",
+			Filters: []runtime.FileFilter{{DisplayName: "Lumen config bundle (*.json)", Pattern: "*.json"}},
+		})
+		if err != nil || path == "" {
+			return ImportReport{}, err
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	return a.importConfig(data, options)
+}
+
+// exportConfig builds a config bundle and returns it as JSON.
+func (a *App) exportConfig(options ExportOptions) ([]byte, error) {
+	sections := options.Sections
+	if len(sections) == 0 {
+		sections = defaultExportSections
+	}
+
+	bundle := ConfigBundle{
+		Format:     configBundleFormat,
+		Version:    configBundleVersion,
+		ExportedAt: time.Now(),
+	}
+
+	a.configMutex.RLock()
+	bundle.AppVersion = a.appInfo.Version
+	for _, section := range sections {
+		switch section {
+		case BundleSectionCustomProviders:
+			bundle.CustomProviders = make(map[string]CustomProviderConfig, len(a.customProviderConfigs))
+			for name, config := range a.customProviderConfigs {
+				config.HasAPIKey = false
+				bundle.CustomProviders[name] = config
+			}
+		case BundleSectionEndpoints:
+			bundle.Endpoints = make(map[string]string, len(a.providerEndpoints))
+			for provider, endpoint := range a.providerEndpoints {
+				bundle.Endpoints[provider] = endpoint
+			}
+		case BundleSectionModelConfigs:
+			bundle.ModelConfigs = make(map[string]ModelConfig, len(a.modelConfigs))
+			for key, config := range a.modelConfigs {
+				bundle.ModelConfigs[key] = config
+			}
+		case BundleSectionPresets:
+			bundle.Presets = make(map[string]string, len(a.presets))
+			for _, preset := range a.presets {
+				bundle.Presets[preset.Name] = preset.SystemPrompt
+			}
+		case BundleSectionModelPresets:
+			bundle.ModelPresets = make(map[string]string, len(a.modelPresets))
+			for key, presetID := range a.modelPresets {
+				if preset, ok := a.presets[presetID]; ok {
+					bundle.ModelPresets[key] = preset.Name
+				}
+			}
+		case BundleSectionAPIKeys:
+		default:
+			a.configMutex.RUnlock()
+			return nil, fmt.Errorf("unknown bundle section %q", section)
+		}
+	}
+	a.configMutex.RUnlock()
+
+	if slices.Contains(sections, BundleSectionAPIKeys) {
+		if options.Passphrase == "" {
+			return nil, fmt.Errorf("a passphrase is required to export API keys")
+		}
+		keys := make(map[string]string)
+		for _, info := range a.registry.List() {
+			if !info.Capabilities.RequiresAPIKey {
+				continue
+			}
+			status := a.providerStatus(info.Name)
+			if status.State == KeyStateLocked {
+				return nil, fmt.Errorf("secret store is locked; unlock it to export API keys")
+			}
+			if !status.HasKey {
+				continue
+			}
+			apiKey, err := a.GetAPIKey(info.Name)
+			if err != nil {
+				return nil, err
+			}
+			keys[info.Name] = apiKey
+		}
+		sealed, err := secrets.Seal(keys, options.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		bundle.APIKeys = sealed
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	return data, nil
+}
+
+// importConfig applies a config bundle given as JSON.
+func (a *App) importConfig(data []byte, options ImportOptions) (ImportReport, error) {
+	var bundle ConfigBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return ImportReport{}, fmt.Errorf("not a valid config bundle: %w", err)
+	}
+	if bundle.Format != configBundleFormat {
+		return ImportReport{}, fmt.Errorf("not a Lumen config bundle")
+	}
+	if bundle.Version > configBundleVersion {
+		return ImportReport{}, fmt.Errorf("bundle version %d is newer than this app supports", bundle.Version)
+	}
+
+	modes := make(map[string]string, len(bundleSections))
+	for _, section := range bundleSections {
+		modes[section] = ImportMerge
+	}
+	for section, mode := range options.Modes {
+		if _, ok := modes[section]; !ok {
+			return ImportReport{}, fmt.Errorf("unknown bundle section %q", section)
+		}
+		if mode != ImportMerge && mode != ImportOverwrite && mode != ImportSkip {
+			return ImportReport{}, fmt.Errorf("unknown import mode %q for %s", mode, section)
+		}
+		modes[section] = mode
+	}
+
+	// Decrypt keys up front so a wrong passphrase fails before anything changes.
+	var apiKeys map[string]string
+	if bundle.APIKeys != nil && modes[BundleSectionAPIKeys] != ImportSkip {
+		if options.Passphrase == "" {
+			return ImportReport{}, fmt.Errorf("the bundle contains API keys: enter its passphrase or skip the %s section", BundleSectionAPIKeys)
+		}
+		var err error
+		if apiKeys, err = bundle.APIKeys.Open(options.Passphrase); err != nil {
+			return ImportReport{}, err
+		}
+	}
+
+	report := ImportReport{DryRun: options.DryRun}
+	// Custom providers that a dry run would have added
+	var pendingProviders map[string]CustomProviderConfig
+	a.configMutex.Lock()
+	for _, section := range bundleSections {
+		mode := modes[section]
+		if section == BundleSectionAPIKeys {
+			continue
+		}
+		if mode == ImportSkip {
+			report.Sections = append(report.Sections, ImportSectionReport{Section: section, Mode: mode})
+			continue
+		}
+
+		var sectionReport ImportSectionReport
+		switch section {
+		case BundleSectionCustomProviders:
+			var incoming map[string]CustomProviderConfig
+			incoming, sectionReport = a.importCustomProviders(mode, bundle.CustomProviders, options.DryRun)
+			if options.DryRun {
+				pendingProviders = incoming
+			}
+		case BundleSectionEndpoints:
+			incoming, skipped := a.usableEndpoints(bundle.Endpoints, pendingProviders)
+			sectionReport = importEntries(section, mode, a.providerEndpoints, incoming, options.DryRun)
+			sectionReport.Skipped = skipped
+		case BundleSectionModelConfigs:
+			incoming, skipped := a.usableModelConfigs(bundle.ModelConfigs, pendingProviders)
+			sectionReport = importEntries(section, mode, a.modelConfigs, incoming, options.DryRun)
+			sectionReport.Skipped = skipped
+		case BundleSectionPresets:
+			sectionReport = a.importPresets(mode, bundle.Presets, options.DryRun)
+		case BundleSectionModelPresets:
+			// A dry run has not created the bundle's presets yet.
+			var pending map[string]string
+			if options.DryRun && modes[BundleSectionPresets] != ImportSkip {
+				pending = bundle.Presets
+			}
+			sectionReport = a.importModelPresets(mode, bundle.ModelPresets, pending, options.DryRun)
+		}
+		report.Sections = append(report.Sections, sectionReport)
+	}
+	a.configMutex.Unlock()
+
+	if !options.DryRun {
+		if err := a.saveConfig(); err != nil {
+			return report, err
+		}
+	}
+
+	keysReport, err := a.importAPIKeys(modes[BundleSectionAPIKeys], apiKeys, options.DryRun)
+	report.Sections = append(report.Sections, keysReport)
+	return report, err
+}
+
+// importEntries merges incoming into existing according to mode. existing is
+// only modified when dryRun is false. Callers hold configMutex.
+func importEntries[V any](section string, mode string, existing map[string]V, incoming map[string]V, dryRun bool) ImportSectionReport {
+	report := ImportSectionReport{Section: section, Mode: mode}
+	if mode == ImportSkip {
+		return report
+	}
+	for _, key := range sortedKeys(incoming) {
+		value := incoming[key]
+		current, exists := existing[key]
+		switch {
+		case !exists:
+			report.Added = append(report.Added, key)
+		case reflect.DeepEqual(current, value):
+			report.Unchanged++
+			continue
+		default:
+			report.Conflicts = append(report.Conflicts, key)
+			if mode == ImportMerge {
+				continue
+			}
+		}
+		if !dryRun {
+			existing[key] = value
+		}
+	}
+	return report
+}
+
+// importCustomProviders adds the bundle's custom providers and registers
+// them, keeping local API keys and header values. It returns the providers
+// that are usable here. Callers hold configMutex.
+func (a *App) importCustomProviders(mode string, providers map[string]CustomProviderConfig, dryRun bool) (map[string]CustomProviderConfig, ImportSectionReport) {
+	usable := make(map[string]CustomProviderConfig, len(providers))
+	var skipped []string
+	for _, name := range sortedKeys(providers) {
+		local, exists := a.customProviderConfigs[name]
+		if !customProviderNamePattern.MatchString(name) {
+			skipped = append(skipped, name+": invalid provider name")
+			continue
+		}
+		if _, registered := a.registry.Info(name); registered && !exists {
+			skipped = append(skipped, name+": a built-in provider has this name")
+			continue
+		}
+		config, err := normalizeCustomProvider(providers[name])
+		if err != nil {
+			skipped = append(skipped, name+": "+err.Error())
+			continue
+		}
+		config.Headers = secretNames(config.Headers)
+		config.HasAPIKey = exists && local.HasAPIKey
+		usable[name] = config
+	}
+
+	report := importEntries(BundleSectionCustomProviders, mode, a.customProviderConfigs, usable, dryRun)
+	report.Skipped = skipped
+	if dryRun {
+		return usable, report
+	}
+	for _, name := range append(report.Added, conflictsToApply(report)...) {
+		// The base URL replaces any endpoint set for the provider
+		delete(a.providerEndpoints, name)
+		a.registry.Unregister(name)
+		a.modelCache.Invalidate(name)
+		if err := a.registerCustomProvider(name, usable[name]); err != nil {
+			delete(a.customProviderConfigs, name)
+			report.Skipped = append(report.Skipped, name+": "+err.Error())
+		}
+	}
+	return usable, report
+}
+
+// bundleProviderInfo looks a provider up in the registry or, during a dry
+// run, among the custom providers the bundle would add.
+func (a *App) bundleProviderInfo(name string, pending map[string]CustomProviderConfig) (connectors.ProviderInfo, bool) {
+	if info, ok := a.registry.Info(name); ok {
+		return info, true
+	}
+	config, ok := pending[name]
+	if !ok {
+		return connectors.ProviderInfo{}, false
+	}
+	p := connectors.NewOpenAICompatibleConnector(name, config.BaseURL, "", nil, config.Models)
+	return connectors.ProviderInfo{
+		Name:            name,
+		DefaultEndpoint: config.BaseURL,
+		Capabilities:    p.Capabilities(),
+		Parameters:      p.Parameters(),
+	}, true
+}
+
+// usableEndpoints drops endpoints for unknown providers and for those whose
+// endpoint cannot be set, and normalizes the rest. pending holds the custom
+// providers a dry run would add. Callers hold configMutex.
+func (a *App) usableEndpoints(endpoints map[string]string, pending map[string]CustomProviderConfig) (map[string]string, []string) {
+	usable := make(map[string]string, len(endpoints))
+	var skipped []string
+	for _, provider := range sortedKeys(endpoints) {
+		_, bundled := pending[provider]
+		if info, ok := a.bundleProviderInfo(provider, pending); !ok || !(bundled || a.hasEndpointLocked(info)) {
+			skipped = append(skipped, provider+": unsupported provider")
+			continue
+		}
+		normalized, err := normalizeEndpoint(endpoints[provider])
+		if err != nil {
+			skipped = append(skipped, provider+": "+err.Error())
+			continue
+		}
+		usable[provider] = normalized
+	}
+	return usable, skipped
+}
+
+// usableModelConfigs drops model configs for unknown providers and configs
+// outside their provider's parameter ranges. pending holds the custom
+// providers a dry run would add.
+func (a *App) usableModelConfigs(configs map[string]ModelConfig, pending map[string]CustomProviderConfig) (map[string]ModelConfig, []string) {
+	usable := make(map[string]ModelConfig, len(configs))
+	var skipped []string
+	for _, key := range sortedKeys(configs) {
+		provider, model, _ := strings.Cut(key, "/")
+		info, ok := a.bundleProviderInfo(provider, pending)
+		if !ok || model == "" {
+			skipped = append(skipped, key+": unsupported provider")
+			continue
+		}
+		config := configs[key]
+		if err := a.checkModelConfig(info, model, config); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		if config.Stop == nil {
+			config.Stop = []string{}
+		}
+		usable[key] = config
+	}
+	return usable, skipped
+}
+
+// importPresets matches presets by name, keeping local IDs. Callers hold configMutex.
+func (a *App) importPresets(mode string, incoming map[string]string, dryRun bool) ImportSectionReport {
+	existing := make(map[string]string, len(a.presets))
+	ids := make(map[string]string, len(a.presets))
+	for id, preset := range a.presets {
+		existing[preset.Name] = preset.SystemPrompt
+		ids[preset.Name] = id
+	}
+
+	cleaned := make(map[string]string, len(incoming))
+	var skipped []string
+	for _, name := range sortedKeys(incoming) {
+		trimmed := strings.TrimSpace(name)
+		if trimmed == "" {
+			skipped = append(skipped, "preset without a name")
+			continue
+		}
+		cleaned[trimmed] = strings.TrimSpace(incoming[name])
+	}
+
+	report := importEntries(BundleSectionPresets, mode, existing, cleaned, dryRun)
+	report.Skipped = skipped
+	if dryRun {
+		return report
+	}
+
+	now := time.Now()
+	for name, prompt := range existing {
+		id, ok := ids[name]
+		if !ok {
+			newID, err := newGenerationID()
+			if err != nil {
+				report.Skipped = append(report.Skipped, name+": "+err.Error())
+				continue
+			}
+			a.presets[newID] = Preset{ID: newID, Name: name, SystemPrompt: prompt, CreatedAt: now, UpdatedAt: now}
+			continue
+		}
+		if preset := a.presets[id]; preset.SystemPrompt != prompt {
+			preset.SystemPrompt = prompt
+			preset.UpdatedAt = now
+			a.presets[id] = preset
+		}
+	}
+	return report
+}
+
+// importModelPresets attaches presets to models by name. pending names
+// presets that a dry run would have imported; a.modelPresets is only
+// modified when dryRun is false. Callers hold configMutex.
+func (a *App) importModelPresets(mode string, incoming map[string]string, pending map[string]string, dryRun bool) ImportSectionReport {
+	ids := make(map[string]string, len(a.presets))
+	names := make(map[string]string, len(a.presets))
+	for id, preset := range a.presets {
+		ids[preset.Name] = id
+		names[id] = preset.Name
+	}
+
+	existing := make(map[string]string, len(a.modelPresets))
+	for key, presetID := range a.modelPresets {
+		existing[key] = names[presetID]
+	}
+
+	usable := make(map[string]string, len(incoming))
+	var skipped []string
+	for _, key := range sortedKeys(incoming) {
+		name := strings.TrimSpace(incoming[key])
+		_, known := ids[name]
+		_, bundled := pending[name]
+		if !known && !bundled {
+			skipped = append(skipped, key+": preset "+name+" not found")
+			continue
+		}
+		usable[key] = name
+	}
+
+	report := importEntries(BundleSectionModelPresets, mode, existing, usable, dryRun)
+	report.Skipped = skipped
+	if dryRun {
+		return report
+	}
+	for key, name := range existing {
+		if id, ok := ids[name]; ok {
+			a.modelPresets[key] = id
+		}
+	}
+	return report
+}
+
+// importAPIKeys saves decrypted API keys through the usual key lifecycle.
+// Imported keys are not validated and show as unverified.
+func (a *App) importAPIKeys(mode string, incoming map[string]string, dryRun bool) (ImportSectionReport, error) {
+	report := ImportSectionReport{Section: BundleSectionAPIKeys, Mode: mode}
+	if mode == ImportSkip || len(incoming) == 0 {
+		return report, nil
+	}
+
+	existing := make(map[string]string)
+	usable := make(map[string]string, len(incoming))
+	for _, provider := range sortedKeys(incoming) {
+		if info, ok := a.registry.Info(provider); !ok || !info.Capabilities.RequiresAPIKey {
+			report.Skipped = append(report.Skipped, provider+": unsupported provider")
+			continue
+		}
+		usable[provider] = incoming[provider]
+		if a.providerStatus(provider).HasKey {
+			apiKey, err := a.GetAPIKey(provider)
+			if err != nil {
+				return report, err
+			}
+			existing[provider] = apiKey
+		}
+	}
+
+	planned := importEntries(BundleSectionAPIKeys, mode, existing, usable, true)
+	planned.Skipped = report.Skipped
+	if dryRun {
+		return planned, nil
+	}
+	for _, provider := range append(planned.Added, conflictsToApply(planned)...) {
+		if err := a.storeAPIKey(provider, usable[provider], false); err != nil {
+			return planned, err
+		}
+	}
+	return planned, nil
+}
+
+// conflictsToApply returns the conflicting entries the bundle wins.
+func conflictsToApply(report ImportSectionReport) []string {
+	if report.Mode == ImportOverwrite {
+		return report.Conflicts
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"myproject/internal/atomicfile"
+	"os"
+	"time"
+)
+
+// configMigration upgrades a config written before build to the schema that
+// build introduced. It works on the raw JSON so that renamed or reshaped
+// fields can still be read.
+type configMigration struct {
+	build       int
+	description string
+	migrate     func(config map[string]json.RawMessage) error
+}
+
+// configMigrations is the ordered chain of schema changes, keyed by the
+// VersionInfo.BuildNumber that introduced them. It is empty until a release
+// changes the schema: append new entries at the end, keyed to that release's
+// buildNumber in version.json.
+var configMigrations = []configMigration{}
+
+// migrateConfig brings raw config data up to the schema of build. It returns
+// the migrated data and the schema version the file was written with. Files
+// from before schema_version existed are dated by their app_details build.
+func migrateConfig(data []byte, build int) ([]byte, int, error) {
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, 0, err
+	}
+	if config == nil {
+		return nil, 0, fmt.Errorf("config is not a JSON object")
+	}
+
+	var version int
+	if raw, ok := config["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, 0, fmt.Errorf("invalid schema_version: %w", err)
+		}
+	} else if raw, ok := config["app_details"]; ok {
+		var details struct {
+			BuildNumber int `json:"buildNumber"`
+		}
+		if err := json.Unmarshal(raw, &details); err != nil {
+			return nil, 0, fmt.Errorf("invalid app_details: %w", err)
+		}
+		version = details.BuildNumber
+	}
+	if version >= build {
+		return data, version, nil
+	}
+
+	for _, m := range configMigrations {
+		if m.build <= version || m.build > build {
+			continue
+		}
+		if err := m.migrate(config); err != nil {
+			return nil, version, fmt.Errorf("migration to build %d (%s) failed: %w", m.build, m.description, err)
+		}
+		fmt.Printf("Migrated config to build %d: %s\n", m.build, m.description)
+	}
+	if err := setConfigField(config, "schema_version", build); err != nil {
+		return nil, version, err
+	}
+
+	migrated, err := json.Marshal(config)
+	if err != nil {
+		return nil, version, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	return migrated, version, nil
+}
+
+// setConfigField replaces a top-level field of a raw config.
+func setConfigField(config map[string]json.RawMessage, field string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", field, err)
+	}
+	config[field] = data
+	return nil
+}
+
+// backupConfig copies the config file next to itself with the given suffix
+// before it is overwritten, and returns the backup path.
+func (a *App) backupConfig(suffix string) (string, error) {
+	data, err := os.ReadFile(a.configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config for backup: %w", err)
+	}
+	path := a.configPath + "." + suffix
+	if err := atomicfile.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// backupCorruptConfig moves an unreadable config aside so it can be recovered
+// by hand, and returns the backup path.
+func (a *App) backupCorruptConfig() (string, error) {
+	path := a.configPath + ".corrupt-" + time.Now().Format("20060102-150405")
+	if err := os.Rename(a.configPath, path); err != nil {
+		return "", fmt.Errorf("failed to move corrupt config aside: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,53 @@
+package connectors
+
+import (
+	"sync"
+	"time"
+)
+
+// ModelCache keeps the latest model list of each provider for a while, so
+// metadata lookups do not have to query the provider every time.
+type ModelCache struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]modelCacheEntry
+}
+
+type modelCacheEntry struct {
+	models    []Model
+	fetchedAt time.Time
+}
+
+// NewModelCache creates a cache whose entries expire after ttl.
+func NewModelCache(ttl time.Duration) *ModelCache {
+	return &ModelCache{
+		ttl:     ttl,
+		entries: make(map[string]modelCacheEntry),
+	}
+}
+
+// Get returns a provider's cached models if they have not expired.
+func (c *ModelCache) Get(provider string) ([]Model, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[provider]
+	if !ok || time.Since(entry.fetchedAt) > c.ttl {
+		return nil, false
+	}
+	return entry.models, true
+}
+
+// Put stores a provider's freshly listed models.
+func (c *ModelCache) Put(provider string, models []Model) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[provider] = modelCacheEntry{models: models, fetchedAt: time.Now()}
+}
+
+// Invalidate drops a provider's cached models, e.g. after its endpoint or
+// API key changed or a model was added or removed.
+func (c *ModelCache) Invalidate(provider string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, provider)
+}
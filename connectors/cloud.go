@@ -1,6 +1,7 @@
 package connectors
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,13 +9,13 @@ import (
 	"time"
 )
 
-// CloudConnector handles API validation for cloud models.
+// CloudConnector holds the API key and HTTP helpers shared by the cloud providers.
 type CloudConnector struct {
 	Provider string
 	APIKey   string
 }
 
-// NewCloudConnector creates a new connector for cloud models.
+// NewCloudConnector creates the shared base for a cloud provider connector.
 func NewCloudConnector(provider, apiKey string) *CloudConnector {
 	return &CloudConnector{
 		Provider: provider,
@@ -22,52 +23,27 @@ func NewCloudConnector(provider, apiKey string) *CloudConnector {
 	}
 }
 
-// TestConnection sends a test request to validate the API key.
-func (c *CloudConnector) TestConnection() error {
-	switch c.Provider {
-	case "openai":
-		return c.testOpenAI()
-	case "anthropic":
-		return c.testAnthropic()
-	case "google":
-		return c.testGoogle()
-	// Add other providers here
-	default:
-		return fmt.Errorf("unsupported provider: %s", c.Provider)
-	}
-}
-
-// ListModels fetches the available models from the cloud provider.
-func (c *CloudConnector) ListModels() ([]Model, error) {
-	switch c.Provider {
-	case "openai":
-		return c.listOpenAIModels()
-	case "anthropic":
-		return c.listAnthropicModels()
-	case "google":
-		return c.listGoogleModels()
-	default:
-		return nil, fmt.Errorf("unsupported provider for listing models: %s", c.Provider)
-	}
-}
-
-func (c *CloudConnector) testOpenAI() error {
-	req, _ := http.NewRequest("GET", "https://api.openai.com/v1/models", nil)
+// HealthCheck validates the API key by listing models.
+func (c *OpenAIConnector) HealthCheck(ctx context.Context) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.openai.com/v1/models", nil)
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
 	return c.sendTestRequest(req)
 }
 
-func (c *CloudConnector) testAnthropic() error {
-	req, _ := http.NewRequest("GET", "https://api.anthropic.com/v1/messages", nil)
+// HealthCheck validates the API key by listing models; the Messages API
+// only accepts POST.
+func (c *AnthropicConnector) HealthCheck(ctx context.Context) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.anthropic.com/v1/models?limit=1", nil)
 	req.Header.Set("x-api-key", c.APIKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 	return c.sendTestRequest(req)
 }
 
-func (c *CloudConnector) testGoogle() error {
+// HealthCheck validates the API key by listing models.
+func (c *GoogleConnector) HealthCheck(ctx context.Context) error {
 	// Google uses API keys in the URL, so we'll use a simple list models request
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models?key=%s", c.APIKey)
-	req, _ := http.NewRequest("GET", url, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	return c.sendTestRequest(req)
 }
 
@@ -87,8 +63,9 @@ func (c *CloudConnector) sendTestRequest(req *http.Request) error {
 
 // --- Model Listing Implementations ---
 
-func (c *CloudConnector) listOpenAIModels() ([]Model, error) {
-	req, _ := http.NewRequest("GET", "https://api.openai.com/v1/models", nil)
+// ListModels fetches the models available to the API key.
+func (c *OpenAIConnector) ListModels(ctx context.Context) ([]Model, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.openai.com/v1/models", nil)
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
 
 	client := &http.Client{Timeout: 10 * time.Second}
@@ -104,33 +81,76 @@ func (c *CloudConnector) listOpenAIModels() ([]Model, error) {
 
 	var openAIResp struct {
 		Data []struct {
-			ID string `json:"id"`
+			ID      string `json:"id"`
+			Created int64  `json:"created"`
 		} `json:"data"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
 		return nil, fmt.Errorf("failed to decode OpenAI response: %w", err)
 	}
 
+	// The models API reports only IDs and creation dates.
 	var models []Model
 	for _, m := range openAIResp.Data {
-		models = append(models, Model{Name: m.ID})
+		model := Model{Name: m.ID}
+		if m.Created > 0 {
+			model.Modified = time.Unix(m.Created, 0).Format("2006-01-02")
+		}
+		models = append(models, model)
 	}
 	return models, nil
 }
 
-func (c *CloudConnector) listAnthropicModels() ([]Model, error) {
-	// Anthropic does not have a public models API. We return a static list.
-	// This can be updated if they release a models API endpoint.
-	return []Model{
-		{Name: "claude-3-opus-20240229"},
-		{Name: "claude-3-sonnet-20240229"},
-		{Name: "claude-3-haiku-20240307"},
-	}, nil
+// ListModels fetches the Claude models available to the API key.
+func (c *AnthropicConnector) ListModels(ctx context.Context) ([]Model, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.anthropic.com/v1/models?limit=1000", nil)
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to Anthropic: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Anthropic API error: %s", resp.Status)
+	}
+
+	var anthropicResp struct {
+		Data []struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+			CreatedAt   string `json:"created_at"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
+		return nil, fmt.Errorf("failed to decode Anthropic response: %w", err)
+	}
+
+	// Every Claude model served by the API takes images, supports tool use and
+	// has a 200K-token context window.
+	var models []Model
+	for _, m := range anthropicResp.Data {
+		models = append(models, Model{
+			Name:           m.ID,
+			DisplayName:    m.DisplayName,
+			Modified:       formatDate(m.CreatedAt),
+			Family:         "claude",
+			ContextLength:  200000,
+			Modalities:     []string{ModalityText, ModalityImage},
+			SupportsTools:  true,
+			SupportsVision: true,
+		})
+	}
+	return models, nil
 }
 
-func (c *CloudConnector) listGoogleModels() ([]Model, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models?key=%s", c.APIKey)
-	req, _ := http.NewRequest("GET", url, nil)
+// ListModels fetches the Gemini models available to the API key.
+func (c *GoogleConnector) ListModels(ctx context.Context) ([]Model, error) {
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models?pageSize=1000&key=%s", c.APIKey)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
@@ -145,7 +165,11 @@ func (c *CloudConnector) listGoogleModels() ([]Model, error) {
 
 	var googleResp struct {
 		Models []struct {
-			Name string `json:"name"`
+			Name                       string   `json:"name"`
+			DisplayName                string   `json:"displayName"`
+			InputTokenLimit            int      `json:"inputTokenLimit"`
+			OutputTokenLimit           int      `json:"outputTokenLimit"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
 		} `json:"models"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&googleResp); err != nil {
@@ -156,7 +180,28 @@ func (c *CloudConnector) listGoogleModels() ([]Model, error) {
 	for _, m := range googleResp.Models {
 		// The name from Google API is "models/gemini-pro", we need to strip "models/"
 		modelName := strings.TrimPrefix(m.Name, "models/")
-		models = append(models, Model{Name: modelName})
+		model := Model{
+			Name:            modelName,
+			DisplayName:     m.DisplayName,
+			ContextLength:   m.InputTokenLimit,
+			MaxOutputTokens: m.OutputTokenLimit,
+			Modalities:      []string{ModalityText},
+		}
+		// Gemini chat models are multimodal and support function calling;
+		// embedding and other non-chat models do not.
+		generates := false
+		for _, method := range m.SupportedGenerationMethods {
+			if method == "generateContent" {
+				generates = true
+			}
+		}
+		if generates && strings.HasPrefix(modelName, "gemini-") {
+			model.Family = "gemini"
+			model.Modalities = []string{ModalityText, ModalityImage, ModalityAudio}
+			model.SupportsTools = true
+			model.SupportsVision = true
+		}
+		models = append(models, model)
 	}
 	return models, nil
 }
\ No newline at end of file
@@ -2,18 +2,95 @@ package connectors
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
 // Generic request and response structures, adaptable for different providers.
 
+// CloudChatMessage is a request message. Content is a string, or a list of
+// content parts when the message has images.
 type CloudChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+	// ToolCalls and ToolCallID are set on OpenAI-style tool turns.
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIContentPart is a text or image_url part of an OpenAI-style message.
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// openAITool declares a function in an OpenAI-style request.
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// openAIToolCall is a function call made by an OpenAI-style model. The
+// arguments are a JSON object encoded as a string.
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// anthropicContentBlock is a text, image, tool_use or tool_result block of
+// an Anthropic message.
+type anthropicContentBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+	// tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result blocks
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+// anthropicTool declares a tool in an Anthropic request.
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicResponse is a non-streamed Messages API response.
+type anthropicResponse struct {
+	Content []anthropicContentBlock `json:"content"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 
 type CloudChatRequest struct {
@@ -23,12 +100,21 @@ type CloudChatRequest struct {
 	MaxTokens   *int               `json:"max_tokens,omitempty"`
 	Temperature *float64           `json:"temperature,omitempty"`
 	TopP        *float64           `json:"top_p,omitempty"`
+	// MaxCompletionTokens replaces MaxTokens on OpenAI, whose reasoning
+	// models reject max_tokens.
+	MaxCompletionTokens *int         `json:"max_completion_tokens,omitempty"`
+	PresencePenalty     *float64     `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64     `json:"frequency_penalty,omitempty"`
+	Seed                *int         `json:"seed,omitempty"`
+	Stop                []string     `json:"stop,omitempty"`
+	Tools               []openAITool `json:"tools,omitempty"`
 }
 
 type CloudChatResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
 		} `json:"message"`
 	} `json:"choices"`
 	Error *struct {
@@ -36,25 +122,248 @@ type CloudChatResponse struct {
 	} `json:"error,omitempty"`
 }
 
-// Chat sends a chat request to the specified cloud provider.
-func (c *CloudConnector) Chat(model string, message string, config map[string]interface{}) (string, error) {
-	switch c.Provider {
-	case "openai":
-		return c.chatOpenAI(model, message, config)
-	case "anthropic":
-		return c.chatAnthropic(model, message, config)
-	case "google":
-		return c.chatGoogle(model, message, config)
-	default:
-		return "", fmt.Errorf("unsupported provider for chat: %s", c.Provider)
+// AnthropicRequest is the request body for Anthropic's Messages API.
+type AnthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []CloudChatMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Stream        bool               `json:"stream,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	TopK          *int               `json:"top_k,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+}
+
+// GooglePart is a single piece of content in a Gemini message: text, an
+// inline image, or a function call or its response.
+type GooglePart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *GoogleBlob             `json:"inline_data,omitempty"`
+	FunctionCall     *GoogleFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GoogleFunctionResponse `json:"functionResponse,omitempty"`
+	// ThoughtSignature accompanies function calls of thinking models and
+	// must be sent back with them.
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
+}
+
+// GoogleFunctionCall is a function call made by a Gemini model.
+type GoogleFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// GoogleFunctionResponse returns a function's result to a Gemini model.
+type GoogleFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// GoogleTool declares the functions a Gemini model may call.
+type GoogleTool struct {
+	FunctionDeclarations []GoogleFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GoogleFunctionDeclaration describes a function with a JSON Schema.
+type GoogleFunctionDeclaration struct {
+	Name                 string          `json:"name"`
+	Description          string          `json:"description,omitempty"`
+	ParametersJSONSchema json.RawMessage `json:"parametersJsonSchema"`
+}
+
+// GoogleBlob is base64 encoded media sent inline with a request.
+type GoogleBlob struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+
+// GoogleContent is a Gemini message; the assistant role is called "model".
+type GoogleContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GooglePart `json:"parts"`
+}
+
+// GoogleGenerationConfig holds Gemini sampling parameters.
+type GoogleGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	TopK             *int     `json:"topK,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+}
+
+// GoogleRequest is the request body for Gemini's generateContent APIs.
+type GoogleRequest struct {
+	SystemInstruction *GoogleContent          `json:"systemInstruction,omitempty"`
+	Contents          []GoogleContent         `json:"contents"`
+	GenerationConfig  *GoogleGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []GoogleTool            `json:"tools,omitempty"`
+}
+
+// GoogleResponse is a generateContent response, or one chunk of a streamed one.
+type GoogleResponse struct {
+	Candidates []struct {
+		Content      GoogleContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// anthropicDefaultMaxTokens is sent when no output limit is configured, since
+// Anthropic requires one.
+const anthropicDefaultMaxTokens = 4096
+
+// toCloudMessages converts chat messages to the OpenAI wire format. Images
+// are sent as image_url parts holding data URLs, ahead of the text.
+func toCloudMessages(messages []ChatMessage) []CloudChatMessage {
+	cloudMessages := make([]CloudChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == RoleTool {
+			cloudMessages = append(cloudMessages, CloudChatMessage{Role: RoleTool, Content: msg.Content, ToolCallID: msg.ToolCallID})
+			continue
+		}
+		if len(msg.ToolCalls) > 0 {
+			cloudMessage := CloudChatMessage{Role: msg.Role, ToolCalls: toOpenAIToolCalls(msg.ToolCalls)}
+			if msg.Content != "" {
+				cloudMessage.Content = msg.Content
+			}
+			cloudMessages = append(cloudMessages, cloudMessage)
+			continue
+		}
+		if len(msg.Images) == 0 {
+			cloudMessages = append(cloudMessages, CloudChatMessage{Role: msg.Role, Content: msg.Content})
+			continue
+		}
+		parts := make([]openAIContentPart, 0, len(msg.Images)+1)
+		for _, image := range msg.Images {
+			parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: image.DataURL()}})
+		}
+		if msg.Content != "" {
+			parts = append(parts, openAIContentPart{Type: "text", Text: msg.Content})
+		}
+		cloudMessages = append(cloudMessages, CloudChatMessage{Role: msg.Role, Content: parts})
+	}
+	return cloudMessages
+}
+
+// toAnthropicMessages converts chat messages to Anthropic's format, where
+// images are base64 image blocks placed before the text. Tool calls become
+// tool_use blocks, and tool results tool_result blocks in a user message
+// shared with any results and user text that follow.
+func toAnthropicMessages(messages []ChatMessage) []CloudChatMessage {
+	anthropicMessages := make([]CloudChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		role := msg.Role
+		var blocks []anthropicContentBlock
+		switch {
+		case msg.Role == RoleTool:
+			role = RoleUser
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		case len(msg.ToolCalls) > 0:
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, anthropicContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: toolCallInput(call)})
+			}
+		case len(msg.Images) == 0:
+			anthropicMessages = appendAnthropicMessage(anthropicMessages, CloudChatMessage{Role: msg.Role, Content: msg.Content})
+			continue
+		default:
+			for _, image := range msg.Images {
+				blocks = append(blocks, anthropicContentBlock{
+					Type:   "image",
+					Source: &anthropicImageSource{Type: "base64", MediaType: image.MimeType, Data: image.Data},
+				})
+			}
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+		}
+		anthropicMessages = appendAnthropicMessage(anthropicMessages, CloudChatMessage{Role: role, Content: blocks})
+	}
+	return anthropicMessages
+}
+
+// appendAnthropicMessage appends a message, joining it to the previous one
+// when both have the same role. Only tool turns are left to join here;
+// mergeConsecutiveTurns has joined the rest.
+func appendAnthropicMessage(messages []CloudChatMessage, msg CloudChatMessage) []CloudChatMessage {
+	n := len(messages)
+	if n == 0 || messages[n-1].Role != msg.Role {
+		return append(messages, msg)
+	}
+	messages[n-1].Content = append(anthropicBlocks(messages[n-1].Content), anthropicBlocks(msg.Content)...)
+	return messages
+}
+
+// anthropicBlocks returns message content as a list of blocks.
+func anthropicBlocks(content interface{}) []anthropicContentBlock {
+	switch content := content.(type) {
+	case []anthropicContentBlock:
+		return content
+	case string:
+		if content != "" {
+			return []anthropicContentBlock{{Type: "text", Text: content}}
+		}
+	}
+	return nil
+}
+
+// mergeConsecutiveTurns joins adjacent messages with the same role. Anthropic
+// and Gemini reject conversations where a role speaks twice in a row. Tool
+// calls and results are left apart, since each provider joins them in its
+// own way.
+func mergeConsecutiveTurns(messages []ChatMessage) []ChatMessage {
+	merged := make([]ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		if n := len(merged); n > 0 && merged[n-1].Role == msg.Role && !isToolTurn(merged[n-1]) && !isToolTurn(msg) {
+			merged[n-1].Content += "\n\n" + msg.Content
+			merged[n-1].Images = append(merged[n-1].Images[:len(merged[n-1].Images):len(merged[n-1].Images)], msg.Images...)
+			continue
+		}
+		merged = append(merged, msg)
+	}
+	return merged
+}
+
+// isToolTurn reports whether a message calls tools or carries a tool result.
+func isToolTurn(msg ChatMessage) bool {
+	return msg.Role == RoleTool || len(msg.ToolCalls) > 0
+}
+
+// --- OpenAI ---
+
+func (c *OpenAIConnector) newRequest(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}, stream bool) (*http.Request, error) {
+	requestBody := newOpenAIChatRequest(model, messages, tools, config, stream)
+	// OpenAI's reasoning models reject max_tokens
+	requestBody.MaxCompletionTokens, requestBody.MaxTokens = requestBody.MaxTokens, nil
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
 }
 
-func (c *CloudConnector) chatOpenAI(model string, message string, config map[string]interface{}) (string, error) {
+// newOpenAIChatRequest builds a Chat Completions request body from the
+// options produced by openAIParams.
+func newOpenAIChatRequest(model string, messages []ChatMessage, tools []Tool, config map[string]interface{}, stream bool) CloudChatRequest {
 	requestBody := CloudChatRequest{
 		Model:    model,
-		Messages: []CloudChatMessage{{Role: "user", Content: message}},
-		Stream:   false,
+		Messages: toCloudMessages(messages),
+		Stream:   stream,
+		Tools:    toOpenAITools(tools),
 	}
 	// Apply config if provided
 	if temp, ok := config["temperature"].(float64); ok {
@@ -66,34 +375,69 @@ func (c *CloudConnector) chatOpenAI(model string, message string, config map[str
 	if maxTokens, ok := config["max_tokens"].(int); ok && maxTokens > 0 {
 		requestBody.MaxTokens = &maxTokens
 	}
+	if penalty, ok := config["presence_penalty"].(float64); ok {
+		requestBody.PresencePenalty = &penalty
+	}
+	if penalty, ok := config["frequency_penalty"].(float64); ok {
+		requestBody.FrequencyPenalty = &penalty
+	}
+	if seed, ok := config["seed"].(int); ok {
+		requestBody.Seed = &seed
+	}
+	if stop, ok := config["stop"].([]string); ok && len(stop) > 0 {
+		requestBody.Stop = stop
+	}
+	return requestBody
+}
 
-	jsonData, err := json.Marshal(requestBody)
+// Chat sends an ordered conversation and waits for the full response.
+func (c *OpenAIConnector) Chat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}) (string, error) {
+	req, err := c.newRequest(ctx, model, messages, nil, config, false)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return "", err
 	}
+	message, err := c.sendChatRequest(req)
+	return message.Content, err
+}
 
-	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Content-Type", "application/json")
-
+// ChatWithTools sends a conversation with the tools the model may call.
+func (c *OpenAIConnector) ChatWithTools(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}) (ChatMessage, error) {
+	req, err := c.newRequest(ctx, model, messages, tools, config, false)
+	if err != nil {
+		return ChatMessage{}, err
+	}
 	return c.sendChatRequest(req)
 }
 
-func (c *CloudConnector) chatAnthropic(model string, message string, config map[string]interface{}) (string, error) {
-	// Anthropic has a different request structure
-	type AnthropicRequest struct {
-		Model         string             `json:"model"`
-		Messages      []CloudChatMessage `json:"messages"`
-		MaxTokens     int                `json:"max_tokens"`
-		Temperature   *float64           `json:"temperature,omitempty"`
-		TopP          *float64           `json:"top_p,omitempty"`
-		TopK          *int               `json:"top_k,omitempty"`
-		StopSequences []string           `json:"stop_sequences,omitempty"`
+// StreamChat sends an ordered conversation and reports text as it is generated.
+func (c *OpenAIConnector) StreamChat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}, onDelta StreamHandler) (string, error) {
+	req, err := c.newRequest(ctx, model, messages, nil, config, true)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.sendStreamRequest(req)
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
+
+	return readOpenAIStream(resp.Body, onDelta)
+}
+
+// --- Anthropic ---
+
+func (c *AnthropicConnector) newRequest(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}, stream bool) (*http.Request, error) {
+	// The system prompt is a top-level field and turns must alternate
+	system, turns := splitSystemMessages(messages)
 	requestBody := AnthropicRequest{
 		Model:     model,
-		Messages:  []CloudChatMessage{{Role: "user", Content: message}},
-		MaxTokens: 4096, // Default, can be overridden by config
+		System:    system,
+		Messages:  toAnthropicMessages(mergeConsecutiveTurns(turns)),
+		MaxTokens: anthropicDefaultMaxTokens,
+		Stream:    stream,
+	}
+	for _, tool := range tools {
+		requestBody.Tools = append(requestBody.Tools, anthropicTool{Name: tool.Name, Description: tool.Description, InputSchema: toolParameters(tool)})
 	}
 	if max, ok := config["max_tokens"].(int); ok && max > 0 {
 		requestBody.MaxTokens = max
@@ -113,84 +457,178 @@ func (c *CloudConnector) chatAnthropic(model string, message string, config map[
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
 	req.Header.Set("x-api-key", c.APIKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// Chat sends an ordered conversation and waits for the full response.
+func (c *AnthropicConnector) Chat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}) (string, error) {
+	req, err := c.newRequest(ctx, model, messages, nil, config, false)
+	if err != nil {
+		return "", err
+	}
+
+	message, err := c.sendMessagesRequest(req)
+	if err != nil {
+		return "", err
+	}
+	return message.Content, nil
+}
 
+// ChatWithTools sends a conversation with the tools the model may call.
+func (c *AnthropicConnector) ChatWithTools(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}) (ChatMessage, error) {
+	req, err := c.newRequest(ctx, model, messages, tools, config, false)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	return c.sendMessagesRequest(req)
+}
+
+// sendMessagesRequest sends a non-streamed Messages API request and returns
+// the reply's text and tool calls.
+func (c *AnthropicConnector) sendMessagesRequest(req *http.Request) (ChatMessage, error) {
 	// Custom response handling for Anthropic
 	client := &http.Client{Timeout: 120 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return ChatMessage{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var anthropicResp struct {
-		Content []struct {
-			Text string `json:"text"`
-		} `json:"content"`
-		Error *struct {
-			Message string `json:"message"`
-		} `json:"error,omitempty"`
-	}
-
+	var anthropicResp anthropicResponse
 	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
-		return "", fmt.Errorf("failed to decode anthropic response: %w", err)
+		return ChatMessage{}, fmt.Errorf("failed to decode anthropic response: %w", err)
 	}
 
 	if anthropicResp.Error != nil {
-		return "", fmt.Errorf("anthropic API error: %s", anthropicResp.Error.Message)
+		return ChatMessage{}, fmt.Errorf("anthropic API error: %s", anthropicResp.Error.Message)
+	}
+	message := ChatMessage{Role: RoleAssistant}
+	var text []string
+	for _, block := range anthropicResp.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: toolArguments(string(block.Input))})
+		}
+	}
+	message.Content = strings.Join(text, "")
+	if message.Content == "" && len(message.ToolCalls) == 0 {
+		return ChatMessage{}, fmt.Errorf("no response content from anthropic")
+	}
+	return message, nil
+}
+
+// StreamChat sends an ordered conversation and reports text as it is generated.
+func (c *AnthropicConnector) StreamChat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}, onDelta StreamHandler) (string, error) {
+	req, err := c.newRequest(ctx, model, messages, nil, config, true)
+	if err != nil {
+		return "", err
 	}
-	if len(anthropicResp.Content) > 0 {
-		return anthropicResp.Content[0].Text, nil
+	resp, err := c.sendStreamRequest(req)
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
 
-	return "", fmt.Errorf("no response content from anthropic")
+	var full strings.Builder
+	err = readSSE(resp.Body, func(event string, data string) error {
+		var payload struct {
+			Type  string `json:"type"`
+			Delta struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"delta"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error,omitempty"`
+		}
+		if err := json.Unmarshal([]byte(data), &payload); err != nil {
+			return fmt.Errorf("failed to parse anthropic stream event: %w", err)
+		}
+		switch payload.Type {
+		case "content_block_delta":
+			if payload.Delta.Type == "text_delta" && payload.Delta.Text != "" {
+				full.WriteString(payload.Delta.Text)
+				onDelta(payload.Delta.Text)
+			}
+		case "error":
+			if payload.Error != nil {
+				return fmt.Errorf("anthropic API error: %s", payload.Error.Message)
+			}
+			return fmt.Errorf("anthropic API error")
+		}
+		return nil
+	})
+	return full.String(), err
 }
 
-func (c *CloudConnector) chatGoogle(model string, message string, config map[string]interface{}) (string, error) {
-	// Google has a different request structure
-	type GoogleRequest struct {
-		Contents         []struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		} `json:"contents"`
-		GenerationConfig *struct {
-			Temperature     *float64 `json:"temperature,omitempty"`
-			TopP            *float64 `json:"topP,omitempty"`
-			TopK            *int     `json:"topK,omitempty"`
-			MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
-			StopSequences   []string `json:"stopSequences,omitempty"`
-		} `json:"generationConfig,omitempty"`
-	}
-	requestBody := GoogleRequest{
-		Contents: []struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		}{
-			{
-				Parts: []struct {
-					Text string `json:"text"`
-				}{
-					{Text: message},
-				},
-			},
-		},
-	}
-
-	genConfig := struct {
-		Temperature     *float64 `json:"temperature,omitempty"`
-		TopP            *float64 `json:"topP,omitempty"`
-		TopK            *int     `json:"topK,omitempty"`
-		MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
-		StopSequences   []string `json:"stopSequences,omitempty"`
-	}{}
+// --- Google ---
+
+func (c *GoogleConnector) newRequest(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}, stream bool) (*http.Request, error) {
+	// Gemini calls the assistant role "model" and takes the system prompt separately
+	system, turns := splitSystemMessages(messages)
+	requestBody := GoogleRequest{}
+	if system != "" {
+		requestBody.SystemInstruction = &GoogleContent{Parts: []GooglePart{{Text: system}}}
+	}
+	for _, msg := range mergeConsecutiveTurns(turns) {
+		role := "user"
+		if msg.Role == RoleAssistant {
+			role = "model"
+		}
+		var parts []GooglePart
+		switch {
+		case msg.Role == RoleTool:
+			// Gemini matches results to calls by name and order
+			parts = append(parts, GooglePart{FunctionResponse: &GoogleFunctionResponse{
+				Name:     msg.ToolName,
+				Response: map[string]interface{}{"result": msg.Content},
+			}})
+		case len(msg.ToolCalls) > 0:
+			if msg.Content != "" {
+				parts = append(parts, GooglePart{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, GooglePart{
+					FunctionCall:     &GoogleFunctionCall{Name: call.Name, Args: toolCallInput(call)},
+					ThoughtSignature: call.Signature,
+				})
+			}
+		default:
+			for _, image := range msg.Images {
+				parts = append(parts, GooglePart{InlineData: &GoogleBlob{MimeType: image.MimeType, Data: image.Data}})
+			}
+			if msg.Content != "" || len(parts) == 0 {
+				parts = append(parts, GooglePart{Text: msg.Content})
+			}
+		}
+		// Function responses share a turn with each other and any user text after them
+		if n := len(requestBody.Contents); n > 0 && requestBody.Contents[n-1].Role == role {
+			requestBody.Contents[n-1].Parts = append(requestBody.Contents[n-1].Parts, parts...)
+			continue
+		}
+		requestBody.Contents = append(requestBody.Contents, GoogleContent{
+			Role:  role,
+			Parts: parts,
+		})
+	}
+	if len(tools) > 0 {
+		declarations := make([]GoogleFunctionDeclaration, 0, len(tools))
+		for _, tool := range tools {
+			declarations = append(declarations, GoogleFunctionDeclaration{Name: tool.Name, Description: tool.Description, ParametersJSONSchema: toolParameters(tool)})
+		}
+		requestBody.Tools = []GoogleTool{{FunctionDeclarations: declarations}}
+	}
+
+	genConfig := GoogleGenerationConfig{}
 	configApplied := false
 
 	if temp, ok := config["temperature"].(float64); ok {
@@ -213,6 +651,18 @@ func (c *CloudConnector) chatGoogle(model string, message string, config map[str
 		genConfig.StopSequences = stop
 		configApplied = true
 	}
+	if penalty, ok := config["presence_penalty"].(float64); ok {
+		genConfig.PresencePenalty = &penalty
+		configApplied = true
+	}
+	if penalty, ok := config["frequency_penalty"].(float64); ok {
+		genConfig.FrequencyPenalty = &penalty
+		configApplied = true
+	}
+	if seed, ok := config["seed"].(int); ok {
+		genConfig.Seed = &seed
+		configApplied = true
+	}
 
 	if configApplied {
 		requestBody.GenerationConfig = &genConfig
@@ -220,81 +670,186 @@ func (c *CloudConnector) chatGoogle(model string, message string, config map[str
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", model, c.APIKey)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if stream {
+		url = fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:streamGenerateContent?alt=sse&key=%s", model, c.APIKey)
+	}
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// Chat sends an ordered conversation and waits for the full response.
+func (c *GoogleConnector) Chat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}) (string, error) {
+	req, err := c.newRequest(ctx, model, messages, nil, config, false)
+	if err != nil {
+		return "", err
+	}
+
+	message, err := c.sendGenerateRequest(req)
+	if err != nil {
+		return "", err
+	}
+	return message.Content, nil
+}
 
+// ChatWithTools sends a conversation with the functions the model may call.
+func (c *GoogleConnector) ChatWithTools(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}) (ChatMessage, error) {
+	req, err := c.newRequest(ctx, model, messages, tools, config, false)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	return c.sendGenerateRequest(req)
+}
+
+// sendGenerateRequest sends a generateContent request and returns the
+// reply's text and function calls. Gemini does not identify its calls, so
+// they are given IDs here.
+func (c *GoogleConnector) sendGenerateRequest(req *http.Request) (ChatMessage, error) {
 	// Custom response handling for Google
 	client := &http.Client{Timeout: 120 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return ChatMessage{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read google response body: %w", err)
+		return ChatMessage{}, fmt.Errorf("failed to read google response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("google API error (%s): %s", resp.Status, string(body))
-	}
-
-	var googleResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-			FinishReason string `json:"finishReason"`
-		} `json:"candidates"`
-		Error *struct {
-			Message string `json:"message"`
-		} `json:"error,omitempty"`
+		return ChatMessage{}, fmt.Errorf("google API error (%s): %s", resp.Status, string(body))
 	}
 
+	var googleResp GoogleResponse
 	if err := json.Unmarshal(body, &googleResp); err != nil {
-		return "", fmt.Errorf("failed to decode google response: %w. Response: %s", err, string(body))
+		return ChatMessage{}, fmt.Errorf("failed to decode google response: %w. Response: %s", err, string(body))
 	}
 
 	if googleResp.Error != nil {
-		return "", fmt.Errorf("google API error: %s", googleResp.Error.Message)
-	}
-	if len(googleResp.Candidates) > 0 && len(googleResp.Candidates[0].Content.Parts) > 0 {
-		return googleResp.Candidates[0].Content.Parts[0].Text, nil
+		return ChatMessage{}, fmt.Errorf("google API error: %s", googleResp.Error.Message)
+	}
+	if len(googleResp.Candidates) > 0 {
+		message := ChatMessage{Role: RoleAssistant}
+		var text []string
+		for _, part := range googleResp.Candidates[0].Content.Parts {
+			if part.FunctionCall != nil {
+				message.ToolCalls = append(message.ToolCalls, ToolCall{
+					ID:        newToolCallID(),
+					Name:      part.FunctionCall.Name,
+					Arguments: toolArguments(string(part.FunctionCall.Args)),
+					Signature: part.ThoughtSignature,
+				})
+			} else if part.Text != "" {
+				text = append(text, part.Text)
+			}
+		}
+		message.Content = strings.Join(text, "")
+		if message.Content != "" || len(message.ToolCalls) > 0 {
+			return message, nil
+		}
 	}
 
 	if len(googleResp.Candidates) > 0 && googleResp.Candidates[0].FinishReason != "" {
-		return "", fmt.Errorf("google model finished with reason: '%s'. This can be due to safety filters or an invalid request", googleResp.Candidates[0].FinishReason)
+		return ChatMessage{}, fmt.Errorf("google model finished with reason: '%s'. This can be due to safety filters or an invalid request", googleResp.Candidates[0].FinishReason)
+	}
+
+	return ChatMessage{}, fmt.Errorf("no response content from google. Raw response: %s", string(body))
+}
+
+// StreamChat sends an ordered conversation and reports text as it is generated.
+func (c *GoogleConnector) StreamChat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}, onDelta StreamHandler) (string, error) {
+	req, err := c.newRequest(ctx, model, messages, nil, config, true)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.sendStreamRequest(req)
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
 
-	return "", fmt.Errorf("no response content from google. Raw response: %s", string(body))
+	var full strings.Builder
+	finishReason := ""
+	err = readSSE(resp.Body, func(_ string, data string) error {
+		var chunk GoogleResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode google stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("google API error: %s", chunk.Error.Message)
+		}
+		for _, candidate := range chunk.Candidates {
+			for _, part := range candidate.Content.Parts {
+				if part.Text == "" {
+					continue
+				}
+				full.WriteString(part.Text)
+				onDelta(part.Text)
+			}
+			if candidate.FinishReason != "" {
+				finishReason = candidate.FinishReason
+			}
+		}
+		return nil
+	})
+	if err == nil && full.Len() == 0 && finishReason != "" && finishReason != "STOP" {
+		return "", fmt.Errorf("google model finished with reason: '%s'. This can be due to safety filters or an invalid request", finishReason)
+	}
+	return full.String(), err
 }
 
-func (c *CloudConnector) sendChatRequest(req *http.Request) (string, error) {
+// sendChatRequest sends a non-streamed OpenAI-style chat request and returns
+// the reply's text and tool calls.
+func (c *CloudConnector) sendChatRequest(req *http.Request) (ChatMessage, error) {
 	client := &http.Client{Timeout: 120 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return ChatMessage{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("failed to read response: %w", err)
+	}
 	var chatResp CloudChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	decodeErr := json.Unmarshal(body, &chatResp)
+	if decodeErr == nil && chatResp.Error != nil {
+		return ChatMessage{}, fmt.Errorf("API error: %s", chatResp.Error.Message)
 	}
-
-	if chatResp.Error != nil {
-		return "", fmt.Errorf("API error: %s", chatResp.Error.Message)
+	// OpenAI-compatible servers do not always report errors in OpenAI's format
+	if resp.StatusCode != http.StatusOK {
+		return ChatMessage{}, fmt.Errorf("%s API error (%s): %s", c.Provider, resp.Status, string(body))
+	}
+	if decodeErr != nil {
+		return ChatMessage{}, fmt.Errorf("failed to decode response: %w", decodeErr)
 	}
 	if len(chatResp.Choices) > 0 {
-		return chatResp.Choices[0].Message.Content, nil
+		message := chatResp.Choices[0].Message
+		return ChatMessage{Role: RoleAssistant, Content: message.Content, ToolCalls: fromOpenAIToolCalls(message.ToolCalls)}, nil
 	}
 
-	return "", fmt.Errorf("no response choices from provider")
-}
\ No newline at end of file
+	return ChatMessage{}, fmt.Errorf("no response choices from provider")
+}
+
+// sendStreamRequest starts a streamed request and returns the open response.
+// The caller must close the body.
+func (c *CloudConnector) sendStreamRequest(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := newStreamClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s API error (%s): %s", c.Provider, resp.Status, string(body))
+	}
+	return resp, nil
+}
@@ -0,0 +1,112 @@
+package connectors
+
+// OpenAIConnector talks to the OpenAI API.
+type OpenAIConnector struct {
+	CloudConnector
+}
+
+// NewOpenAIConnector creates a connector for OpenAI.
+func NewOpenAIConnector(apiKey string) *OpenAIConnector {
+	return &OpenAIConnector{CloudConnector: *NewCloudConnector("openai", apiKey)}
+}
+
+// AnthropicConnector talks to the Anthropic Messages API.
+type AnthropicConnector struct {
+	CloudConnector
+}
+
+// NewAnthropicConnector creates a connector for Anthropic.
+func NewAnthropicConnector(apiKey string) *AnthropicConnector {
+	return &AnthropicConnector{CloudConnector: *NewCloudConnector("anthropic", apiKey)}
+}
+
+// GoogleConnector talks to the Gemini API.
+type GoogleConnector struct {
+	CloudConnector
+}
+
+// NewGoogleConnector creates a connector for Google Gemini.
+func NewGoogleConnector(apiKey string) *GoogleConnector {
+	return &GoogleConnector{CloudConnector: *NewCloudConnector("google", apiKey)}
+}
+
+// Name returns the provider ID.
+func (c *CloudConnector) Name() string {
+	return c.Provider
+}
+
+// Capabilities reports that cloud providers need a key and support streaming
+// chat with images and tools.
+func (c *CloudConnector) Capabilities() Capabilities {
+	return Capabilities{
+		RequiresAPIKey: true,
+		Chat:           true,
+		Streaming:      true,
+		Images:         true,
+		Tools:          true,
+	}
+}
+
+// openAIParams are the Chat Completions sampling parameters. OpenAI has no
+// top_k, min_p or repeat penalty, and allows at most four stop sequences.
+var openAIParams = []ParamSpec{
+	temperatureParam,
+	topPParam,
+	presencePenaltyParam,
+	frequencyPenaltyParam,
+	{Name: ParamMaxOutputTokens, Type: ParamInt, Min: 1, Max: 128000, Option: "max_tokens"},
+	seedParam,
+	{Name: ParamStop, Type: ParamStringList, MaxItems: 4},
+}
+
+// anthropicParams are the Messages API sampling parameters. max_tokens is
+// required by the API, so requests fall back to Default when it is unset.
+var anthropicParams = []ParamSpec{
+	{Name: ParamTemperature, Type: ParamFloat, Min: 0, Max: 1, Default: 1},
+	topPParam,
+	{Name: ParamTopK, Type: ParamInt, Min: 1, Max: 1000},
+	{Name: ParamMaxOutputTokens, Type: ParamInt, Min: 1, Max: 128000, Default: anthropicDefaultMaxTokens, Option: "max_tokens"},
+	{Name: ParamStop, Type: ParamStringList},
+}
+
+// googleParams are the Gemini generationConfig parameters.
+var googleParams = []ParamSpec{
+	temperatureParam,
+	topPParam,
+	{Name: ParamTopK, Type: ParamInt, Min: 1, Max: 1000, Default: 40},
+	presencePenaltyParam,
+	frequencyPenaltyParam,
+	{Name: ParamMaxOutputTokens, Type: ParamInt, Min: 1, Max: 65536, Option: "max_tokens"},
+	seedParam,
+	{Name: ParamStop, Type: ParamStringList, MaxItems: 5},
+}
+
+// Parameters lists the generation parameters OpenAI accepts.
+func (c *OpenAIConnector) Parameters() []ParamSpec {
+	return openAIParams
+}
+
+// MapConfig converts generation parameters to the options read by the OpenAI chat request.
+func (c *OpenAIConnector) MapConfig(config GenerationConfig) map[string]interface{} {
+	return mapParams(openAIParams, config)
+}
+
+// Parameters lists the generation parameters Anthropic accepts.
+func (c *AnthropicConnector) Parameters() []ParamSpec {
+	return anthropicParams
+}
+
+// MapConfig converts generation parameters to the options read by the Anthropic chat request.
+func (c *AnthropicConnector) MapConfig(config GenerationConfig) map[string]interface{} {
+	return mapParams(anthropicParams, config)
+}
+
+// Parameters lists the generation parameters Gemini accepts.
+func (c *GoogleConnector) Parameters() []ParamSpec {
+	return googleParams
+}
+
+// MapConfig converts generation parameters to the options read by the Gemini chat request.
+func (c *GoogleConnector) MapConfig(config GenerationConfig) map[string]interface{} {
+	return mapParams(googleParams, config)
+}
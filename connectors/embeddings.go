@@ -0,0 +1,184 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Embedder is implemented by providers that can turn text into embedding
+// vectors. Providers report it through Capabilities.Embeddings.
+type Embedder interface {
+	// Embed returns one vector per input, in input order.
+	Embed(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
+
+// Compile-time checks for the connectors that can embed.
+var (
+	_ Embedder = (*OllamaConnector)(nil)
+	_ Embedder = (*LMStudioConnector)(nil)
+	_ Embedder = (*OpenAIConnector)(nil)
+)
+
+// embeddingTimeout bounds a single embeddings request. A local model may
+// have to load first, so it is generous.
+const embeddingTimeout = 120 * time.Second
+
+// ollamaEmbedRequest is the request body for Ollama's /api/embed.
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// Embed embeds inputs with Ollama's /api/embed endpoint.
+func (c *OllamaConnector) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	jsonData, err := json.Marshal(ollamaEmbedRequest{Model: model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, embeddingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint+"/api/embed", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to Ollama: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("model %s not found", model)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, ollamaAPIError(resp)
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	return checkEmbeddings(result.Embeddings, len(inputs))
+}
+
+// Embed embeds inputs with LM Studio's OpenAI-compatible /v1/embeddings endpoint.
+func (c *LMStudioConnector) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	return openAIEmbeddings(ctx, c.endpoint+"/v1/embeddings", "", model, inputs)
+}
+
+// Embed embeds inputs with OpenAI's /v1/embeddings endpoint.
+func (c *OpenAIConnector) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	return openAIEmbeddings(ctx, "https://api.openai.com/v1/embeddings", c.APIKey, model, inputs)
+}
+
+// Capabilities adds embeddings to what the shared cloud connector reports.
+func (c *OpenAIConnector) Capabilities() Capabilities {
+	capabilities := c.CloudConnector.Capabilities()
+	capabilities.Embeddings = true
+	return capabilities
+}
+
+// openAIEmbeddingsRequest is the request body of an OpenAI-style embeddings API.
+type openAIEmbeddingsRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// openAIEmbeddingsResponse is the response of an OpenAI-style embeddings API.
+// Entries carry their input's index and need not arrive in order.
+type openAIEmbeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// openAIEmbeddings calls an OpenAI-compatible embeddings endpoint. apiKey is
+// sent as a bearer token when set.
+func openAIEmbeddings(ctx context.Context, url string, apiKey string, model string, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	jsonData, err := json.Marshal(openAIEmbeddingsRequest{Model: model, Input: inputs, EncodingFormat: "float"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, embeddingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	var result openAIEmbeddingsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("embeddings API error (HTTP %d): %s", resp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("embeddings API error: %s", result.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings API error (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embeddings API returned an unknown input index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return checkEmbeddings(vectors, len(inputs))
+}
+
+// checkEmbeddings makes sure a response has one non-empty vector per input,
+// all of the same length.
+func checkEmbeddings(vectors [][]float32, inputs int) ([][]float32, error) {
+	if len(vectors) != inputs {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", inputs, len(vectors))
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+		if len(vector) != len(vectors[0]) {
+			return nil, fmt.Errorf("embeddings have mixed dimensions %d and %d", len(vectors[0]), len(vector))
+		}
+	}
+	return vectors, nil
+}
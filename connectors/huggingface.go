@@ -1,7 +1,9 @@
 package connectors
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +17,8 @@ type HuggingFaceModel struct {
     Object     string `json:"object"`
     Created    int64  `json:"created"`
     OwnedBy    string `json:"owned_by"`
+    // MaxModelLen is the context length, reported by vLLM-style servers.
+    MaxModelLen int `json:"max_model_len,omitempty"`
 }
 
 // HuggingFaceResponse represents Hugging Face API response
@@ -23,7 +27,17 @@ type HuggingFaceResponse struct {
     Data   []HuggingFaceModel `json:"data"`
 }
 
-// HuggingFaceConnector handles Hugging Face model operations
+// TGIInfo is the /info response of a Text Generation Inference server,
+// which serves a single model.
+type TGIInfo struct {
+    ModelID        string `json:"model_id"`
+    Version        string `json:"version"`
+    MaxTotalTokens int    `json:"max_total_tokens"`
+    MaxInputTokens int    `json:"max_input_tokens"`
+}
+
+// HuggingFaceConnector handles Hugging Face model operations. It serves Text
+// Generation Inference and OpenAI-compatible servers such as vLLM.
 type HuggingFaceConnector struct {
     endpoint string
 }
@@ -37,14 +51,99 @@ func NewHuggingFaceConnector(endpoint string) *HuggingFaceConnector {
 
 // ScanModels scans for available Hugging Face models
 func (c *HuggingFaceConnector) ScanModels() ScanResult {
+    return c.scanModels(context.Background())
+}
+
+// ListModels returns the models served by Hugging Face
+func (c *HuggingFaceConnector) ListModels(ctx context.Context) ([]Model, error) {
+    result := c.scanModels(ctx)
+    if !result.Success {
+        return nil, errors.New(result.Error)
+    }
+    return result.Models, nil
+}
+
+// HealthCheck checks that the Hugging Face server is responding on one of
+// the model listing paths it may expose
+func (c *HuggingFaceConnector) HealthCheck(ctx context.Context) error {
+    var err error
+    for _, path := range []string{"/info", "/v1/models", "/models"} {
+        if err = CheckConnectivity(ctx, c.endpoint, path); err == nil {
+            return nil
+        }
+    }
+    return err
+}
+
+// tgiInfo fetches /info, failing for servers other than Text Generation Inference
+func (c *HuggingFaceConnector) tgiInfo(ctx context.Context) (TGIInfo, error) {
     client := &http.Client{
         Timeout: 10 * time.Second,
     }
 
-    url := c.endpoint + "/models"
+    req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint+"/info", nil)
+    if err != nil {
+        return TGIInfo{}, err
+    }
+    resp, err := client.Do(req)
+    if err != nil {
+        return TGIInfo{}, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return TGIInfo{}, fmt.Errorf("HTTP %d", resp.StatusCode)
+    }
+
+    var info TGIInfo
+    if err := json.NewDecoder(resp.Body).Decode(&info); err != nil || info.ModelID == "" {
+        return TGIInfo{}, fmt.Errorf("not a Text Generation Inference server")
+    }
+    return info, nil
+}
+
+// scanModels lists the model of a Text Generation Inference server, or else
+// the models of an OpenAI-compatible server
+func (c *HuggingFaceConnector) scanModels(ctx context.Context) ScanResult {
+    if info, err := c.tgiInfo(ctx); err == nil {
+        fmt.Printf("Found Text Generation Inference %s serving %s\n", info.Version, info.ModelID)
+        return ScanResult{
+            Models: []Model{{
+                Name:          info.ModelID,
+                ContextLength: info.MaxTotalTokens,
+                Modalities:    []string{ModalityText},
+            }},
+            Success: true,
+        }
+    }
+
+    result := c.scanModelList(ctx, "/v1/models")
+    if !result.Success {
+        if legacy := c.scanModelList(ctx, "/models"); legacy.Success {
+            return legacy
+        }
+    }
+    return result
+}
+
+// scanModelList lists models from an OpenAI-style model list at path
+func (c *HuggingFaceConnector) scanModelList(ctx context.Context, path string) ScanResult {
+    client := &http.Client{
+        Timeout: 10 * time.Second,
+    }
+
+    url := c.endpoint + path
     fmt.Printf("Scanning Hugging Face at: %s\n", url)
     
-    resp, err := client.Get(url)
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return ScanResult{
+            Models:  []Model{},
+            Error:   fmt.Sprintf("Failed to create request: %s", err.Error()),
+            Success: false,
+        }
+    }
+
+    resp, err := client.Do(req)
     if err != nil {
         return ScanResult{
             Models:  []Model{},
@@ -93,7 +192,9 @@ func (c *HuggingFaceConnector) ScanModels() ScanResult {
     var models []Model
     for _, model := range hfResp.Data {
         models = append(models, Model{
-            Name: model.ID,
+            Name:          model.ID,
+            ContextLength: model.MaxModelLen,
+            Modalities:    []string{ModalityText},
         })
     }
 
@@ -103,4 +204,42 @@ func (c *HuggingFaceConnector) ScanModels() ScanResult {
         Models:  models,
         Success: true,
     }
-}
\ No newline at end of file
+}
+
+// Name returns the provider ID
+func (c *HuggingFaceConnector) Name() string {
+    return "huggingface"
+}
+
+// Capabilities reports what the Hugging Face connector supports
+func (c *HuggingFaceConnector) Capabilities() Capabilities {
+    return Capabilities{
+        Local:     true,
+        Chat:      true,
+        Streaming: true,
+    }
+}
+
+// huggingFaceParams are the parameters both of Text Generation Inference's
+// APIs accept. Its /generate API allows at most four stop sequences.
+var huggingFaceParams = []ParamSpec{
+    temperatureParam,
+    topPParam,
+    {Name: ParamTopK, Type: ParamInt, Min: 1, Max: 1000},
+    {Name: ParamRepeatPenalty, Type: ParamFloat, Min: 0, Max: 2, Option: "repetition_penalty"},
+    presencePenaltyParam,
+    frequencyPenaltyParam,
+    {Name: ParamMaxOutputTokens, Type: ParamInt, Min: 1, Max: 1 << 20, Option: "max_tokens"},
+    seedParam,
+    {Name: ParamStop, Type: ParamStringList, MaxItems: 4},
+}
+
+// Parameters lists the generation parameters Hugging Face servers accept
+func (c *HuggingFaceConnector) Parameters() []ParamSpec {
+    return huggingFaceParams
+}
+
+// MapConfig converts generation parameters to Hugging Face request options
+func (c *HuggingFaceConnector) MapConfig(config GenerationConfig) map[string]interface{} {
+    return mapParams(huggingFaceParams, config)
+}
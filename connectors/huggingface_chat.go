@@ -0,0 +1,406 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tgiTurnStop ends the assistant's turn in a /generate transcript.
+const tgiTurnStop = "\nUser:"
+
+// tgiDefaultMaxNewTokens is sent to /generate when no output limit is set,
+// since Text Generation Inference otherwise stops after a few tokens.
+const tgiDefaultMaxNewTokens = 1024
+
+// errNoChatAPI means a Text Generation Inference server cannot serve
+// /v1/chat/completions, because it predates it or its model has no chat template.
+var errNoChatAPI = errors.New("server has no chat completions API")
+
+// tgiGenerateOnly holds the endpoints found to have no chat completions API,
+// so their chats go straight to /generate.
+var tgiGenerateOnly sync.Map
+
+// huggingFaceChatRequest is an OpenAI-style chat completion request. Text
+// Generation Inference ignores the model, which vLLM needs; top_k and
+// repetition_penalty are extensions vLLM accepts.
+type huggingFaceChatRequest struct {
+	Model             string             `json:"model"`
+	Messages          []CloudChatMessage `json:"messages"`
+	Stream            bool               `json:"stream"`
+	Temperature       *float64           `json:"temperature,omitempty"`
+	TopP              *float64           `json:"top_p,omitempty"`
+	TopK              *int               `json:"top_k,omitempty"`
+	RepetitionPenalty *float64           `json:"repetition_penalty,omitempty"`
+	PresencePenalty   *float64           `json:"presence_penalty,omitempty"`
+	FrequencyPenalty  *float64           `json:"frequency_penalty,omitempty"`
+	MaxTokens         *int               `json:"max_tokens,omitempty"`
+	Seed              *int               `json:"seed,omitempty"`
+	Stop              []string           `json:"stop,omitempty"`
+}
+
+// tgiGenerateRequest is the body of /generate and /generate_stream.
+type tgiGenerateRequest struct {
+	Inputs     string        `json:"inputs"`
+	Parameters tgiParameters `json:"parameters"`
+}
+
+type tgiParameters struct {
+	DoSample          bool     `json:"do_sample"`
+	Temperature       *float64 `json:"temperature,omitempty"`
+	TopP              *float64 `json:"top_p,omitempty"`
+	TopK              *int     `json:"top_k,omitempty"`
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+	FrequencyPenalty  *float64 `json:"frequency_penalty,omitempty"`
+	MaxNewTokens      int      `json:"max_new_tokens"`
+	Seed              *int     `json:"seed,omitempty"`
+	Stop              []string `json:"stop,omitempty"`
+	ReturnFullText    bool     `json:"return_full_text"`
+}
+
+// tgiGenerateResponse is the body of a /generate response, or of an error.
+type tgiGenerateResponse struct {
+	GeneratedText string `json:"generated_text"`
+	Error         string `json:"error,omitempty"`
+}
+
+// tgiStreamEvent is one event of /generate_stream.
+type tgiStreamEvent struct {
+	Token *struct {
+		Text    string `json:"text"`
+		Special bool   `json:"special"`
+	} `json:"token"`
+	Error string `json:"error,omitempty"`
+}
+
+// Chat sends a conversation to the Hugging Face server's chat completions
+// API, or to /generate on Text Generation Inference servers without one.
+func (c *HuggingFaceConnector) Chat(ctx context.Context, model string, messages []ChatMessage, options map[string]interface{}) (string, error) {
+	if _, generateOnly := tgiGenerateOnly.Load(c.endpoint); !generateOnly {
+		jsonData, err := newHuggingFaceChatPayload(model, messages, options, false)
+		if err != nil {
+			return "", err
+		}
+		resp, err := c.postChat(ctx, jsonData, false)
+		if !errors.Is(err, errNoChatAPI) {
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			return readHuggingFaceChatResponse(resp.Body)
+		}
+	}
+
+	jsonData, err := newTGIGeneratePayload(messages, options)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.postGenerate(ctx, "/generate", jsonData, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var genResp tgiGenerateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %v", err)
+	}
+	if genResp.Error != "" {
+		return "", fmt.Errorf("Hugging Face error: %s", genResp.Error)
+	}
+	text := genResp.GeneratedText
+	if i := strings.Index(text, tgiTurnStop); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// StreamChat is like Chat but reports the response as it is generated.
+func (c *HuggingFaceConnector) StreamChat(ctx context.Context, model string, messages []ChatMessage, options map[string]interface{}, onDelta StreamHandler) (string, error) {
+	if _, generateOnly := tgiGenerateOnly.Load(c.endpoint); !generateOnly {
+		jsonData, err := newHuggingFaceChatPayload(model, messages, options, true)
+		if err != nil {
+			return "", err
+		}
+		resp, err := c.postChat(ctx, jsonData, true)
+		if !errors.Is(err, errNoChatAPI) {
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			return readOpenAIStream(resp.Body, onDelta)
+		}
+	}
+
+	jsonData, err := newTGIGeneratePayload(messages, options)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.postGenerate(ctx, "/generate_stream", jsonData, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return readTGIStream(resp.Body, onDelta)
+}
+
+// postChat posts to /v1/chat/completions. When the server turns out to be a
+// Text Generation Inference server that cannot chat, it is remembered and
+// errNoChatAPI is returned.
+func (c *HuggingFaceConnector) postChat(ctx context.Context, jsonData []byte, stream bool) (*http.Response, error) {
+	url := c.endpoint + "/v1/chat/completions"
+	fmt.Printf("Sending chat request to Hugging Face at: %s\n", url)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: 300 * time.Second}
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+		client = newStreamClient()
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to Hugging Face: %v", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	missing := resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed ||
+		(resp.StatusCode == http.StatusUnprocessableEntity && strings.Contains(strings.ToLower(string(body)), "template"))
+	if missing {
+		if info, err := c.tgiInfo(ctx); err == nil {
+			fmt.Printf("Text Generation Inference %s has no chat API for %s; using /generate\n", info.Version, info.ModelID)
+			tgiGenerateOnly.Store(c.endpoint, true)
+			return nil, errNoChatAPI
+		}
+	}
+	return nil, fmt.Errorf("Hugging Face API error (HTTP %d): %s", resp.StatusCode, string(body))
+}
+
+// postGenerate posts to one of Text Generation Inference's generate APIs.
+func (c *HuggingFaceConnector) postGenerate(ctx context.Context, path string, jsonData []byte, stream bool) (*http.Response, error) {
+	url := c.endpoint + path
+	fmt.Printf("Sending generate request to Hugging Face at: %s\n", url)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: 300 * time.Second}
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+		client = newStreamClient()
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to Hugging Face: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		var genResp tgiGenerateResponse
+		if json.Unmarshal(body, &genResp) == nil && genResp.Error != "" {
+			return nil, fmt.Errorf("Hugging Face API error (HTTP %d): %s", resp.StatusCode, genResp.Error)
+		}
+		return nil, fmt.Errorf("Hugging Face API error (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+// readHuggingFaceChatResponse reads a non-streamed chat completion.
+func readHuggingFaceChatResponse(body io.Reader) (string, error) {
+	var chatResp LMStudioChatResponse
+	if err := json.NewDecoder(body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %v", err)
+	}
+	if chatResp.Error != nil {
+		return "", fmt.Errorf("Hugging Face error: %s", chatResp.Error.Message)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("no response from Hugging Face")
+	}
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+// readTGIStream reads /generate_stream events, skipping special tokens and
+// holding back text that may be the start of the next user turn, where the
+// response ends.
+func readTGIStream(body io.Reader, onDelta StreamHandler) (string, error) {
+	var full strings.Builder
+	pending := ""
+	done := false
+	err := readSSE(body, func(_ string, data string) error {
+		if done {
+			return nil
+		}
+		var event tgiStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to parse stream event: %w", err)
+		}
+		if event.Error != "" {
+			return fmt.Errorf("Hugging Face error: %s", event.Error)
+		}
+		if event.Token == nil || event.Token.Special {
+			return nil
+		}
+
+		pending += event.Token.Text
+		ready := pending
+		if i := strings.Index(pending, tgiTurnStop); i >= 0 {
+			ready, pending, done = pending[:i], "", true
+		} else {
+			held := stopPrefixLength(pending, tgiTurnStop)
+			ready, pending = pending[:len(pending)-held], pending[len(pending)-held:]
+		}
+		if ready != "" {
+			full.WriteString(ready)
+			onDelta(ready)
+		}
+		return nil
+	})
+	if pending != "" {
+		full.WriteString(pending)
+		onDelta(pending)
+	}
+	return strings.TrimSpace(full.String()), err
+}
+
+// stopPrefixLength returns the length of the longest end of text that
+// begins stop.
+func stopPrefixLength(text string, stop string) int {
+	for n := min(len(text), len(stop)-1); n > 0; n-- {
+		if strings.HasSuffix(text, stop[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// newHuggingFaceChatPayload builds the JSON body for a /v1/chat/completions request.
+func newHuggingFaceChatPayload(model string, messages []ChatMessage, options map[string]interface{}, stream bool) ([]byte, error) {
+	requestBody := huggingFaceChatRequest{
+		Model:    model,
+		Messages: toCloudMessages(messages),
+		Stream:   stream,
+	}
+	if temp, ok := options["temperature"].(float64); ok {
+		requestBody.Temperature = &temp
+	}
+	if topP, ok := tgiTopP(options); ok {
+		requestBody.TopP = &topP
+	}
+	if topK, ok := options["top_k"].(int); ok {
+		requestBody.TopK = &topK
+	}
+	if penalty, ok := options["repetition_penalty"].(float64); ok {
+		requestBody.RepetitionPenalty = &penalty
+	}
+	if penalty, ok := options["presence_penalty"].(float64); ok {
+		requestBody.PresencePenalty = &penalty
+	}
+	if penalty, ok := options["frequency_penalty"].(float64); ok {
+		requestBody.FrequencyPenalty = &penalty
+	}
+	if maxTokens, ok := options["max_tokens"].(int); ok {
+		requestBody.MaxTokens = &maxTokens
+	}
+	if seed, ok := options["seed"].(int); ok {
+		requestBody.Seed = &seed
+	}
+	if stop, ok := options["stop"].([]string); ok && len(stop) > 0 {
+		requestBody.Stop = stop
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	return jsonData, nil
+}
+
+// newTGIGeneratePayload builds the JSON body for /generate and
+// /generate_stream. The conversation is sent as a plain transcript ending
+// with the assistant's turn, and a temperature of zero means greedy decoding.
+func newTGIGeneratePayload(messages []ChatMessage, options map[string]interface{}) ([]byte, error) {
+	params := tgiParameters{DoSample: true, MaxNewTokens: tgiDefaultMaxNewTokens}
+	if temp, ok := options["temperature"].(float64); ok {
+		if temp > 0 {
+			params.Temperature = &temp
+		} else {
+			params.DoSample = false
+		}
+	}
+	if topP, ok := tgiTopP(options); ok {
+		params.TopP = &topP
+	}
+	if topK, ok := options["top_k"].(int); ok {
+		params.TopK = &topK
+	}
+	if penalty, ok := options["repetition_penalty"].(float64); ok && penalty > 0 {
+		params.RepetitionPenalty = &penalty
+	}
+	if penalty, ok := options["frequency_penalty"].(float64); ok {
+		params.FrequencyPenalty = &penalty
+	}
+	if maxTokens, ok := options["max_tokens"].(int); ok {
+		params.MaxNewTokens = maxTokens
+	}
+	if seed, ok := options["seed"].(int); ok {
+		params.Seed = &seed
+	}
+	if stop, ok := options["stop"].([]string); ok {
+		params.Stop = append(params.Stop, stop...)
+	}
+	if len(params.Stop) < 4 {
+		params.Stop = append(params.Stop, tgiTurnStop)
+	}
+
+	jsonData, err := json.Marshal(tgiGenerateRequest{Inputs: tgiPrompt(messages), Parameters: params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	return jsonData, nil
+}
+
+// tgiTopP returns top_p when Text Generation Inference accepts it: it must
+// be strictly between 0 and 1, and 1 means no filtering anyway.
+func tgiTopP(options map[string]interface{}) (float64, bool) {
+	topP, ok := options["top_p"].(float64)
+	return topP, ok && topP > 0 && topP < 1
+}
+
+// tgiPrompt renders a conversation as a transcript for /generate, which
+// takes raw text.
+func tgiPrompt(messages []ChatMessage) string {
+	var prompt strings.Builder
+	for _, msg := range messages {
+		label := "User"
+		switch msg.Role {
+		case RoleSystem:
+			label = "System"
+		case RoleAssistant:
+			label = "Assistant"
+		case RoleTool:
+			label = "Tool"
+		}
+		fmt.Fprintf(&prompt, "%s: %s\n\n", label, strings.TrimSpace(msg.Content))
+	}
+	prompt.WriteString("Assistant:")
+	return prompt.String()
+}
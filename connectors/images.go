@@ -0,0 +1,203 @@
+package connectors
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register the GIF decoder for image.Decode
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+)
+
+// Image limits applied before images are sent to a provider.
+const (
+	// MaxImageInputBytes is the largest image accepted for attaching.
+	MaxImageInputBytes = 20 << 20
+	// maxImageBytes is Anthropic's per-image limit, the strictest of the providers.
+	maxImageBytes = 5 << 20
+	// maxImageDimension is the longest side sent. Anthropic downscales anything
+	// larger anyway, and OpenAI and Gemini tile images of this size well.
+	maxImageDimension = 1568
+	// maxImagePixels guards against decompression bombs.
+	maxImagePixels = 50_000_000
+	// MaxImagesPerRequest bounds the images in one conversation.
+	MaxImagesPerRequest = 20
+)
+
+// ImagePart is an image attached to a chat message. Data is base64 encoded.
+type ImagePart struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
+	Name     string `json:"name,omitempty"`
+}
+
+// DataURL returns the image as a data: URL, the form OpenAI-style APIs accept.
+func (p ImagePart) DataURL() string {
+	return "data:" + p.MimeType + ";base64," + p.Data
+}
+
+// NewImagePart checks raw image bytes and downscales images that are larger
+// than providers accept. PNG, JPEG and GIF can be downscaled; WebP is passed
+// through as long as it is within the size limit.
+func NewImagePart(data []byte) (ImagePart, error) {
+	if len(data) == 0 {
+		return ImagePart{}, fmt.Errorf("image is empty")
+	}
+	if len(data) > MaxImageInputBytes {
+		return ImagePart{}, fmt.Errorf("image is %s; the limit is %s", formatBytes(int64(len(data))), formatBytes(MaxImageInputBytes))
+	}
+
+	mimeType := http.DetectContentType(data)
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif":
+	case "image/webp":
+		if len(data) > maxImageBytes {
+			return ImagePart{}, fmt.Errorf("WebP images over %s cannot be downscaled; convert it to PNG or JPEG", formatBytes(maxImageBytes))
+		}
+		return ImagePart{MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(data)}, nil
+	default:
+		return ImagePart{}, fmt.Errorf("unsupported image type %s; use PNG, JPEG, GIF or WebP", mimeType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return ImagePart{}, fmt.Errorf("invalid image: %v", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return ImagePart{}, fmt.Errorf("image is %dx%d pixels, which is too large to process", config.Width, config.Height)
+	}
+	if config.Width <= maxImageDimension && config.Height <= maxImageDimension && len(data) <= maxImageBytes {
+		return ImagePart{MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(data)}, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ImagePart{}, fmt.Errorf("invalid image: %v", err)
+	}
+	resized := downscale(img, maxImageDimension)
+	encoded, mimeType, err := encodeImage(resized, mimeType == "image/jpeg")
+	if err != nil {
+		return ImagePart{}, err
+	}
+	return ImagePart{MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(encoded)}, nil
+}
+
+// ImagePartFromDataURL parses a base64 data: URL and applies NewImagePart.
+func ImagePartFromDataURL(url string) (ImagePart, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok || !strings.HasPrefix(url, "data:") || !strings.HasSuffix(header, ";base64") {
+		return ImagePart{}, fmt.Errorf("images must be base64 data: URLs")
+	}
+	return PrepareImagePart(payload)
+}
+
+// PrepareImagePart decodes base64 image data and applies NewImagePart.
+func PrepareImagePart(data string) (ImagePart, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return ImagePart{}, fmt.Errorf("invalid base64 image data: %v", err)
+	}
+	return NewImagePart(raw)
+}
+
+// PrepareImages applies NewImagePart to every image in messages, so images
+// that were attached without going through it are checked and downscaled too.
+func PrepareImages(messages []ChatMessage) ([]ChatMessage, error) {
+	count := 0
+	prepared := make([]ChatMessage, len(messages))
+	for i, msg := range messages {
+		prepared[i] = msg
+		if len(msg.Images) == 0 {
+			continue
+		}
+		count += len(msg.Images)
+		if count > MaxImagesPerRequest {
+			return nil, fmt.Errorf("a conversation can include at most %d images", MaxImagesPerRequest)
+		}
+		prepared[i].Images = make([]ImagePart, len(msg.Images))
+		for j, part := range msg.Images {
+			checked, err := PrepareImagePart(part.Data)
+			if err != nil {
+				return nil, fmt.Errorf("message %d image %d: %v", i, j, err)
+			}
+			checked.Name = part.Name
+			prepared[i].Images[j] = checked
+		}
+	}
+	return prepared, nil
+}
+
+// downscale shrinks img so that neither side exceeds maxSide, averaging the
+// source pixels that fall into each destination pixel. Averaging happens on
+// premultiplied RGBA so transparent pixels do not tint their neighbours.
+func downscale(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSide && height <= maxSide {
+		return img
+	}
+
+	newWidth, newHeight := maxSide, maxSide
+	if width > height {
+		newHeight = max(1, height*maxSide/width)
+	} else {
+		newWidth = max(1, width*maxSide/height)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+
+	for y := 0; y < newHeight; y++ {
+		y0, y1 := y*height/newHeight, max((y+1)*height/newHeight, y*height/newHeight+1)
+		for x := 0; x < newWidth; x++ {
+			x0, x1 := x*width/newWidth, max((x+1)*width/newWidth, x*width/newWidth+1)
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// encodeImage encodes a downscaled image. Photos go back to JPEG; other
+// images stay lossless PNG unless that is over the size limit.
+func encodeImage(img image.Image, photo bool) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if !photo {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode image: %v", err)
+		}
+		if buf.Len() <= maxImageBytes {
+			return buf.Bytes(), "image/png", nil
+		}
+		buf.Reset()
+	}
+
+	// JPEG has no alpha channel, so transparent areas are flattened onto white
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %v", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
@@ -1,7 +1,9 @@
 package connectors
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,6 +24,19 @@ type LMStudioResponse struct {
     Data   []LMStudioModel `json:"data"`
 }
 
+// LMStudioModelDetails is a model entry from LM Studio's native REST API,
+// which describes models in more detail than the OpenAI-compatible one.
+type LMStudioModelDetails struct {
+    ID                string   `json:"id"`
+    Type              string   `json:"type"`
+    Publisher         string   `json:"publisher"`
+    Arch              string   `json:"arch"`
+    CompatibilityType string   `json:"compatibility_type"`
+    Quantization      string   `json:"quantization"`
+    MaxContextLength  int      `json:"max_context_length"`
+    Capabilities      []string `json:"capabilities,omitempty"`
+}
+
 // LMStudioConnector handles LM Studio model operations
 type LMStudioConnector struct {
     endpoint string
@@ -36,6 +51,24 @@ func NewLMStudioConnector(endpoint string) *LMStudioConnector {
 
 // ScanModels scans for available LM Studio models
 func (c *LMStudioConnector) ScanModels() ScanResult {
+    return c.scanModels(context.Background())
+}
+
+// ListModels returns the models served by LM Studio
+func (c *LMStudioConnector) ListModels(ctx context.Context) ([]Model, error) {
+    result := c.scanModels(ctx)
+    if !result.Success {
+        return nil, errors.New(result.Error)
+    }
+    return result.Models, nil
+}
+
+// HealthCheck checks that the LM Studio server is responding
+func (c *LMStudioConnector) HealthCheck(ctx context.Context) error {
+    return CheckConnectivity(ctx, c.endpoint, "/v1/models")
+}
+
+func (c *LMStudioConnector) scanModels(ctx context.Context) ScanResult {
     client := &http.Client{
         Timeout: 10 * time.Second,
     }
@@ -43,7 +76,16 @@ func (c *LMStudioConnector) ScanModels() ScanResult {
     url := c.endpoint + "/v1/models"
     fmt.Printf("Scanning LM Studio at: %s\n", url)
     
-    resp, err := client.Get(url)
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return ScanResult{
+            Models:  []Model{},
+            Error:   fmt.Sprintf("Failed to create request: %s", err.Error()),
+            Success: false,
+        }
+    }
+
+    resp, err := client.Do(req)
     if err != nil {
         return ScanResult{
             Models:  []Model{},
@@ -92,9 +134,11 @@ func (c *LMStudioConnector) ScanModels() ScanResult {
     var models []Model
     for _, model := range lmStudioResp.Data {
         models = append(models, Model{
-            Name: model.ID,
+            Name:       model.ID,
+            Modalities: []string{ModalityText},
         })
     }
+    c.addModelDetails(ctx, models)
 
     fmt.Printf("Found %d LM Studio models\n", len(models))
 
@@ -102,4 +146,101 @@ func (c *LMStudioConnector) ScanModels() ScanResult {
         Models:  models,
         Success: true,
     }
-}
\ No newline at end of file
+}
+
+// addModelDetails fills in architecture, quantization, context length and
+// capabilities from /api/v0/models. Older LM Studio versions lack that API,
+// in which case the models keep only their IDs.
+func (c *LMStudioConnector) addModelDetails(ctx context.Context, models []Model) {
+    ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint+"/api/v0/models", nil)
+    if err != nil {
+        return
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        fmt.Printf("Could not read LM Studio model details: %v\n", err)
+        return
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        fmt.Printf("LM Studio model details unavailable (HTTP %d)\n", resp.StatusCode)
+        return
+    }
+
+    var detailsResp struct {
+        Data []LMStudioModelDetails `json:"data"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&detailsResp); err != nil {
+        fmt.Printf("Failed to parse LM Studio model details: %v\n", err)
+        return
+    }
+
+    byID := make(map[string]LMStudioModelDetails, len(detailsResp.Data))
+    for _, details := range detailsResp.Data {
+        byID[details.ID] = details
+    }
+    for i := range models {
+        details, ok := byID[models[i].Name]
+        if !ok {
+            continue
+        }
+        models[i].Family = details.Arch
+        models[i].Quantization = details.Quantization
+        models[i].Format = details.CompatibilityType
+        models[i].ContextLength = details.MaxContextLength
+        if details.Type == "vlm" {
+            models[i].SupportsVision = true
+            models[i].Modalities = []string{ModalityText, ModalityImage}
+        }
+        for _, capability := range details.Capabilities {
+            if capability == "tool_use" {
+                models[i].SupportsTools = true
+            }
+        }
+    }
+}
+
+// Name returns the provider ID
+func (c *LMStudioConnector) Name() string {
+    return "lmstudio"
+}
+
+// Capabilities reports what LM Studio supports
+func (c *LMStudioConnector) Capabilities() Capabilities {
+    return Capabilities{
+        Local:      true,
+        Chat:       true,
+        Streaming:  true,
+        Images:     true,
+        Embeddings: true,
+        Tools:      true,
+    }
+}
+
+// lmStudioParams are the sampling parameters LM Studio's OpenAI-compatible
+// endpoint accepts. The context length is fixed when a model is loaded, so
+// num_ctx cannot be set per request.
+var lmStudioParams = []ParamSpec{
+    {Name: ParamTemperature, Type: ParamFloat, Min: 0, Max: 2, Default: 0.8},
+    topPParam,
+    {Name: ParamTopK, Type: ParamInt, Min: 1, Max: 1000, Default: 40},
+    {Name: ParamRepeatPenalty, Type: ParamFloat, Min: 0, Max: 2, Default: 1.1},
+    presencePenaltyParam,
+    frequencyPenaltyParam,
+    {Name: ParamMaxOutputTokens, Type: ParamInt, Min: 1, Max: 1 << 20, Option: "max_tokens"},
+    seedParam,
+    {Name: ParamStop, Type: ParamStringList},
+}
+
+// Parameters lists the generation parameters LM Studio accepts
+func (c *LMStudioConnector) Parameters() []ParamSpec {
+    return lmStudioParams
+}
+
+// MapConfig converts generation parameters to LM Studio's OpenAI-compatible parameters
+func (c *LMStudioConnector) MapConfig(config GenerationConfig) map[string]interface{} {
+    return mapParams(lmStudioParams, config)
+}
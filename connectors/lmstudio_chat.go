@@ -2,6 +2,7 @@ package connectors
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,22 +10,30 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // LMStudioChatRequest represents the request structure for LM Studio chat API (OpenAI compatible)
 type LMStudioChatRequest struct {
-	Model       string                 `json:"model"`
-	Messages    []LMStudioMessage      `json:"messages"`
-	Stream      bool                   `json:"stream"`
-	Temperature *float64               `json:"temperature,omitempty"`
-	TopP        *float64               `json:"top_p,omitempty"`
-	MaxTokens   *int                   `json:"max_tokens,omitempty"`
-	Stop        []string               `json:"stop,omitempty"`
+	Model            string             `json:"model"`
+	Messages         []CloudChatMessage `json:"messages"`
+	Stream           bool               `json:"stream"`
+	Temperature      *float64           `json:"temperature,omitempty"`
+	TopP             *float64           `json:"top_p,omitempty"`
+	MaxTokens        *int               `json:"max_tokens,omitempty"`
+	Stop             []string           `json:"stop,omitempty"`
+	TopK             *int               `json:"top_k,omitempty"`
+	RepeatPenalty    *float64           `json:"repeat_penalty,omitempty"`
+	PresencePenalty  *float64           `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64           `json:"frequency_penalty,omitempty"`
+	Seed             *int               `json:"seed,omitempty"`
+	Tools            []openAITool       `json:"tools,omitempty"`
 }
 
 type LMStudioMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
 }
 
 // LMStudioChatResponse represents the response structure for LM Studio chat API
@@ -106,89 +115,267 @@ func cleanModelResponse(response string) string {
 	return cleaned
 }
 
-// ChatWithLMStudio sends a chat request to LM Studio with specific parameters
-func (c *LMStudioConnector) ChatWithLMStudio(model string, message string, config map[string]interface{}) (string, error) {
-	client := &http.Client{
-		Timeout: 300 * time.Second,
-	}
-
+// Chat sends a conversation to LM Studio with specific parameters
+func (c *LMStudioConnector) Chat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}) (string, error) {
 	// Prepare the request payload
-	requestBody := LMStudioChatRequest{
-		Model: model,
-		Messages: []LMStudioMessage{
-			{
-				Role:    "user",
-				Content: message,
-			},
-		},
-		Stream: false,
+	jsonData, err := newLMStudioChatPayload(model, messages, nil, config, false)
+	if err != nil {
+		return "", err
 	}
 
-	// Add configuration options
-	if temp, ok := config["temperature"].(float64); ok {
-		requestBody.Temperature = &temp
-	}
-	if topP, ok := config["top_p"].(float64); ok {
-		requestBody.TopP = &topP
+	message, err := c.sendChatRequest(ctx, jsonData)
+	if err != nil {
+		return "", err
 	}
-	if maxTokens, ok := config["max_tokens"].(int); ok {
-		requestBody.MaxTokens = &maxTokens
+
+	rawResponse := message.Content
+	fmt.Printf("Raw response from LM Studio: %s\n", rawResponse)
+
+	// Clean the response to remove thinking tags and unwanted content
+	cleanedResponse := cleanModelResponse(rawResponse)
+	fmt.Printf("Cleaned response: %s\n", cleanedResponse)
+
+	return cleanedResponse, nil
+}
+
+// ChatWithTools sends a conversation with the tools the model may call. The
+// reply's text is cleaned like Chat's.
+func (c *LMStudioConnector) ChatWithTools(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}) (ChatMessage, error) {
+	jsonData, err := newLMStudioChatPayload(model, messages, tools, config, false)
+	if err != nil {
+		return ChatMessage{}, err
 	}
-	if stop, ok := config["stop"].([]string); ok && len(stop) > 0 {
-		requestBody.Stop = stop
+	reply, err := c.sendChatRequest(ctx, jsonData)
+	if err != nil {
+		return ChatMessage{}, err
 	}
+	return ChatMessage{
+		Role:      RoleAssistant,
+		Content:   cleanModelResponse(reply.Content),
+		ToolCalls: fromOpenAIToolCalls(reply.ToolCalls),
+	}, nil
+}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+// sendChatRequest posts a non-streamed chat completion and returns the
+// reply as sent by the model.
+func (c *LMStudioConnector) sendChatRequest(ctx context.Context, jsonData []byte) (LMStudioMessage, error) {
+	client := &http.Client{
+		Timeout: 300 * time.Second,
 	}
 
 	url := c.endpoint + "/v1/chat/completions"
 	fmt.Printf("Sending chat request to LM Studio at: %s\n", url)
-	fmt.Printf("Request body: %s\n", string(jsonData))
+	if len(jsonData) <= 4096 {
+		fmt.Printf("Request body: %s\n", string(jsonData))
+	} else {
+		fmt.Printf("Request body: %d bytes\n", len(jsonData))
+	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return LMStudioMessage{}, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to LM Studio: %v", err)
+		return LMStudioMessage{}, fmt.Errorf("failed to send request to LM Studio: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("LM Studio API error (HTTP %d): %s", resp.StatusCode, string(body))
+		return LMStudioMessage{}, fmt.Errorf("LM Studio API error (HTTP %d): %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		return LMStudioMessage{}, fmt.Errorf("failed to read response body: %v", err)
 	}
 
 	var chatResp LMStudioChatResponse
 	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
+		return LMStudioMessage{}, fmt.Errorf("failed to parse response: %v", err)
 	}
 
 	if chatResp.Error != nil {
-		return "", fmt.Errorf("LM Studio error: %s", chatResp.Error.Message)
+		return LMStudioMessage{}, fmt.Errorf("LM Studio error: %s", chatResp.Error.Message)
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from LM Studio")
+		return LMStudioMessage{}, fmt.Errorf("no response from LM Studio")
 	}
 
-	rawResponse := chatResp.Choices[0].Message.Content
-	fmt.Printf("Raw response from LM Studio: %s\n", rawResponse)
+	return chatResp.Choices[0].Message, nil
+}
 
-	// Clean the response to remove thinking tags and unwanted content
-	cleanedResponse := cleanModelResponse(rawResponse)
-	fmt.Printf("Cleaned response: %s\n", cleanedResponse)
+// StreamChat sends a conversation to LM Studio with streaming enabled.
+// Thinking blocks are left out of the deltas, and the returned response is
+// the text the deltas reported.
+func (c *LMStudioConnector) StreamChat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}, onDelta StreamHandler) (string, error) {
+	jsonData, err := newLMStudioChatPayload(model, messages, nil, config, true)
+	if err != nil {
+		return "", err
+	}
 
-	return cleanedResponse, nil
-}
\ No newline at end of file
+	url := c.endpoint + "/v1/chat/completions"
+	fmt.Printf("Streaming chat request to LM Studio at: %s\n", url)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := newStreamClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to LM Studio: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("LM Studio API error (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	filter := &thinkFilter{onDelta: onDelta}
+	_, err = readOpenAIStream(resp.Body, filter.write)
+	filter.flush()
+	return strings.TrimRightFunc(filter.text.String(), unicode.IsSpace), err
+}
+
+// thinkTags are the thinking blocks removed from streamed responses, as
+// cleanModelResponse removes them from whole ones.
+var thinkTags = []struct{ open, close string }{
+	{"<think>", "</think>"},
+	{"<thinking>", "</thinking>"},
+	{"<thought>", "</thought>"},
+	{"<reasoning>", "</reasoning>"},
+	{"[thinking]", "[/thinking]"},
+	{"[thought]", "[/thought]"},
+	{"<!-- thinking:", "-->"},
+}
+
+// thinkFilter passes streamed text on without its thinking blocks. Text that
+// may be the start of a tag is held back until the next delta shows whether
+// it is one. Leading whitespace is dropped, like cleanModelResponse does.
+type thinkFilter struct {
+	onDelta StreamHandler
+	pending string
+	// closing is the tag that ends the block being skipped, if any
+	closing string
+	text    strings.Builder
+}
+
+func (f *thinkFilter) write(delta string) {
+	f.pending += delta
+	for {
+		if f.closing != "" {
+			end := strings.Index(f.pending, f.closing)
+			if end < 0 {
+				// Only a partial closing tag needs to be kept
+				if keep := len(f.closing) - 1; len(f.pending) > keep {
+					f.pending = f.pending[len(f.pending)-keep:]
+				}
+				return
+			}
+			f.pending = f.pending[end+len(f.closing):]
+			f.closing = ""
+			continue
+		}
+
+		start, open, closing := -1, "", ""
+		for _, tag := range thinkTags {
+			if i := strings.Index(f.pending, tag.open); i >= 0 && (start < 0 || i < start) {
+				start, open, closing = i, tag.open, tag.close
+			}
+		}
+		if start < 0 {
+			break
+		}
+		f.emit(f.pending[:start])
+		f.pending = f.pending[start+len(open):]
+		f.closing = closing
+	}
+
+	hold := 0
+	for _, tag := range thinkTags {
+		for n := min(len(tag.open)-1, len(f.pending)); n > hold; n-- {
+			if strings.HasSuffix(f.pending, tag.open[:n]) {
+				hold = n
+				break
+			}
+		}
+	}
+	f.emit(f.pending[:len(f.pending)-hold])
+	f.pending = f.pending[len(f.pending)-hold:]
+}
+
+// flush reports the text held back at the end of the stream. An unfinished
+// thinking block is dropped.
+func (f *thinkFilter) flush() {
+	if f.closing == "" {
+		f.emit(f.pending)
+	}
+	f.pending = ""
+}
+
+func (f *thinkFilter) emit(text string) {
+	if f.text.Len() == 0 {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	}
+	if text == "" {
+		return
+	}
+	f.text.WriteString(text)
+	f.onDelta(text)
+}
+
+// newLMStudioChatPayload builds the JSON body for a /v1/chat/completions request
+func newLMStudioChatPayload(model string, messages []ChatMessage, tools []Tool, config map[string]interface{}, stream bool) ([]byte, error) {
+	// LM Studio takes OpenAI-style messages, including image_url parts for vision models
+	requestBody := LMStudioChatRequest{
+		Model:    model,
+		Messages: toCloudMessages(messages),
+		Stream:   stream,
+		Tools:    toOpenAITools(tools),
+	}
+
+	// Add configuration options
+	if temp, ok := config["temperature"].(float64); ok {
+		requestBody.Temperature = &temp
+	}
+	if topP, ok := config["top_p"].(float64); ok {
+		requestBody.TopP = &topP
+	}
+	if maxTokens, ok := config["max_tokens"].(int); ok {
+		requestBody.MaxTokens = &maxTokens
+	}
+	if stop, ok := config["stop"].([]string); ok && len(stop) > 0 {
+		requestBody.Stop = stop
+	}
+	if topK, ok := config["top_k"].(int); ok {
+		requestBody.TopK = &topK
+	}
+	if penalty, ok := config["repeat_penalty"].(float64); ok {
+		requestBody.RepeatPenalty = &penalty
+	}
+	if penalty, ok := config["presence_penalty"].(float64); ok {
+		requestBody.PresencePenalty = &penalty
+	}
+	if penalty, ok := config["frequency_penalty"].(float64); ok {
+		requestBody.FrequencyPenalty = &penalty
+	}
+	if seed, ok := config["seed"].(int); ok {
+		requestBody.Seed = &seed
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	return jsonData, nil
+}
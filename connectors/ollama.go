@@ -3,6 +3,7 @@ package connectors
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -44,11 +45,21 @@ func NewOllamaConnector(endpoint string) *OllamaConnector {
 
 // QuickHealthCheck performs a quick health check to see if Ollama is responsive
 func (c *OllamaConnector) QuickHealthCheck() error {
+	return c.HealthCheck(context.Background())
+}
+
+// HealthCheck checks that the Ollama server is responding
+func (c *OllamaConnector) HealthCheck(ctx context.Context) error {
 	client := &http.Client{
 		Timeout: 5 * time.Second,
 	}
 
-	resp, err := client.Get(c.endpoint)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("Ollama is not responding: %v", err)
 	}
@@ -63,6 +74,19 @@ func (c *OllamaConnector) QuickHealthCheck() error {
 
 // ScanModels scans for available Ollama models using the API
 func (c *OllamaConnector) ScanModels() ScanResult {
+	return c.scanModels(context.Background())
+}
+
+// ListModels returns the installed Ollama models
+func (c *OllamaConnector) ListModels(ctx context.Context) ([]Model, error) {
+	result := c.scanModels(ctx)
+	if !result.Success {
+		return nil, errors.New(result.Error)
+	}
+	return result.Models, nil
+}
+
+func (c *OllamaConnector) scanModels(ctx context.Context) ScanResult {
 	// Create a client with a very short timeout to quickly detect if Ollama is offline
 	client := &http.Client{
 		Timeout: 3 * time.Second,
@@ -72,10 +96,10 @@ func (c *OllamaConnector) ScanModels() ScanResult {
 	fmt.Printf("Scanning Ollama at: %s\n", url)
 
 	// Create request with context for better cancellation
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	reqCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(reqCtx, "GET", url, nil)
 	if err != nil {
 		fmt.Printf("Error creating Ollama request: %s\n", err.Error())
 		return ScanResult{
@@ -191,12 +215,20 @@ func (c *OllamaConnector) ScanModels() ScanResult {
 	var models []Model
 	for _, model := range ollamaResp.Models {
 		formattedModel := Model{
-			Name:     model.Name,
-			Size:     formatBytes(model.Size),
-			Modified: formatDate(model.ModifiedAt),
+			Name:          model.Name,
+			Size:          formatBytes(model.Size),
+			Modified:      formatDate(model.ModifiedAt),
+			SizeBytes:     model.Size,
+			Digest:        model.Digest,
+			Family:        model.Details.Family,
+			ParameterSize: model.Details.ParameterSize,
+			Quantization:  model.Details.QuantizationLevel,
+			Format:        model.Details.Format,
+			Modalities:    []string{ModalityText},
 		}
 		models = append(models, formattedModel)
 	}
+	c.addShowDetails(ctx, models)
 
 	fmt.Printf("Successfully found %d Ollama models\n", len(models))
 
@@ -221,4 +253,47 @@ func (c *OllamaConnector) IsOllamaRunning() bool {
 	defer resp.Body.Close()
 
 	return resp.StatusCode < 500
-}
\ No newline at end of file
+}
+
+// Name returns the provider ID
+func (c *OllamaConnector) Name() string {
+	return "ollama"
+}
+
+// Capabilities reports what Ollama supports
+func (c *OllamaConnector) Capabilities() Capabilities {
+	return Capabilities{
+		Local:      true,
+		Chat:       true,
+		Streaming:  true,
+		Images:     true,
+		Embeddings: true,
+		Tools:      true,
+	}
+}
+
+// ollamaParams are the Ollama request options Lumen sets. Ollama takes them
+// all in "options", so they are passed through under Ollama's names.
+var ollamaParams = []ParamSpec{
+	{Name: ParamTemperature, Type: ParamFloat, Min: 0, Max: 2, Default: 0.8},
+	topPParam,
+	{Name: ParamTopK, Type: ParamInt, Min: 1, Max: 1000, Default: 40},
+	{Name: ParamMinP, Type: ParamFloat, Min: 0, Max: 1},
+	{Name: ParamRepeatPenalty, Type: ParamFloat, Min: 0, Max: 2, Default: 1.1},
+	presencePenaltyParam,
+	frequencyPenaltyParam,
+	{Name: ParamNumCtx, Type: ParamInt, Min: 128, Max: 1 << 20, Default: 2048},
+	{Name: ParamMaxOutputTokens, Type: ParamInt, Min: 1, Max: 1 << 20, Option: "num_predict"},
+	seedParam,
+	{Name: ParamStop, Type: ParamStringList},
+}
+
+// Parameters lists the generation parameters Ollama accepts
+func (c *OllamaConnector) Parameters() []ParamSpec {
+	return ollamaParams
+}
+
+// MapConfig converts generation parameters to Ollama's request options
+func (c *OllamaConnector) MapConfig(config GenerationConfig) map[string]interface{} {
+	return mapParams(ollamaParams, config)
+}
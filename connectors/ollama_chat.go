@@ -7,55 +7,115 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// OllamaMessage represents a single message in an Ollama chat request
+type OllamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Images holds base64 encoded images for vision models
+	Images []string `json:"images,omitempty"`
+	// ToolCalls are the tools an assistant message calls, and ToolName the
+	// tool whose result a tool message carries.
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+// ollamaToolCall is a function call made by an Ollama model. Unlike OpenAI,
+// the arguments are a JSON object rather than a string.
+type ollamaToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
 // OllamaChatRequest represents the request structure for Ollama chat API
 type OllamaChatRequest struct {
-	Model   string                 `json:"model"`
-	Prompt  string                 `json:"prompt"`
-	Stream  bool                   `json:"stream"`
-	Options map[string]interface{} `json:"options,omitempty"`
+	Model    string                 `json:"model"`
+	Messages []OllamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+	// Tools uses the OpenAI function format
+	Tools []openAITool `json:"tools,omitempty"`
 }
 
 // OllamaChatResponse represents the response structure for Ollama chat API
 type OllamaChatResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
-	Error    string `json:"error,omitempty"`
+	Message OllamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error,omitempty"`
 }
 
-// ChatWithOllama sends a chat request to Ollama with specific parameters
-func (c *OllamaConnector) ChatWithOllama(model string, message string, config map[string]interface{}) (string, error) {
-	// Increase timeout significantly for chat operations
-	client := &http.Client{
-		Timeout: 120 * time.Second, // 2 minutes timeout
+// Chat sends a conversation to Ollama's /api/chat endpoint with specific parameters
+func (c *OllamaConnector) Chat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}) (string, error) {
+	// Prepare the request payload
+	jsonData, err := newOllamaChatPayload(model, messages, nil, config, false)
+	if err != nil {
+		return "", err
 	}
 
-	// Prepare the request payload
-	requestBody := OllamaChatRequest{
-		Model:   model,
-		Prompt:  message,
-		Stream:  false,
-		Options: config,
+	message, err := c.sendChatRequest(ctx, model, jsonData)
+	if err != nil {
+		return "", err
+	}
+	if message.Content == "" {
+		return "", fmt.Errorf("received empty response from Ollama")
 	}
+	return message.Content, nil
+}
 
-	jsonData, err := json.Marshal(requestBody)
+// ChatWithTools sends a conversation with the tools the model may call.
+// Models without tool support are rejected by Ollama.
+func (c *OllamaConnector) ChatWithTools(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}) (ChatMessage, error) {
+	jsonData, err := newOllamaChatPayload(model, messages, tools, config, false)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return ChatMessage{}, err
+	}
+	reply, err := c.sendChatRequest(ctx, model, jsonData)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+
+	message := ChatMessage{Role: RoleAssistant, Content: reply.Content}
+	for _, call := range reply.ToolCalls {
+		id := call.ID
+		if id == "" {
+			id = newToolCallID()
+		}
+		message.ToolCalls = append(message.ToolCalls, ToolCall{ID: id, Name: call.Function.Name, Arguments: toolArguments(string(call.Function.Arguments))})
+	}
+	if message.Content == "" && len(message.ToolCalls) == 0 {
+		return ChatMessage{}, fmt.Errorf("received empty response from Ollama")
+	}
+	return message, nil
+}
+
+// sendChatRequest posts a non-streamed /api/chat request and returns the reply.
+func (c *OllamaConnector) sendChatRequest(ctx context.Context, model string, jsonData []byte) (OllamaMessage, error) {
+	// Increase timeout significantly for chat operations
+	client := &http.Client{
+		Timeout: 120 * time.Second, // 2 minutes timeout
 	}
 
-	url := c.endpoint + "/api/generate"
+	url := c.endpoint + "/api/chat"
 	fmt.Printf("Sending chat request to Ollama at: %s\n", url)
-	fmt.Printf("Request payload: %s\n", string(jsonData))
+	if len(jsonData) <= 4096 {
+		fmt.Printf("Request payload: %s\n", string(jsonData))
+	} else {
+		fmt.Printf("Request payload: %d bytes\n", len(jsonData))
+	}
 
 	// Create request with custom context for better timeout control
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return OllamaMessage{}, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -66,37 +126,109 @@ func (c *OllamaConnector) ChatWithOllama(model string, message string, config ma
 	if err != nil {
 		// Check if it's a timeout error
 		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("request timed out after 2 minutes. The model '%s' may be too large or your system may be under heavy load. Try using a smaller model or increasing system resources", model)
+			return OllamaMessage{}, fmt.Errorf("request timed out after 2 minutes. The model '%s' may be too large or your system may be under heavy load. Try using a smaller model or increasing system resources", model)
 		}
-		return "", fmt.Errorf("failed to send request to Ollama: %v", err)
+		return OllamaMessage{}, fmt.Errorf("failed to send request to Ollama: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Ollama API error (HTTP %d): %s", resp.StatusCode, string(body))
+		return OllamaMessage{}, fmt.Errorf("Ollama API error (HTTP %d): %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		return OllamaMessage{}, fmt.Errorf("failed to read response body: %v", err)
 	}
 
 	fmt.Printf("Ollama response: %s\n", string(body))
 
 	var chatResp OllamaChatResponse
 	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
+		return OllamaMessage{}, fmt.Errorf("failed to parse response: %v", err)
 	}
 
 	if chatResp.Error != "" {
-		return "", fmt.Errorf("Ollama error: %s", chatResp.Error)
+		return OllamaMessage{}, fmt.Errorf("Ollama error: %s", chatResp.Error)
 	}
 
-	if chatResp.Response == "" {
-		return "", fmt.Errorf("received empty response from Ollama")
+	return chatResp.Message, nil
+}
+
+// StreamChat sends a conversation to /api/chat with streaming enabled,
+// reporting each NDJSON chunk as it arrives and returning the full response.
+func (c *OllamaConnector) StreamChat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}, onDelta StreamHandler) (string, error) {
+	jsonData, err := newOllamaChatPayload(model, messages, nil, config, true)
+	if err != nil {
+		return "", err
 	}
 
-	return chatResp.Response, nil
+	url := c.endpoint + "/api/chat"
+	fmt.Printf("Streaming chat request to Ollama at: %s\n", url)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := newStreamClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to Ollama: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("Ollama API error (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var full strings.Builder
+	err = readNDJSON(resp.Body, func(line []byte) error {
+		var chunk OllamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %v", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("Ollama error: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			full.WriteString(chunk.Message.Content)
+			onDelta(chunk.Message.Content)
+		}
+		return nil
+	})
+	return full.String(), err
 }
 
+// newOllamaChatPayload builds the JSON body for an /api/chat request
+func newOllamaChatPayload(model string, messages []ChatMessage, tools []Tool, config map[string]interface{}, stream bool) ([]byte, error) {
+	ollamaMessages := make([]OllamaMessage, 0, len(messages))
+	for _, msg := range messages {
+		ollamaMessage := OllamaMessage{Role: msg.Role, Content: msg.Content, ToolName: msg.ToolName}
+		for _, image := range msg.Images {
+			ollamaMessage.Images = append(ollamaMessage.Images, image.Data)
+		}
+		for _, call := range msg.ToolCalls {
+			ollamaCall := ollamaToolCall{ID: call.ID}
+			ollamaCall.Function.Name = call.Name
+			ollamaCall.Function.Arguments = toolCallInput(call)
+			ollamaMessage.ToolCalls = append(ollamaMessage.ToolCalls, ollamaCall)
+		}
+		ollamaMessages = append(ollamaMessages, ollamaMessage)
+	}
+	requestBody := OllamaChatRequest{
+		Model:    model,
+		Messages: ollamaMessages,
+		Stream:   stream,
+		Options:  config,
+		Tools:    toOpenAITools(tools),
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	return jsonData, nil
+}
@@ -0,0 +1,277 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OllamaPullProgress is one progress update from /api/pull. Total and
+// Completed are byte counts of the layer named by Digest, when downloading.
+type OllamaPullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// OllamaModelDetails describes a model's format and size class.
+type OllamaModelDetails struct {
+	ParentModel       string   `json:"parent_model,omitempty"`
+	Format            string   `json:"format,omitempty"`
+	Family            string   `json:"family,omitempty"`
+	Families          []string `json:"families,omitempty"`
+	ParameterSize     string   `json:"parameter_size,omitempty"`
+	QuantizationLevel string   `json:"quantization_level,omitempty"`
+}
+
+// OllamaModelInfo is the /api/show response: how a model was built and the
+// defaults it runs with.
+type OllamaModelInfo struct {
+	Modelfile    string                 `json:"modelfile"`
+	Parameters   string                 `json:"parameters"`
+	Template     string                 `json:"template"`
+	System       string                 `json:"system,omitempty"`
+	License      string                 `json:"license"`
+	Details      OllamaModelDetails     `json:"details"`
+	ModelInfo    map[string]interface{} `json:"model_info,omitempty"`
+	Capabilities []string               `json:"capabilities,omitempty"`
+	ModifiedAt   string                 `json:"modified_at,omitempty"`
+}
+
+// ollamaShowDetails is the model metadata derived from /api/show.
+type ollamaShowDetails struct {
+	contextLength int
+	vision        bool
+	tools         bool
+}
+
+// ollamaShowCache holds ollamaShowDetails by model digest. A digest always
+// names the same model content, so entries never go stale.
+var ollamaShowCache sync.Map
+
+// ollamaShowConcurrency bounds the /api/show requests made while listing models.
+const ollamaShowConcurrency = 4
+
+// ollamaModelRequest names a model; "name" is sent too for older Ollama versions.
+type ollamaModelRequest struct {
+	Model  string `json:"model"`
+	Name   string `json:"name"`
+	Stream *bool  `json:"stream,omitempty"`
+}
+
+// PullModel downloads a model from the Ollama library, reporting progress as
+// it goes. It blocks until the pull finishes, fails or ctx is cancelled.
+func (c *OllamaConnector) PullModel(ctx context.Context, model string, onProgress func(OllamaPullProgress)) error {
+	stream := true
+	jsonData, err := json.Marshal(ollamaModelRequest{Model: model, Name: model, Stream: &stream})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	url := c.endpoint + "/api/pull"
+	fmt.Printf("Pulling %s from Ollama at: %s\n", model, url)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Large models take far longer than any fixed timeout; ctx bounds the pull.
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to Ollama: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ollamaAPIError(resp)
+	}
+
+	return readNDJSON(resp.Body, func(line []byte) error {
+		var progress OllamaPullProgress
+		if err := json.Unmarshal(line, &progress); err != nil {
+			return fmt.Errorf("failed to parse pull progress: %v", err)
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("Ollama error: %s", progress.Error)
+		}
+		onProgress(progress)
+		return nil
+	})
+}
+
+// DeleteModel removes a model and frees its disk space.
+func (c *OllamaConnector) DeleteModel(ctx context.Context, model string) error {
+	resp, err := c.modelRequest(ctx, "DELETE", "/api/delete", ollamaModelRequest{Model: model, Name: model})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("model %s not found", model)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ollamaAPIError(resp)
+	}
+	return nil
+}
+
+// CopyModel creates destination as a copy of source, e.g. to give a model a
+// shorter name before customizing it.
+func (c *OllamaConnector) CopyModel(ctx context.Context, source string, destination string) error {
+	payload := map[string]string{"source": source, "destination": destination}
+	resp, err := c.modelRequest(ctx, "POST", "/api/copy", payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("model %s not found", source)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ollamaAPIError(resp)
+	}
+	return nil
+}
+
+// ShowModel returns a model's modelfile, parameters, template and license.
+func (c *OllamaConnector) ShowModel(ctx context.Context, model string) (OllamaModelInfo, error) {
+	resp, err := c.modelRequest(ctx, "POST", "/api/show", ollamaModelRequest{Model: model, Name: model})
+	if err != nil {
+		return OllamaModelInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return OllamaModelInfo{}, fmt.Errorf("model %s not found", model)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return OllamaModelInfo{}, ollamaAPIError(resp)
+	}
+
+	var info OllamaModelInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return OllamaModelInfo{}, fmt.Errorf("failed to parse response: %v", err)
+	}
+	return info, nil
+}
+
+// modelRequest sends a short JSON request to a model management endpoint.
+func (c *OllamaConnector) modelRequest(ctx context.Context, method string, path string, payload interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to Ollama: %v", err)
+	}
+	return resp, nil
+}
+
+// ollamaAPIError turns an error response into an error, preferring Ollama's
+// {"error": "..."} message over the raw body.
+func ollamaAPIError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+		return fmt.Errorf("Ollama error (HTTP %d): %s", resp.StatusCode, apiErr.Error)
+	}
+	return fmt.Errorf("Ollama API error (HTTP %d): %s", resp.StatusCode, string(body))
+}
+
+// addShowDetails fills in context length and capabilities from /api/show,
+// which /api/tags does not report. Models whose details cannot be fetched
+// keep what /api/tags gave.
+func (c *OllamaConnector) addShowDetails(ctx context.Context, models []Model) {
+	slots := make(chan struct{}, ollamaShowConcurrency)
+	var wg sync.WaitGroup
+	for i := range models {
+		model := &models[i]
+		if cached, ok := ollamaShowCache.Load(model.Digest); ok && model.Digest != "" {
+			applyShowDetails(model, cached.(ollamaShowDetails))
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			showCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			info, err := c.ShowModel(showCtx, model.Name)
+			if err != nil {
+				fmt.Printf("Could not read details of Ollama model %s: %v\n", model.Name, err)
+				return
+			}
+			details := showDetails(info)
+			if model.Digest != "" {
+				ollamaShowCache.Store(model.Digest, details)
+			}
+			applyShowDetails(model, details)
+		}()
+	}
+	wg.Wait()
+}
+
+// showDetails extracts the metadata Lumen uses from an /api/show response.
+func showDetails(info OllamaModelInfo) ollamaShowDetails {
+	var details ollamaShowDetails
+	for key, value := range info.ModelInfo {
+		if length, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			details.contextLength = int(length)
+		}
+	}
+
+	if len(info.Capabilities) > 0 {
+		for _, capability := range info.Capabilities {
+			switch capability {
+			case "vision":
+				details.vision = true
+			case "tools":
+				details.tools = true
+			}
+		}
+		return details
+	}
+	// Ollama before 0.6 does not report capabilities; vision models carry a
+	// CLIP projector family instead.
+	for _, family := range info.Details.Families {
+		if family == "clip" || family == "mllama" {
+			details.vision = true
+		}
+	}
+	return details
+}
+
+func applyShowDetails(model *Model, details ollamaShowDetails) {
+	model.ContextLength = details.contextLength
+	model.SupportsTools = details.tools
+	model.SupportsVision = details.vision
+	if details.vision {
+		model.Modalities = []string{ModalityText, ModalityImage}
+	}
+}
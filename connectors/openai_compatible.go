@@ -0,0 +1,218 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OpenAICompatibleConnector talks to a server that implements the OpenAI
+// Chat Completions API, such as vLLM, the llama.cpp server or LiteLLM. Its
+// endpoint is the API's base URL, e.g. http://localhost:8000/v1.
+type OpenAICompatibleConnector struct {
+	CloudConnector
+	endpoint string
+	local    bool
+	headers  map[string]string
+	// models, when set, replaces the server's /models list.
+	models []string
+}
+
+// NewOpenAICompatibleConnector creates a connector for a user-defined
+// provider. The API key is optional and sent as a bearer token; headers are
+// added to every request. When models is empty they are listed by the server.
+func NewOpenAICompatibleConnector(name string, endpoint string, apiKey string, headers map[string]string, models []string) *OpenAICompatibleConnector {
+	return &OpenAICompatibleConnector{
+		CloudConnector: *NewCloudConnector(name, apiKey),
+		endpoint:       strings.TrimRight(endpoint, "/"),
+		local:          isLocalURL(endpoint),
+		headers:        headers,
+		models:         models,
+	}
+}
+
+// Capabilities reports the provider as local when its server is on this
+// machine or a private network. Whether the server's models take images or
+// tools is only known when they are used.
+func (c *OpenAICompatibleConnector) Capabilities() Capabilities {
+	return Capabilities{
+		Local:     c.local,
+		Chat:      true,
+		Streaming: true,
+		Images:    true,
+		Tools:     true,
+	}
+}
+
+// Parameters lists the standard Chat Completions parameters.
+func (c *OpenAICompatibleConnector) Parameters() []ParamSpec {
+	return openAIParams
+}
+
+// MapConfig converts generation parameters to the options read by the chat request.
+func (c *OpenAICompatibleConnector) MapConfig(config GenerationConfig) map[string]interface{} {
+	return mapParams(openAIParams, config)
+}
+
+// isLocalURL reports whether a URL's host is localhost or a loopback,
+// private or link-local address.
+func isLocalURL(endpoint string) bool {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
+
+// setHeaders adds the key and the configured headers to a request.
+func (c *OpenAICompatibleConnector) setHeaders(req *http.Request) {
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// HealthCheck lists the server's models. A server with a fixed model list
+// only has to answer, since it may not serve /models at all.
+func (c *OpenAICompatibleConnector) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	c.setHeaders(req)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("the server rejected the credentials: %s", resp.Status)
+	case len(c.models) > 0 && resp.StatusCode < http.StatusInternalServerError:
+		return nil
+	}
+	return fmt.Errorf("%s API error: %s", c.Provider, resp.Status)
+}
+
+// ListModels returns the configured models, or the server's /models list.
+// vLLM and the llama.cpp server report context lengths there.
+func (c *OpenAICompatibleConnector) ListModels(ctx context.Context) ([]Model, error) {
+	if len(c.models) > 0 {
+		models := make([]Model, 0, len(c.models))
+		for _, name := range c.models {
+			models = append(models, Model{Name: name})
+		}
+		return models, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	c.setHeaders(req)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to %s: %w", c.Provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s API error: %s", c.Provider, resp.Status)
+	}
+
+	var listResp struct {
+		Data []struct {
+			ID          string `json:"id"`
+			Created     int64  `json:"created"`
+			MaxModelLen int    `json:"max_model_len"`
+			Meta        struct {
+				NCtxTrain int `json:"n_ctx_train"`
+			} `json:"meta"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response: %w", c.Provider, err)
+	}
+
+	models := make([]Model, 0, len(listResp.Data))
+	for _, m := range listResp.Data {
+		model := Model{Name: m.ID, ContextLength: m.MaxModelLen}
+		if model.ContextLength == 0 {
+			model.ContextLength = m.Meta.NCtxTrain
+		}
+		if m.Created > 0 {
+			model.Modified = time.Unix(m.Created, 0).Format("2006-01-02")
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+func (c *OpenAICompatibleConnector) newRequest(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}, stream bool) (*http.Request, error) {
+	jsonData, err := json.Marshal(newOpenAIChatRequest(model, messages, tools, config, stream))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// Chat sends an ordered conversation and waits for the full response.
+func (c *OpenAICompatibleConnector) Chat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}) (string, error) {
+	req, err := c.newRequest(ctx, model, messages, nil, config, false)
+	if err != nil {
+		return "", err
+	}
+	message, err := c.sendChatRequest(req)
+	return message.Content, err
+}
+
+// ChatWithTools sends a conversation with the tools the model may call.
+func (c *OpenAICompatibleConnector) ChatWithTools(ctx context.Context, model string, messages []ChatMessage, tools []Tool, config map[string]interface{}) (ChatMessage, error) {
+	req, err := c.newRequest(ctx, model, messages, tools, config, false)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	return c.sendChatRequest(req)
+}
+
+// StreamChat sends an ordered conversation and reports text as it is generated.
+func (c *OpenAICompatibleConnector) StreamChat(ctx context.Context, model string, messages []ChatMessage, config map[string]interface{}, onDelta StreamHandler) (string, error) {
+	req, err := c.newRequest(ctx, model, messages, nil, config, true)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.sendStreamRequest(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	return readOpenAIStream(resp.Body, onDelta)
+}
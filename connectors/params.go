@@ -0,0 +1,145 @@
+package connectors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Generation parameter names, matching the JSON names of the saved model config.
+const (
+	ParamTemperature      = "temperature"
+	ParamTopP             = "top_p"
+	ParamTopK             = "top_k"
+	ParamMinP             = "min_p"
+	ParamRepeatPenalty    = "repeat_penalty"
+	ParamPresencePenalty  = "presence_penalty"
+	ParamFrequencyPenalty = "frequency_penalty"
+	ParamNumCtx           = "num_ctx"
+	ParamMaxOutputTokens  = "max_output_tokens"
+	ParamSeed             = "seed"
+	ParamStop             = "stop"
+)
+
+// ParamType is the kind of value a generation parameter takes.
+type ParamType string
+
+const (
+	ParamFloat      ParamType = "float"
+	ParamInt        ParamType = "int"
+	ParamStringList ParamType = "string_list"
+)
+
+// ParamSpec declares a generation parameter a provider supports. Min and Max
+// bound numeric values; MaxItems bounds string lists, with zero meaning no
+// limit. Default is what the provider uses when the parameter is not sent.
+type ParamSpec struct {
+	Name     string    `json:"name"`
+	Type     ParamType `json:"type"`
+	Min      float64   `json:"min,omitempty"`
+	Max      float64   `json:"max,omitempty"`
+	MaxItems int       `json:"max_items,omitempty"`
+	Default  float64   `json:"default,omitempty"`
+	// Option is the key MapConfig uses for the parameter when it differs
+	// from Name, e.g. Ollama's "num_predict" for max_output_tokens.
+	Option string `json:"-"`
+}
+
+// Specs shared by providers that accept the same parameter with the same range.
+var (
+	temperatureParam      = ParamSpec{Name: ParamTemperature, Type: ParamFloat, Min: 0, Max: 2, Default: 1}
+	topPParam             = ParamSpec{Name: ParamTopP, Type: ParamFloat, Min: 0, Max: 1, Default: 1}
+	presencePenaltyParam  = ParamSpec{Name: ParamPresencePenalty, Type: ParamFloat, Min: -2, Max: 2}
+	frequencyPenaltyParam = ParamSpec{Name: ParamFrequencyPenalty, Type: ParamFloat, Min: -2, Max: 2}
+	seedParam             = ParamSpec{Name: ParamSeed, Type: ParamInt, Min: 0, Max: 1<<31 - 1}
+)
+
+// value returns a parameter's value in the form the request builders read it
+// and whether it is set. Parameters whose zero value means "let the provider
+// decide" count as unset when zero, so they are left out of requests.
+func (c GenerationConfig) value(name string) (interface{}, bool) {
+	switch name {
+	case ParamTemperature:
+		return c.Temperature, true
+	case ParamTopP:
+		return c.TopP, true
+	case ParamTopK:
+		return c.TopK, c.TopK != 0
+	case ParamMinP:
+		return c.MinP, c.MinP != 0
+	case ParamRepeatPenalty:
+		return c.RepeatPenalty, c.RepeatPenalty != 0
+	case ParamPresencePenalty:
+		return c.PresencePenalty, c.PresencePenalty != 0
+	case ParamFrequencyPenalty:
+		return c.FrequencyPenalty, c.FrequencyPenalty != 0
+	case ParamNumCtx:
+		return c.NumCtx, c.NumCtx != 0
+	case ParamMaxOutputTokens:
+		return c.MaxOutputTokens, c.MaxOutputTokens != 0
+	case ParamSeed:
+		if c.Seed == nil {
+			return nil, false
+		}
+		return *c.Seed, true
+	case ParamStop:
+		return c.Stop, len(c.Stop) > 0
+	}
+	return nil, false
+}
+
+// ValidateConfig checks every set parameter in specs against its range.
+// Parameters missing from specs are not checked since MapConfig drops them.
+func ValidateConfig(specs []ParamSpec, config GenerationConfig) error {
+	var problems []string
+	for _, spec := range specs {
+		value, set := config.value(spec.Name)
+		if !set {
+			continue
+		}
+		if err := spec.check(value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (s ParamSpec) check(value interface{}) error {
+	var number float64
+	switch v := value.(type) {
+	case []string:
+		if s.MaxItems > 0 && len(v) > s.MaxItems {
+			return fmt.Errorf("%s accepts at most %d entries", s.Name, s.MaxItems)
+		}
+		return nil
+	case int:
+		number = float64(v)
+	case float64:
+		number = v
+	}
+	// Written so that NaN fails too
+	if !(number >= s.Min && number <= s.Max) {
+		return fmt.Errorf("%s must be between %g and %g", s.Name, s.Min, s.Max)
+	}
+	return nil
+}
+
+// mapParams builds MapConfig's options from the parameters in specs that are
+// set, so unsupported parameters never reach a request.
+func mapParams(specs []ParamSpec, config GenerationConfig) map[string]interface{} {
+	options := make(map[string]interface{})
+	for _, spec := range specs {
+		value, set := config.value(spec.Name)
+		if !set {
+			continue
+		}
+		key := spec.Option
+		if key == "" {
+			key = spec.Name
+		}
+		options[key] = value
+	}
+	return options
+}
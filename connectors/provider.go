@@ -0,0 +1,80 @@
+package connectors
+
+import (
+	"context"
+)
+
+// Capabilities describes what a provider supports so callers can decide how
+// to use it without knowing which provider it is.
+type Capabilities struct {
+	Local          bool `json:"local"`
+	RequiresAPIKey bool `json:"requires_api_key"`
+	Chat           bool `json:"chat"`
+	Streaming      bool `json:"streaming"`
+	// Images reports whether chat messages can carry image attachments.
+	// Whether a given model can see them is in Model.SupportsVision.
+	Images bool `json:"images"`
+	// Embeddings reports whether the provider implements Embedder.
+	Embeddings bool `json:"embeddings"`
+	// Tools reports whether the provider implements ToolCaller. Whether a
+	// given model can call tools is in Model.SupportsTools.
+	Tools bool `json:"tools"`
+}
+
+// GenerationConfig holds provider-neutral generation parameters. Each provider
+// maps it to its own request options through MapConfig.
+type GenerationConfig struct {
+	Temperature   float64
+	TopP          float64
+	TopK          int
+	RepeatPenalty float64
+	NumCtx        int
+	Stop          []string
+
+	MinP             float64
+	PresencePenalty  float64
+	FrequencyPenalty float64
+	// MaxOutputTokens caps the length of the response; NumCtx is the size
+	// of the context window, which only local providers let callers set.
+	MaxOutputTokens int
+	Seed            *int
+}
+
+// ProviderSettings carries what a provider needs to reach its API.
+type ProviderSettings struct {
+	Endpoint string
+	APIKey   string
+	// Headers are added to every request, for providers that allow it.
+	Headers map[string]string
+}
+
+// Provider is implemented by every model backend Lumen can talk to.
+type Provider interface {
+	// Name returns the provider ID used in configs and bindings, e.g. "ollama".
+	Name() string
+	Capabilities() Capabilities
+	ListModels(ctx context.Context) ([]Model, error)
+	HealthCheck(ctx context.Context) error
+	// Parameters declares the generation parameters the provider accepts and
+	// their ranges. MapConfig passes on these and nothing else.
+	Parameters() []ParamSpec
+	// MapConfig converts generation parameters into the options map accepted
+	// by Chat and StreamChat, dropping anything the provider does not support.
+	MapConfig(config GenerationConfig) map[string]interface{}
+	Chat(ctx context.Context, model string, messages []ChatMessage, options map[string]interface{}) (string, error)
+	StreamChat(ctx context.Context, model string, messages []ChatMessage, options map[string]interface{}, onDelta StreamHandler) (string, error)
+}
+
+// ProviderFactory builds a provider from its connection settings.
+type ProviderFactory func(settings ProviderSettings) Provider
+
+// Compile-time checks that the built-in connectors implement Provider.
+var (
+	_ Provider = (*OllamaConnector)(nil)
+	_ Provider = (*LMStudioConnector)(nil)
+	_ Provider = (*HuggingFaceConnector)(nil)
+	_ Provider = (*OpenAIConnector)(nil)
+	_ Provider = (*AnthropicConnector)(nil)
+	_ Provider = (*GoogleConnector)(nil)
+	_ Provider = (*OpenAICompatibleConnector)(nil)
+)
@@ -0,0 +1,141 @@
+package connectors
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ProviderInfo describes a registered provider.
+type ProviderInfo struct {
+	Name            string       `json:"name"`
+	DefaultEndpoint string       `json:"default_endpoint,omitempty"`
+	Capabilities    Capabilities `json:"capabilities"`
+	Parameters      []ParamSpec  `json:"parameters"`
+}
+
+type registration struct {
+	info    ProviderInfo
+	factory ProviderFactory
+}
+
+// Registry maps provider IDs to the factories that build them.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]registration
+	order     []string
+}
+
+// NewRegistry creates an empty provider registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[string]registration),
+	}
+}
+
+// NewDefaultRegistry creates a registry with all built-in providers.
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.MustRegister("ollama", "http://localhost:11434", func(s ProviderSettings) Provider {
+		return NewOllamaConnector(s.Endpoint)
+	})
+	r.MustRegister("lmstudio", "http://localhost:1234", func(s ProviderSettings) Provider {
+		return NewLMStudioConnector(s.Endpoint)
+	})
+	r.MustRegister("huggingface", "http://localhost:8000", func(s ProviderSettings) Provider {
+		return NewHuggingFaceConnector(s.Endpoint)
+	})
+	r.MustRegister("openai", "", func(s ProviderSettings) Provider {
+		return NewOpenAIConnector(s.APIKey)
+	})
+	r.MustRegister("anthropic", "", func(s ProviderSettings) Provider {
+		return NewAnthropicConnector(s.APIKey)
+	})
+	r.MustRegister("google", "", func(s ProviderSettings) Provider {
+		return NewGoogleConnector(s.APIKey)
+	})
+	return r
+}
+
+// Register adds a provider under the given ID. Registering an ID twice is an error.
+func (r *Registry) Register(name string, defaultEndpoint string, factory ProviderFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("provider name and factory are required")
+	}
+
+	// Build a throwaway instance to learn the provider's capabilities and parameters
+	instance := factory(ProviderSettings{Endpoint: defaultEndpoint})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.providers[name]; exists {
+		return fmt.Errorf("provider %s is already registered", name)
+	}
+	r.providers[name] = registration{
+		info: ProviderInfo{
+			Name:            name,
+			DefaultEndpoint: defaultEndpoint,
+			Capabilities:    instance.Capabilities(),
+			Parameters:      instance.Parameters(),
+		},
+		factory: factory,
+	}
+	r.order = append(r.order, name)
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is meant for built-ins.
+func (r *Registry) MustRegister(name string, defaultEndpoint string, factory ProviderFactory) {
+	if err := r.Register(name, defaultEndpoint, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes a provider. It is a no-op for unknown IDs.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.providers[name]; !exists {
+		return
+	}
+	delete(r.providers, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Info returns the registration details for a provider.
+func (r *Registry) Info(name string) (ProviderInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	reg, ok := r.providers[name]
+	return reg.info, ok
+}
+
+// List returns every registered provider in registration order.
+func (r *Registry) List() []ProviderInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := make([]ProviderInfo, 0, len(r.order))
+	for _, name := range r.order {
+		infos = append(infos, r.providers[name].info)
+	}
+	return infos
+}
+
+// New builds a provider instance. An empty endpoint falls back to the
+// provider's default.
+func (r *Registry) New(name string, settings ProviderSettings) (Provider, error) {
+	r.mu.RLock()
+	reg, ok := r.providers[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", name)
+	}
+	if settings.Endpoint == "" {
+		settings.Endpoint = reg.info.DefaultEndpoint
+	}
+	return reg.factory(settings), nil
+}
@@ -0,0 +1,180 @@
+package connectors
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// StreamHandler receives each incremental piece of generated text.
+type StreamHandler func(delta string)
+
+// streamIdleTimeout is how long a streamed response may send nothing, e.g.
+// while a local model loads or reads a long prompt, before it is given up
+// on. A generation that keeps sending has no time limit; it ends when its
+// request's context is cancelled.
+const streamIdleTimeout = 5 * time.Minute
+
+// newStreamClient returns an HTTP client suitable for long-lived streamed responses.
+func newStreamClient() *http.Client {
+	return &http.Client{Transport: idleTimeoutTransport{base: http.DefaultTransport}}
+}
+
+// idleTimeoutTransport cancels a request once streamIdleTimeout passes
+// without response headers or body data.
+type idleTimeoutTransport struct {
+	base http.RoundTripper
+}
+
+func (t idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	body := &idleTimeoutBody{cancel: cancel}
+	body.timer = time.AfterFunc(streamIdleTimeout, func() {
+		body.expired.Store(true)
+		cancel()
+	})
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		body.timer.Stop()
+		cancel()
+		if body.expired.Load() {
+			return nil, errStreamIdle
+		}
+		return nil, err
+	}
+	body.ReadCloser = resp.Body
+	resp.Body = body
+	return resp, nil
+}
+
+// errStreamIdle is returned when a streamed response stops sending.
+var errStreamIdle = fmt.Errorf("no data received for %v", streamIdleTimeout)
+
+// idleTimeoutBody restarts the idle timer whenever data arrives.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired atomic.Bool
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.expired.Load() {
+		b.timer.Reset(streamIdleTimeout)
+	}
+	if err != nil && err != io.EOF && b.expired.Load() {
+		err = errStreamIdle
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// readSSE parses a text/event-stream body and calls fn for every event with
+// its event name (empty when unnamed) and joined data lines.
+func readSSE(body io.Reader, fn func(event string, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive line
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+	return dispatch()
+}
+
+// readNDJSON decodes a newline-delimited JSON body, calling fn for each object.
+func readNDJSON(body io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
+
+// openAIStreamChunk is a single chat.completion.chunk from an OpenAI-compatible server.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// readOpenAIStream consumes an OpenAI-style SSE body (also used by LM Studio)
+// and returns the full generated text.
+func readOpenAIStream(body io.Reader, onDelta StreamHandler) (string, error) {
+	var full strings.Builder
+	err := readSSE(body, func(_ string, data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			full.WriteString(choice.Delta.Content)
+			onDelta(choice.Delta.Content)
+		}
+		return nil
+	})
+	return full.String(), err
+}
@@ -0,0 +1,272 @@
+package connectors
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Tool describes a function the model may call. Parameters is a JSON Schema
+// object describing the arguments; each connector translates it into its
+// provider's tool format.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters" ts_type:"any"`
+}
+
+// ToolCall is a model's request to run a tool. Arguments is a JSON object.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments" ts_type:"any"`
+	// Signature is opaque provider data that must be sent back with the
+	// call, such as a Gemini thought signature.
+	Signature string `json:"signature,omitempty"`
+}
+
+// ToolCaller is implemented by providers that can offer tools to a model.
+// Providers report it through Capabilities.Tools.
+type ToolCaller interface {
+	// ChatWithTools sends a conversation together with the tools the model
+	// may call. The reply is either a final answer or an assistant message
+	// whose ToolCalls must each be answered with a RoleTool message.
+	ChatWithTools(ctx context.Context, model string, messages []ChatMessage, tools []Tool, options map[string]interface{}) (ChatMessage, error)
+}
+
+// Compile-time checks for the connectors that can call tools.
+var (
+	_ ToolCaller = (*OllamaConnector)(nil)
+	_ ToolCaller = (*LMStudioConnector)(nil)
+	_ ToolCaller = (*OpenAIConnector)(nil)
+	_ ToolCaller = (*AnthropicConnector)(nil)
+	_ ToolCaller = (*GoogleConnector)(nil)
+)
+
+// Tool loop limits.
+const (
+	// MaxToolRounds bounds how many times a model may call tools before it
+	// has to answer.
+	MaxToolRounds = 10
+	// maxToolResultBytes caps a tool result sent back to the model.
+	maxToolResultBytes = 32 << 10
+)
+
+// toolNamePattern is the tool name syntax accepted by every provider.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// emptyToolParameters is the schema of a tool without arguments.
+var emptyToolParameters = json.RawMessage(`{"type":"object","properties":{}}`)
+
+// ToolHandler runs a tool with the JSON arguments the model supplied and
+// returns the text sent back to it. An error is reported to the model too,
+// so it can correct its arguments or give up.
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// ToolSet holds the tools offered to models and their handlers. It is safe
+// for concurrent use.
+type ToolSet struct {
+	mu       sync.RWMutex
+	tools    []Tool
+	handlers map[string]ToolHandler
+}
+
+// NewToolSet creates an empty tool set.
+func NewToolSet() *ToolSet {
+	return &ToolSet{handlers: make(map[string]ToolHandler)}
+}
+
+// Register adds a tool. A tool without parameters gets an empty object schema.
+func (s *ToolSet) Register(tool Tool, handler ToolHandler) error {
+	if !toolNamePattern.MatchString(tool.Name) {
+		return fmt.Errorf("invalid tool name %q; use up to 64 letters, digits, _ or -", tool.Name)
+	}
+	if handler == nil {
+		return fmt.Errorf("tool %s has no handler", tool.Name)
+	}
+	if len(tool.Parameters) == 0 {
+		tool.Parameters = emptyToolParameters
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(tool.Parameters, &schema); err != nil {
+		return fmt.Errorf("tool %s: parameters must be a JSON Schema object: %v", tool.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.handlers[tool.Name]; exists {
+		return fmt.Errorf("tool %s is already registered", tool.Name)
+	}
+	s.tools = append(s.tools, tool)
+	s.handlers[tool.Name] = handler
+	return nil
+}
+
+// Unregister removes a tool, reporting whether it was registered.
+func (s *ToolSet) Unregister(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.handlers[name]; !ok {
+		return false
+	}
+	delete(s.handlers, name)
+	s.tools = slices.DeleteFunc(s.tools, func(tool Tool) bool { return tool.Name == name })
+	return true
+}
+
+// Tools lists the registered tools in registration order.
+func (s *ToolSet) Tools() []Tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.tools)
+}
+
+// Call runs the handler of the called tool. Long results are truncated.
+func (s *ToolSet) Call(ctx context.Context, call ToolCall) (string, error) {
+	s.mu.RLock()
+	handler, ok := s.handlers[call.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown tool %s", call.Name)
+	}
+
+	arguments := call.Arguments
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	result, err := handler(ctx, arguments)
+	if err != nil {
+		return "", err
+	}
+	if len(result) > maxToolResultBytes {
+		result = strings.ToValidUTF8(result[:maxToolResultBytes], "") + "\n[output truncated]"
+	}
+	return result, nil
+}
+
+// ToolObserver is told about every tool call once it has run. err is the
+// handler's error, which the model receives as the result.
+type ToolObserver func(call ToolCall, result string, err error)
+
+// RunTools chats with the model, running the tools it calls and sending back
+// their results, until it gives a final answer. It returns the messages
+// added to the conversation, ending with the answer. The model must answer
+// within MaxToolRounds rounds of tool calls.
+func RunTools(ctx context.Context, caller ToolCaller, model string, messages []ChatMessage, options map[string]interface{}, tools *ToolSet, onCall ToolObserver) ([]ChatMessage, error) {
+	available := tools.Tools()
+	var added []ChatMessage
+	for round := 0; ; round++ {
+		reply, err := caller.ChatWithTools(ctx, model, slices.Concat(messages, added), available, options)
+		if err != nil {
+			return added, err
+		}
+		added = append(added, reply)
+		if len(reply.ToolCalls) == 0 {
+			return added, nil
+		}
+		if round == MaxToolRounds {
+			return added, fmt.Errorf("the model was still calling tools after %d rounds", MaxToolRounds)
+		}
+
+		for _, call := range reply.ToolCalls {
+			result, err := tools.Call(ctx, call)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return added, ctxErr
+			}
+			if onCall != nil {
+				onCall(call, result, err)
+			}
+			if err != nil {
+				result = "Error: " + err.Error()
+			}
+			added = append(added, ChatMessage{
+				Role:       RoleTool,
+				Content:    result,
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+			})
+		}
+	}
+}
+
+// toolArguments turns arguments received as text into JSON. Text that is not
+// valid JSON is kept as a JSON string, which handlers reject as arguments.
+func toolArguments(arguments string) json.RawMessage {
+	arguments = strings.TrimSpace(arguments)
+	if arguments == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(arguments)) {
+		return json.RawMessage(arguments)
+	}
+	quoted, _ := json.Marshal(arguments)
+	return quoted
+}
+
+// newToolCallID returns an ID for providers that do not identify tool calls.
+func newToolCallID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return "call_" + hex.EncodeToString(buf)
+}
+
+// toolParameters returns a tool's schema, or an empty object schema.
+func toolParameters(tool Tool) json.RawMessage {
+	if len(tool.Parameters) == 0 {
+		return emptyToolParameters
+	}
+	return tool.Parameters
+}
+
+// toolCallInput returns a call's arguments as a JSON object, for providers
+// that take them as one.
+func toolCallInput(call ToolCall) json.RawMessage {
+	if len(call.Arguments) == 0 || call.Arguments[0] != '{' {
+		return json.RawMessage("{}")
+	}
+	return call.Arguments
+}
+
+// toOpenAITools declares tools in the OpenAI function format, which Ollama
+// and LM Studio use as well.
+func toOpenAITools(tools []Tool) []openAITool {
+	var openAITools []openAITool
+	for _, tool := range tools {
+		openAITools = append(openAITools, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: tool.Name, Description: tool.Description, Parameters: toolParameters(tool)},
+		})
+	}
+	return openAITools
+}
+
+// toOpenAIToolCalls converts tool calls to the OpenAI format.
+func toOpenAIToolCalls(calls []ToolCall) []openAIToolCall {
+	openAICalls := make([]openAIToolCall, 0, len(calls))
+	for _, call := range calls {
+		openAICall := openAIToolCall{ID: call.ID, Type: "function"}
+		openAICall.Function.Name = call.Name
+		openAICall.Function.Arguments = string(toolCallInput(call))
+		openAICalls = append(openAICalls, openAICall)
+	}
+	return openAICalls
+}
+
+// fromOpenAIToolCalls converts the tool calls of an OpenAI-style reply.
+func fromOpenAIToolCalls(calls []openAIToolCall) []ToolCall {
+	var toolCalls []ToolCall
+	for _, call := range calls {
+		id := call.ID
+		if id == "" {
+			id = newToolCallID()
+		}
+		toolCalls = append(toolCalls, ToolCall{ID: id, Name: call.Function.Name, Arguments: toolArguments(call.Function.Arguments)})
+	}
+	return toolCalls
+}
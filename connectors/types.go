@@ -2,16 +2,40 @@ package connectors
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
-// Model represents a local model
+// Model describes a model offered by a provider. Size and Modified are
+// preformatted for display; the other fields are filled in as far as the
+// provider's API exposes them and are left zero when unknown.
 type Model struct {
     Name     string `json:"name"`
     Size     string `json:"size,omitempty"`
     Modified string `json:"modified,omitempty"`
+
+    DisplayName     string   `json:"display_name,omitempty"`
+    SizeBytes       int64    `json:"size_bytes,omitempty"`
+    Digest          string   `json:"digest,omitempty"`
+    Family          string   `json:"family,omitempty"`
+    ParameterSize   string   `json:"parameter_size,omitempty"`
+    Quantization    string   `json:"quantization,omitempty"`
+    Format          string   `json:"format,omitempty"`
+    ContextLength   int      `json:"context_length,omitempty"`
+    MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+    // Modalities lists the accepted input types, e.g. "text" and "image".
+    Modalities     []string `json:"modalities,omitempty"`
+    SupportsTools  bool     `json:"supports_tools"`
+    SupportsVision bool     `json:"supports_vision"`
 }
 
+// Input modalities reported in Model.Modalities
+const (
+    ModalityText  = "text"
+    ModalityImage = "image"
+    ModalityAudio = "audio"
+)
+
 // ScanResult represents the result of model scanning
 type ScanResult struct {
     Models  []Model `json:"models"`
@@ -19,11 +43,51 @@ type ScanResult struct {
     Success bool    `json:"success"`
 }
 
+// Chat roles understood by every connector
+const (
+    RoleSystem    = "system"
+    RoleUser      = "user"
+    RoleAssistant = "assistant"
+    // RoleTool carries the result of a tool call back to the model.
+    RoleTool = "tool"
+)
+
+// ChatMessage is a single role-tagged turn in a conversation. Images may
+// only be attached to user messages.
+type ChatMessage struct {
+    Role    string      `json:"role"`
+    Content string      `json:"content"`
+    Images  []ImagePart `json:"images,omitempty"`
+    // ToolCalls are the tools an assistant message asks to run.
+    ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+    // ToolCallID and ToolName identify the call a RoleTool message answers.
+    ToolCallID string `json:"tool_call_id,omitempty"`
+    ToolName   string `json:"tool_name,omitempty"`
+}
+
 // ModelConnector interface for all model connectors
 type ModelConnector interface {
     ScanModels() ScanResult
 }
 
+// splitSystemMessages separates system messages from the conversation turns.
+// Providers with a dedicated system field (Anthropic, Gemini) use this to
+// lift the instructions out of the message list.
+func splitSystemMessages(messages []ChatMessage) (string, []ChatMessage) {
+    var system []string
+    turns := make([]ChatMessage, 0, len(messages))
+    for _, msg := range messages {
+        if msg.Role == RoleSystem {
+            if msg.Content != "" {
+                system = append(system, msg.Content)
+            }
+            continue
+        }
+        turns = append(turns, msg)
+    }
+    return strings.Join(system, "\n\n"), turns
+}
+
 // formatBytes converts bytes to human readable format
 func formatBytes(bytes int64) string {
     if bytes == 0 {
@@ -1,19 +1,25 @@
 package connectors
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
 )
 
 // CheckConnectivity performs a quick health check on the given endpoint
-func CheckConnectivity(endpoint string, healthPath string) error {
+func CheckConnectivity(ctx context.Context, endpoint string, healthPath string) error {
     client := &http.Client{
         Timeout: 3 * time.Second,
     }
     
     url := endpoint + healthPath
-    resp, err := client.Get(url)
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return fmt.Errorf("invalid endpoint: %w", err)
+    }
+
+    resp, err := client.Do(req)
     if err != nil {
         return fmt.Errorf("connection failed: %w", err)
     }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"myproject/conversations"
+)
+
+// CreateConversation starts a new saved conversation.
+func (a *App) CreateConversation(title string) (conversations.Conversation, error) {
+	store, err := a.requireConversationStore()
+	if err != nil {
+		return conversations.Conversation{}, err
+	}
+	return store.Create(title)
+}
+
+// ListConversations returns all saved conversations, most recent first, without messages.
+func (a *App) ListConversations() ([]conversations.Conversation, error) {
+	store, err := a.requireConversationStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.List()
+}
+
+// LoadConversation returns a saved conversation with all of its messages.
+func (a *App) LoadConversation(id string) (conversations.Conversation, error) {
+	store, err := a.requireConversationStore()
+	if err != nil {
+		return conversations.Conversation{}, err
+	}
+	return store.Load(id)
+}
+
+// RenameConversation changes a saved conversation's title.
+func (a *App) RenameConversation(id string, title string) (conversations.Conversation, error) {
+	store, err := a.requireConversationStore()
+	if err != nil {
+		return conversations.Conversation{}, err
+	}
+	return store.Rename(id, title)
+}
+
+// DeleteConversation removes a saved conversation and its messages.
+func (a *App) DeleteConversation(id string) error {
+	store, err := a.requireConversationStore()
+	if err != nil {
+		return err
+	}
+	return store.Delete(id)
+}
+
+// AppendMessage adds a message to a saved conversation and returns it with
+// its ID and timestamp filled in.
+func (a *App) AppendMessage(conversationID string, message conversations.Message) (conversations.Message, error) {
+	store, err := a.requireConversationStore()
+	if err != nil {
+		return conversations.Message{}, err
+	}
+	return store.Append(conversationID, message)
+}
+
+// SearchConversations finds saved messages, titles and model names matching
+// query, best matches first.
+func (a *App) SearchConversations(query string) ([]conversations.SearchHit, error) {
+	store, err := a.requireConversationStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Search(query, 0)
+}
+
+// requireConversationStore returns the conversation store or an error when it is unavailable.
+func (a *App) requireConversationStore() (*conversations.Store, error) {
+	if a.conversationStore == nil {
+		return nil, fmt.Errorf("conversation storage is unavailable: no home directory")
+	}
+	return a.conversationStore, nil
+}
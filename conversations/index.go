@@ -0,0 +1,214 @@
+package conversations
+
+import (
+	"encoding/json"
+	"fmt"
+	"myproject/internal/atomicfile"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Searchable fields of a conversation.
+const (
+	FieldTitle   = "title"
+	FieldContent = "content"
+	FieldModel   = "model"
+)
+
+// indexDoc is one searchable unit within a conversation: its title, a
+// message, or a model name used in it.
+type indexDoc struct {
+	Field string         `json:"field"`
+	Terms map[string]int `json:"terms"`
+	Len   int            `json:"len"`
+}
+
+// shard is the persisted index of a single conversation, keyed by doc ID
+// ("title", "m:<message id>" or "model:<provider>/<model>"). Messages records
+// how many messages were indexed so a stale shard can be detected on load.
+type shard struct {
+	Messages int                 `json:"messages"`
+	Docs     map[string]indexDoc `json:"docs"`
+}
+
+type docRef struct {
+	conversationID string
+	docID          string
+}
+
+// searchIndex is an inverted index over all conversations. It is assembled
+// from per-conversation shards at load time and updated on every write.
+type searchIndex struct {
+	terms    map[string]map[docRef]int
+	docs     map[docRef]indexDoc
+	shards   map[string]*shard
+	totalLen int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		terms:  make(map[string]map[docRef]int),
+		docs:   make(map[docRef]indexDoc),
+		shards: make(map[string]*shard),
+	}
+}
+
+// setDoc indexes text under the given doc, replacing any previous version.
+func (idx *searchIndex) setDoc(conversationID string, docID string, field string, text string) {
+	idx.removeDoc(conversationID, docID)
+
+	termCounts := make(map[string]int)
+	length := 0
+	for _, tok := range tokenize(text) {
+		termCounts[tok.term]++
+		length++
+	}
+	doc := indexDoc{Field: field, Terms: termCounts, Len: length}
+
+	sh := idx.shards[conversationID]
+	if sh == nil {
+		sh = &shard{Docs: make(map[string]indexDoc)}
+		idx.shards[conversationID] = sh
+	}
+	sh.Docs[docID] = doc
+	idx.addDoc(docRef{conversationID, docID}, doc)
+}
+
+// setMessageCount records how many messages a conversation's shard covers.
+func (idx *searchIndex) setMessageCount(conversationID string, count int) {
+	if sh := idx.shards[conversationID]; sh != nil {
+		sh.Messages = count
+	}
+}
+
+// hasDoc reports whether a doc is already indexed.
+func (idx *searchIndex) hasDoc(conversationID string, docID string) bool {
+	_, ok := idx.docs[docRef{conversationID, docID}]
+	return ok
+}
+
+func (idx *searchIndex) addDoc(ref docRef, doc indexDoc) {
+	idx.docs[ref] = doc
+	idx.totalLen += doc.Len
+	for term, count := range doc.Terms {
+		postings := idx.terms[term]
+		if postings == nil {
+			postings = make(map[docRef]int)
+			idx.terms[term] = postings
+		}
+		postings[ref] = count
+	}
+}
+
+func (idx *searchIndex) removeDoc(conversationID string, docID string) {
+	ref := docRef{conversationID, docID}
+	doc, ok := idx.docs[ref]
+	if !ok {
+		return
+	}
+	for term := range doc.Terms {
+		delete(idx.terms[term], ref)
+		if len(idx.terms[term]) == 0 {
+			delete(idx.terms, term)
+		}
+	}
+	idx.totalLen -= doc.Len
+	delete(idx.docs, ref)
+	if sh := idx.shards[conversationID]; sh != nil {
+		delete(sh.Docs, docID)
+	}
+}
+
+// removeConversation drops every doc belonging to a conversation.
+func (idx *searchIndex) removeConversation(conversationID string) {
+	sh := idx.shards[conversationID]
+	if sh == nil {
+		return
+	}
+	for docID := range sh.Docs {
+		idx.removeDoc(conversationID, docID)
+	}
+	delete(idx.shards, conversationID)
+}
+
+// loadShard adds a persisted shard to the index.
+func (idx *searchIndex) loadShard(conversationID string, sh *shard) {
+	idx.removeConversation(conversationID)
+	if sh.Docs == nil {
+		sh.Docs = make(map[string]indexDoc)
+	}
+	idx.shards[conversationID] = sh
+	for docID, doc := range sh.Docs {
+		idx.addDoc(docRef{conversationID, docID}, doc)
+	}
+}
+
+// readShard reads a conversation's persisted index shard.
+func readShard(path string) (*shard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sh shard
+	if err := json.Unmarshal(data, &sh); err != nil {
+		return nil, fmt.Errorf("failed to parse search index: %w", err)
+	}
+	return &sh, nil
+}
+
+// writeShard persists a conversation's index shard.
+func (idx *searchIndex) writeShard(path string, conversationID string) error {
+	sh := idx.shards[conversationID]
+	if sh == nil {
+		sh = &shard{Docs: map[string]indexDoc{}}
+	}
+	data, err := json.Marshal(sh)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %w", err)
+	}
+	return atomicfile.WriteFile(path, data, 0600)
+}
+
+// token is a normalized term with its byte span in the original text.
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// tokenize splits text into lowercase alphanumeric terms. Single-character
+// terms are dropped because they match almost everything.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if utf8.RuneCountInString(text[start:end]) > 1 {
+			tokens = append(tokens, token{term: strings.ToLower(text[start:end]), start: start, end: end})
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+func messageDocID(messageID string) string {
+	return "m:" + messageID
+}
+
+func modelDocID(provider string, model string) string {
+	return "model:" + provider + "/" + model
+}
@@ -0,0 +1,298 @@
+package conversations
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+
+	// BM25 parameters.
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// prefixWeight discounts prefix matches on the last query term, which
+	// keeps search-as-you-type useful without outranking exact matches.
+	prefixWeight = 0.6
+
+	snippetBefore = 60
+	snippetLength = 200
+)
+
+// fieldBoost weights matches by where they occur.
+var fieldBoost = map[string]float64{
+	FieldTitle:   2.0,
+	FieldModel:   1.5,
+	FieldContent: 1.0,
+}
+
+// Highlight marks a matched term inside a snippet. Offsets are in UTF-16 code
+// units so they can be used directly with JavaScript string indexing.
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchHit is a single ranked match. MessageID and Role are only set for
+// content matches.
+type SearchHit struct {
+	ConversationID    string      `json:"conversation_id"`
+	ConversationTitle string      `json:"conversation_title"`
+	MessageID         string      `json:"message_id,omitempty"`
+	Role              string      `json:"role,omitempty"`
+	Field             string      `json:"field"`
+	Score             float64     `json:"score"`
+	Snippet           string      `json:"snippet"`
+	Highlights        []Highlight `json:"highlights"`
+}
+
+// queryTerm is a term from the search query. Prefix terms also match any
+// indexed term that starts with them.
+type queryTerm struct {
+	term   string
+	prefix bool
+}
+
+func (q queryTerm) matches(term string) bool {
+	if q.prefix {
+		return strings.HasPrefix(term, q.term)
+	}
+	return term == q.term
+}
+
+// Search ranks message content, titles and model names against query using
+// BM25 and returns up to limit hits. A limit of zero uses the default.
+func (s *Store) Search(query string, limit int) ([]SearchHit, error) {
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	terms := parseQuery(query)
+	if len(terms) == 0 {
+		return []SearchHit{}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	type scored struct {
+		ref     docRef
+		score   float64
+		matched int
+	}
+	results := make(map[docRef]*scored)
+	docCount := float64(len(s.index.docs))
+	if docCount == 0 {
+		return []SearchHit{}, nil
+	}
+	avgLen := float64(s.index.totalLen) / docCount
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	for _, q := range terms {
+		matchedDocs := make(map[docRef]bool)
+		for term, weight := range s.index.expand(q) {
+			postings := s.index.terms[term]
+			df := float64(len(postings))
+			idf := math.Log(1 + (docCount-df+0.5)/(df+0.5))
+			for ref, tf := range postings {
+				doc := s.index.docs[ref]
+				norm := float64(tf) + bm25K1*(1-bm25B+bm25B*float64(doc.Len)/avgLen)
+				score := idf * float64(tf) * (bm25K1 + 1) / norm * weight * fieldBoost[doc.Field]
+
+				r := results[ref]
+				if r == nil {
+					r = &scored{ref: ref}
+					results[ref] = r
+				}
+				r.score += score
+				if !matchedDocs[ref] {
+					matchedDocs[ref] = true
+					r.matched++
+				}
+			}
+		}
+	}
+
+	ranked := make([]*scored, 0, len(results))
+	for _, r := range results {
+		// Favour docs that match more of the query.
+		r.score *= float64(r.matched) / float64(len(terms))
+		ranked = append(ranked, r)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].matched != ranked[j].matched {
+			return ranked[i].matched > ranked[j].matched
+		}
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return s.metas[ranked[i].ref.conversationID].UpdatedAt.After(s.metas[ranked[j].ref.conversationID].UpdatedAt)
+	})
+
+	hits := []SearchHit{}
+	messageCache := make(map[string]map[string]Message)
+	for _, r := range ranked {
+		if len(hits) >= limit {
+			break
+		}
+		conv, ok := s.metas[r.ref.conversationID]
+		if !ok {
+			continue
+		}
+		hit := SearchHit{
+			ConversationID:    conv.ID,
+			ConversationTitle: conv.Title,
+			Field:             s.index.docs[r.ref].Field,
+			Score:             r.score,
+		}
+
+		var text string
+		switch hit.Field {
+		case FieldTitle:
+			text = conv.Title
+		case FieldModel:
+			text = strings.TrimPrefix(r.ref.docID, "model:")
+		case FieldContent:
+			messages, ok := messageCache[conv.ID]
+			if !ok {
+				list, err := s.readMessages(conv.ID)
+				if err != nil {
+					return nil, err
+				}
+				messages = make(map[string]Message, len(list))
+				for _, msg := range list {
+					messages[msg.ID] = msg
+				}
+				messageCache[conv.ID] = messages
+			}
+			msg, ok := messages[strings.TrimPrefix(r.ref.docID, "m:")]
+			if !ok {
+				continue
+			}
+			hit.MessageID = msg.ID
+			hit.Role = msg.Role
+			text = msg.Content
+		}
+
+		hit.Snippet, hit.Highlights = snippet(text, terms)
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// parseQuery tokenizes a query into unique terms. The last term is treated as
+// a prefix unless the query ends with a separator, so partially typed words
+// still match.
+func parseQuery(query string) []queryTerm {
+	tokens := tokenize(query)
+	seen := make(map[string]bool)
+	terms := make([]queryTerm, 0, len(tokens))
+	for i, tok := range tokens {
+		prefix := i == len(tokens)-1 && tok.end == len(query)
+		if seen[tok.term] {
+			continue
+		}
+		seen[tok.term] = true
+		terms = append(terms, queryTerm{term: tok.term, prefix: prefix})
+	}
+	return terms
+}
+
+// expand returns the indexed terms a query term matches and the weight of
+// each match.
+func (idx *searchIndex) expand(q queryTerm) map[string]float64 {
+	matches := make(map[string]float64)
+	if _, ok := idx.terms[q.term]; ok {
+		matches[q.term] = 1
+	}
+	if q.prefix {
+		for term := range idx.terms {
+			if term != q.term && strings.HasPrefix(term, q.term) {
+				matches[term] = prefixWeight
+			}
+		}
+	}
+	return matches
+}
+
+// snippet cuts a window of text around the first matching term and returns
+// it with highlight offsets for every match inside the window.
+func snippet(text string, terms []queryTerm) (string, []Highlight) {
+	tokens := tokenize(text)
+	var matched []token
+	for _, tok := range tokens {
+		for _, q := range terms {
+			if q.matches(tok.term) {
+				matched = append(matched, tok)
+				break
+			}
+		}
+	}
+
+	start := 0
+	if len(matched) > 0 && matched[0].start > snippetBefore {
+		start = matched[0].start - snippetBefore
+		if space := strings.IndexAny(text[start:matched[0].start], " \t\n"); space >= 0 {
+			start += space + 1
+		}
+		for start < len(text) && !utf8.RuneStart(text[start]) {
+			start++
+		}
+	}
+	end := start + snippetLength
+	if end >= len(text) {
+		end = len(text)
+	} else {
+		if space := strings.LastIndexAny(text[start:end], " \t\n"); space > 0 {
+			end = start + space
+		}
+		for end > start && !utf8.RuneStart(text[end]) {
+			end--
+		}
+	}
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(text) {
+		suffix = "…"
+	}
+	// Line breaks are flattened one-for-one so offsets stay valid.
+	body := strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, text[start:end])
+
+	highlights := []Highlight{}
+	for _, tok := range matched {
+		if tok.start < start || tok.end > end {
+			continue
+		}
+		offset := utf16Len(prefix) + utf16Len(text[start:tok.start])
+		highlights = append(highlights, Highlight{
+			Start: offset,
+			End:   offset + utf16Len(text[tok.start:tok.end]),
+		})
+	}
+	return prefix + body + suffix, highlights
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
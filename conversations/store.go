@@ -0,0 +1,455 @@
+package conversations
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"myproject/connectors"
+	"myproject/internal/atomicfile"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTitle is used when a conversation is created without a title.
+const DefaultTitle = "New Conversation"
+
+// Message is a single persisted chat message. Provider and Model record which
+// model produced (or was addressed by) the message.
+type Message struct {
+	ID        string    `json:"id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Provider  string    `json:"provider,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Images attached to a user message, stored inline in the message log
+	Images []connectors.ImagePart `json:"images,omitempty"`
+}
+
+// Conversation holds a conversation's metadata. Messages is only filled in by
+// Load. PresetID names the system-prompt preset attached to the conversation.
+type Conversation struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Provider     string    `json:"provider,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	PresetID     string    `json:"preset_id,omitempty"`
+	MessageCount int       `json:"message_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Messages     []Message `json:"messages,omitempty"`
+}
+
+// ChatMessages converts the stored messages to the form sent to connectors.
+func (c Conversation) ChatMessages() []connectors.ChatMessage {
+	messages := make([]connectors.ChatMessage, 0, len(c.Messages))
+	for _, msg := range c.Messages {
+		messages = append(messages, connectors.ChatMessage{Role: msg.Role, Content: msg.Content, Images: msg.Images})
+	}
+	return messages
+}
+
+var idPattern = regexp.MustCompile(`^[a-f0-9]{16,64}$`)
+
+// maxMessageLineBytes is the longest message log line read back: a message
+// carrying the most images a request allows, base64 encoded.
+const maxMessageLineBytes = 256 << 20
+
+// Store persists conversations under a directory. Each conversation has a
+// <id>.json metadata file, an append-only <id>.jsonl message log and an
+// <id>.index.json search index shard.
+type Store struct {
+	dir string
+
+	mu     sync.RWMutex
+	loaded bool
+	metas  map[string]Conversation
+	index  *searchIndex
+}
+
+// NewStore creates a store rooted at dir. The directory is created on first write.
+func NewStore(dir string) *Store {
+	return &Store{
+		dir:   dir,
+		metas: make(map[string]Conversation),
+		index: newSearchIndex(),
+	}
+}
+
+// Create starts a new, empty conversation.
+func (s *Store) Create(title string) (Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return Conversation{}, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Conversation{}, err
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = DefaultTitle
+	}
+
+	now := time.Now()
+	conv := Conversation{
+		ID:        id,
+		Title:     title,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.writeMeta(conv); err != nil {
+		return Conversation{}, err
+	}
+	s.metas[id] = conv
+	s.index.setDoc(id, FieldTitle, FieldTitle, title)
+	s.saveShard(id)
+	return conv, nil
+}
+
+// List returns every conversation's metadata, most recently updated first.
+func (s *Store) List() ([]Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	list := make([]Conversation, 0, len(s.metas))
+	for _, conv := range s.metas {
+		list = append(list, conv)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].UpdatedAt.After(list[j].UpdatedAt)
+	})
+	return list, nil
+}
+
+// Get returns a conversation's metadata without its messages.
+func (s *Store) Get(id string) (Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return Conversation{}, err
+	}
+	conv, ok := s.metas[id]
+	if !ok {
+		return Conversation{}, fmt.Errorf("conversation %s not found", id)
+	}
+	return conv, nil
+}
+
+// Load returns a conversation together with all of its messages.
+func (s *Store) Load(id string) (Conversation, error) {
+	conv, err := s.Get(id)
+	if err != nil {
+		return Conversation{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	messages, err := s.readMessages(id)
+	if err != nil {
+		return Conversation{}, err
+	}
+	conv.Messages = messages
+	return conv, nil
+}
+
+// Rename changes a conversation's title.
+func (s *Store) Rename(id string, title string) (Conversation, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return Conversation{}, fmt.Errorf("title cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return Conversation{}, err
+	}
+	conv, ok := s.metas[id]
+	if !ok {
+		return Conversation{}, fmt.Errorf("conversation %s not found", id)
+	}
+
+	conv.Title = title
+	conv.UpdatedAt = time.Now()
+	if err := s.writeMeta(conv); err != nil {
+		return Conversation{}, err
+	}
+	s.metas[id] = conv
+	s.index.setDoc(id, FieldTitle, FieldTitle, title)
+	s.saveShard(id)
+	return conv, nil
+}
+
+// SetPreset attaches a system-prompt preset to a conversation. An empty
+// presetID detaches it.
+func (s *Store) SetPreset(id string, presetID string) (Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return Conversation{}, err
+	}
+	conv, ok := s.metas[id]
+	if !ok {
+		return Conversation{}, fmt.Errorf("conversation %s not found", id)
+	}
+
+	conv.PresetID = presetID
+	if err := s.writeMeta(conv); err != nil {
+		return Conversation{}, err
+	}
+	s.metas[id] = conv
+	return conv, nil
+}
+
+// Delete removes a conversation and its messages.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	if _, ok := s.metas[id]; !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	if err := os.Remove(s.metaPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	if err := os.Remove(s.messagesPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete conversation messages: %w", err)
+	}
+	if err := os.Remove(s.indexPath(id)); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Warning: could not delete search index for conversation %s: %v\n", id, err)
+	}
+	delete(s.metas, id)
+	s.index.removeConversation(id)
+	return nil
+}
+
+// Append adds a message to the end of a conversation. The message ID and
+// timestamp are filled in when missing.
+func (s *Store) Append(id string, msg Message) (Message, error) {
+	switch msg.Role {
+	case connectors.RoleSystem, connectors.RoleUser, connectors.RoleAssistant:
+	default:
+		return Message{}, fmt.Errorf("unsupported message role %q", msg.Role)
+	}
+	if len(msg.Images) > 0 && msg.Role != connectors.RoleUser {
+		return Message{}, fmt.Errorf("only user messages can have images")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureLoaded(); err != nil {
+		return Message{}, err
+	}
+	conv, ok := s.metas[id]
+	if !ok {
+		return Message{}, fmt.Errorf("conversation %s not found", id)
+	}
+
+	if msg.ID == "" {
+		msgID, err := newID()
+		if err != nil {
+			return Message{}, err
+		}
+		msg.ID = msgID
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	file, err := os.OpenFile(s.messagesPath(id), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to open conversation log: %w", err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to append message: %w", err)
+	}
+
+	conv.MessageCount++
+	conv.UpdatedAt = msg.CreatedAt
+	if msg.Provider != "" {
+		conv.Provider = msg.Provider
+	}
+	if msg.Model != "" {
+		conv.Model = msg.Model
+	}
+	if err := s.writeMeta(conv); err != nil {
+		return Message{}, err
+	}
+	s.metas[id] = conv
+	s.indexMessage(id, msg)
+	s.index.setMessageCount(id, conv.MessageCount)
+	s.saveShard(id)
+	return msg, nil
+}
+
+// ensureLoaded reads all metadata files into memory the first time the store
+// is used. Callers must hold the write lock.
+func (s *Store) ensureLoaded() error {
+	if s.loaded {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create conversations directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read conversations directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		id := strings.TrimSuffix(name, ".json")
+		if !idPattern.MatchString(id) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read conversation %s: %v\n", id, err)
+			continue
+		}
+		var conv Conversation
+		if err := json.Unmarshal(data, &conv); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not parse conversation %s: %v\n", id, err)
+			continue
+		}
+		conv.ID = id
+		conv.Messages = nil
+		s.metas[id] = conv
+		s.loadIndex(conv)
+	}
+	s.loaded = true
+	return nil
+}
+
+// loadIndex adds a conversation's search index shard to the in-memory index,
+// rebuilding it from the message log when it is missing or out of date.
+func (s *Store) loadIndex(conv Conversation) {
+	sh, err := readShard(s.indexPath(conv.ID))
+	if err == nil && sh.Messages == conv.MessageCount {
+		s.index.loadShard(conv.ID, sh)
+		s.index.setDoc(conv.ID, FieldTitle, FieldTitle, conv.Title)
+		return
+	}
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Warning: rebuilding search index for conversation %s: %v\n", conv.ID, err)
+	}
+
+	s.index.removeConversation(conv.ID)
+	s.index.setDoc(conv.ID, FieldTitle, FieldTitle, conv.Title)
+	messages, err := s.readMessages(conv.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not index conversation %s: %v\n", conv.ID, err)
+		return
+	}
+	for _, msg := range messages {
+		s.indexMessage(conv.ID, msg)
+	}
+	s.index.setMessageCount(conv.ID, conv.MessageCount)
+	s.saveShard(conv.ID)
+}
+
+// indexMessage adds a message, and the model it was sent to, to the index.
+func (s *Store) indexMessage(id string, msg Message) {
+	s.index.setDoc(id, messageDocID(msg.ID), FieldContent, msg.Content)
+	if msg.Model != "" && !s.index.hasDoc(id, modelDocID(msg.Provider, msg.Model)) {
+		s.index.setDoc(id, modelDocID(msg.Provider, msg.Model), FieldModel, msg.Provider+" "+msg.Model)
+	}
+}
+
+// saveShard persists a conversation's index shard. A failed write only costs
+// a rebuild on the next load, so it is not reported to the caller.
+func (s *Store) saveShard(id string) {
+	if err := s.index.writeShard(s.indexPath(id), id); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save search index for conversation %s: %v\n", id, err)
+	}
+}
+
+// readMessages reads a conversation's message log, skipping corrupt lines.
+func (s *Store) readMessages(id string) ([]Message, error) {
+	file, err := os.Open(s.messagesPath(id))
+	if os.IsNotExist(err) {
+		return []Message{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open conversation log: %w", err)
+	}
+	defer file.Close()
+
+	messages := []Message{}
+	scanner := bufio.NewScanner(file)
+	// Lines holding image attachments can run to tens of megabytes
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageLineBytes)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping corrupt message in conversation %s: %v\n", id, err)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read conversation log: %w", err)
+	}
+	return messages, nil
+}
+
+// writeMeta atomically replaces a conversation's metadata file.
+func (s *Store) writeMeta(conv Conversation) error {
+	conv.Messages = nil
+	data, err := json.MarshalIndent(conv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+	return atomicfile.WriteFile(s.metaPath(conv.ID), data, 0600)
+}
+
+func (s *Store) metaPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *Store) messagesPath(id string) string {
+	return filepath.Join(s.dir, id+".jsonl")
+}
+
+func (s *Store) indexPath(id string) string {
+	return filepath.Join(s.dir, id+".index.json")
+}
+
+// newID returns a random 16-character hex identifier.
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"myproject/connectors"
+	"myproject/secrets"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Where a custom provider's models come from.
+const (
+	// CustomModelsFromServer lists the models the server reports at /models.
+	CustomModelsFromServer = "server"
+	// CustomModelsFromList uses the models given in the config.
+	CustomModelsFromList = "list"
+)
+
+// customProviderNamePattern keeps names usable as provider IDs in model
+// config keys and CLI config keys.
+var customProviderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// CustomProviderConfig defines a user-defined provider that speaks the OpenAI
+// Chat Completions API, e.g. vLLM, the llama.cpp server or LiteLLM. The API
+// key and the values of Headers are kept in the secret store, so config.json
+// and GetCustomProviders only carry the header names and whether there is a key.
+type CustomProviderConfig struct {
+	// BaseURL is the API's base URL, e.g. http://localhost:8000/v1.
+	BaseURL     string            `json:"base_url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ModelSource string            `json:"model_source"`
+	Models      []string          `json:"models,omitempty"`
+	HasAPIKey   bool              `json:"has_api_key"`
+}
+
+// CustomProvider is a configured custom provider.
+type CustomProvider struct {
+	Name   string               `json:"name"`
+	Config CustomProviderConfig `json:"config"`
+}
+
+// customProviderSecretValues holds the API key and header values of a custom provider.
+type customProviderSecretValues struct {
+	APIKey  string            `json:"api_key,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// GetCustomProviders lists the custom providers by name.
+func (a *App) GetCustomProviders() []CustomProvider {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	providers := make([]CustomProvider, 0, len(a.customProviderConfigs))
+	for _, name := range slices.Sorted(maps.Keys(a.customProviderConfigs)) {
+		providers = append(providers, CustomProvider{Name: name, Config: a.customProviderConfigs[name]})
+	}
+	return providers
+}
+
+// AddCustomProvider checks that a custom provider's server answers and saves
+// it, replacing the one with that name. An empty API key, or an empty value
+// for a header, keeps the saved one.
+func (a *App) AddCustomProvider(name string, config CustomProviderConfig, apiKey string) (CustomProvider, error) {
+	if !customProviderNamePattern.MatchString(name) {
+		return CustomProvider{}, fmt.Errorf("invalid provider name %q; use up to 32 lowercase letters, digits, _ or -", name)
+	}
+	a.configMutex.RLock()
+	saved, exists := a.customProviderConfigs[name]
+	a.configMutex.RUnlock()
+	if _, registered := a.registry.Info(name); registered && !exists {
+		return CustomProvider{}, fmt.Errorf("provider %s already exists", name)
+	}
+
+	config, err := normalizeCustomProvider(config)
+	if err != nil {
+		return CustomProvider{}, err
+	}
+
+	var values customProviderSecretValues
+	if exists {
+		if values, err = a.customProviderSecrets(name, saved); err != nil {
+			return CustomProvider{}, err
+		}
+	}
+	values.Headers = mergeSecretValues(config.Headers, values.Headers)
+	if apiKey = strings.TrimSpace(apiKey); apiKey != "" {
+		values.APIKey = apiKey
+	}
+	config.Headers = secretNames(config.Headers)
+	config.HasAPIKey = values.APIKey != ""
+
+	p := connectors.NewOpenAICompatibleConnector(name, config.BaseURL, values.APIKey, values.Headers, config.Models)
+	if err := p.HealthCheck(context.Background()); err != nil {
+		return CustomProvider{}, fmt.Errorf("cannot reach %s at %s: %v", name, config.BaseURL, err)
+	}
+
+	if values.APIKey == "" && len(values.Headers) == 0 {
+		a.deleteCustomProviderSecrets(name)
+	} else if err := a.storeCustomProviderSecrets(name, values); err != nil {
+		return CustomProvider{}, err
+	}
+
+	a.configMutex.Lock()
+	a.customProviderConfigs[name] = config
+	// The base URL replaces any endpoint set for the provider
+	delete(a.providerEndpoints, name)
+	a.registry.Unregister(name)
+	err = a.registerCustomProvider(name, config)
+	a.configMutex.Unlock()
+	a.modelCache.Invalidate(name)
+	if err != nil {
+		return CustomProvider{}, err
+	}
+	return CustomProvider{Name: name, Config: config}, a.saveConfig()
+}
+
+// RemoveCustomProvider deletes a custom provider with its secrets and endpoint.
+func (a *App) RemoveCustomProvider(name string) error {
+	a.configMutex.Lock()
+	_, ok := a.customProviderConfigs[name]
+	if ok {
+		delete(a.customProviderConfigs, name)
+		delete(a.providerEndpoints, name)
+		a.registry.Unregister(name)
+	}
+	a.configMutex.Unlock()
+	if !ok {
+		return fmt.Errorf("custom provider %s not found", name)
+	}
+	a.modelCache.Invalidate(name)
+	a.deleteCustomProviderSecrets(name)
+	return a.saveConfig()
+}
+
+// normalizeCustomProvider checks a custom provider's base URL and model
+// source, and cleans up its model list.
+func normalizeCustomProvider(config CustomProviderConfig) (CustomProviderConfig, error) {
+	baseURL, err := normalizeEndpoint(config.BaseURL)
+	if err != nil {
+		return config, err
+	}
+	config.BaseURL = baseURL
+	switch config.ModelSource {
+	case "", CustomModelsFromServer:
+		config.ModelSource, config.Models = CustomModelsFromServer, nil
+	case CustomModelsFromList:
+		var models []string
+		for _, model := range config.Models {
+			if model = strings.TrimSpace(model); model != "" && !slices.Contains(models, model) {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			return config, fmt.Errorf("a model list is required")
+		}
+		config.Models = models
+	default:
+		return config, fmt.Errorf("unsupported model source %q; use %s or %s", config.ModelSource, CustomModelsFromServer, CustomModelsFromList)
+	}
+	return config, nil
+}
+
+// registerCustomProviders replaces the custom providers in the registry with
+// those of a loaded config, dropping any whose name a built-in uses.
+func (a *App) registerCustomProviders(previous map[string]CustomProviderConfig) {
+	for name := range previous {
+		a.registry.Unregister(name)
+		a.modelCache.Invalidate(name)
+	}
+	a.configMutex.RLock()
+	configs := maps.Clone(a.customProviderConfigs)
+	a.configMutex.RUnlock()
+
+	var skipped []string
+	for name, config := range configs {
+		if err := a.registerCustomProvider(name, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping custom provider %s: %v\n", name, err)
+			skipped = append(skipped, name)
+		}
+	}
+	if len(skipped) > 0 {
+		a.configMutex.Lock()
+		for _, name := range skipped {
+			delete(a.customProviderConfigs, name)
+		}
+		a.configMutex.Unlock()
+	}
+}
+
+// registerCustomProvider adds a custom provider to the registry. Its key and
+// headers are filled in by customProviderSettings when it is built.
+func (a *App) registerCustomProvider(name string, config CustomProviderConfig) error {
+	models := config.Models
+	return a.registry.Register(name, config.BaseURL, func(s connectors.ProviderSettings) connectors.Provider {
+		return connectors.NewOpenAICompatibleConnector(name, s.Endpoint, s.APIKey, s.Headers, models)
+	})
+}
+
+// customProviderSettings adds the API key and headers of a custom provider
+// to its settings. Other providers are left as they are.
+func (a *App) customProviderSettings(name string, settings *connectors.ProviderSettings) error {
+	a.configMutex.RLock()
+	config, ok := a.customProviderConfigs[name]
+	a.configMutex.RUnlock()
+	if !ok {
+		return nil
+	}
+	values, err := a.customProviderSecrets(name, config)
+	if err != nil {
+		return err
+	}
+	settings.APIKey, settings.Headers = values.APIKey, values.Headers
+	return nil
+}
+
+// customProviderSecrets reads the API key and header values of a custom
+// provider. The secret store is only opened when the provider has any.
+func (a *App) customProviderSecrets(name string, config CustomProviderConfig) (customProviderSecretValues, error) {
+	var values customProviderSecretValues
+	if !config.HasAPIKey && len(config.Headers) == 0 {
+		return values, nil
+	}
+	store, err := a.secretStore()
+	if err != nil {
+		return values, err
+	}
+	data, err := store.Get(customProviderSecret(name))
+	switch {
+	case errors.Is(err, secrets.ErrNotFound):
+		return values, nil
+	case errors.Is(err, secrets.ErrLocked):
+		return values, fmt.Errorf("secret store is locked; unlock it to use %s", name)
+	case err != nil:
+		return values, fmt.Errorf("failed to read the secrets of provider %s: %w", name, err)
+	}
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		return values, fmt.Errorf("invalid secrets for provider %s: %v", name, err)
+	}
+	return values, nil
+}
+
+// storeCustomProviderSecrets saves the API key and header values of a custom provider.
+func (a *App) storeCustomProviderSecrets(name string, values customProviderSecretValues) error {
+	store, err := a.secretStore()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if err := store.Set(customProviderSecret(name), string(data)); err != nil {
+		return fmt.Errorf("failed to save the secrets of provider %s: %w", name, err)
+	}
+	return nil
+}
+
+// deleteCustomProviderSecrets removes the saved values of a custom provider, if there are any.
+func (a *App) deleteCustomProviderSecrets(name string) {
+	store, err := a.secretStore()
+	if err != nil {
+		return
+	}
+	if err := store.Delete(customProviderSecret(name)); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "Warning: could not remove the secrets of provider %s: %v\n", name, err)
+	}
+}
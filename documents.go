@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"myproject/connectors"
+	"myproject/ingest"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// defaultContextTokens is the context window assumed for a model whose
+// context length is unknown.
+const defaultContextTokens = 8192
+
+// IngestOptions controls how an attached document is chunked and how much of
+// it is kept.
+type IngestOptions struct {
+	// Provider and Model select the model whose context window bounds the
+	// document.
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// MaxTokens overrides the token budget, for example with what is left
+	// after the conversation so far.
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// ChunkTokens is the size of each chunk, ingest.DefaultChunkTokens if unset.
+	ChunkTokens int `json:"chunk_tokens,omitempty"`
+}
+
+// IngestResult is the chunked text of a document cut to the token budget.
+type IngestResult struct {
+	Name    string         `json:"name"`
+	Format  string         `json:"format"`
+	Chunks  []ingest.Chunk `json:"chunks"`
+	Skipped []string       `json:"skipped,omitempty"`
+	// TotalTokens is the estimated size of the whole document and
+	// IncludedTokens the size of Chunks.
+	TotalTokens    int `json:"total_tokens"`
+	IncludedTokens int `json:"included_tokens"`
+	TokenBudget    int `json:"token_budget"`
+	// Truncated is set when chunks were left out to stay within the budget.
+	Truncated     bool `json:"truncated"`
+	OmittedChunks int  `json:"omitted_chunks,omitempty"`
+}
+
+// IngestFile extracts the text of a PDF, DOCX, Markdown, HTML, text or source
+// file, or a zip or tar archive of source code, asking for a file when path
+// is empty. The text is split into chunks and cut to fit the model's context
+// window, and the result reports what was left out. A cancelled dialog
+// returns an empty result.
+func (a *App) IngestFile(path string, options IngestOptions) (IngestResult, error) {
+	if path == "" {
+		if a.ctx == nil {
+			return IngestResult{}, fmt.Errorf("a file path is required")
+		}
+		var err error
+		path, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+			Title: "Attach document",
+			Filters: []runtime.FileFilter{
+				{DisplayName: "Documents (*.pdf, *.docx, *.md, *.html, *.txt)", Pattern: "*.pdf;*.docx;*.md;*.markdown;*.html;*.htm;*.txt"},
+				{DisplayName: "Archives (*.zip, *.tar, *.tar.gz, *.tgz)", Pattern: "*.zip;*.tar;*.tar.gz;*.tgz"},
+				{DisplayName: "All files", Pattern: "*"},
+			},
+		})
+		if err != nil || path == "" {
+			return IngestResult{}, err
+		}
+	}
+
+	doc, err := ingest.ExtractFile(path)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	budget := options.MaxTokens
+	if budget <= 0 {
+		budget = a.documentTokenBudget(options.Provider, options.Model)
+	}
+	return fitChunks(doc, ingest.Split(doc, options.ChunkTokens), budget), nil
+}
+
+// documentTokenBudget is how many tokens of a document fit in a model's
+// context window, leaving room for the response. The window is the saved
+// num_ctx for providers that take one, otherwise the model's context length.
+func (a *App) documentTokenBudget(provider string, model string) int {
+	config, _ := a.GetModelConfig(provider, model)
+	info, _ := a.registry.Info(provider)
+
+	window := 0
+	if supportsParam(info.Parameters, connectors.ParamNumCtx) {
+		window = config.NumCtx
+	}
+	if window <= 0 && provider != "" {
+		if m, err := a.GetModelInfo(provider, model); err == nil {
+			window = m.ContextLength
+		}
+	}
+	if window <= 0 {
+		window = defaultContextTokens
+	}
+
+	reserve := config.MaxOutputTokens
+	if reserve <= 0 || reserve >= window {
+		reserve = window / 4
+	}
+	return window - reserve
+}
+
+// fitChunks keeps a document's chunks in order until the next one would
+// exceed the budget.
+func fitChunks(doc ingest.Document, chunks []ingest.Chunk, budget int) IngestResult {
+	result := IngestResult{
+		Name:        doc.Name,
+		Format:      doc.Format,
+		Chunks:      []ingest.Chunk{},
+		Skipped:     doc.Skipped,
+		TokenBudget: budget,
+	}
+	for _, chunk := range chunks {
+		result.TotalTokens += chunk.Tokens
+		if result.Truncated || result.IncludedTokens+chunk.Tokens > budget {
+			result.Truncated = true
+			result.OmittedChunks++
+			continue
+		}
+		result.IncludedTokens += chunk.Tokens
+		result.Chunks = append(result.Chunks, chunk)
+	}
+	return result
+}
+
+// documentPrompt formats an ingested document for inclusion in a prompt,
+// labelling each part with where it came from.
+func documentPrompt(result IngestResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<document name=%q>\n", result.Name)
+	source := ""
+	for _, chunk := range result.Chunks {
+		if chunk.Source != source {
+			source = chunk.Source
+			fmt.Fprintf(&b, "[%s]\n", source)
+		}
+		b.WriteString(chunk.Text)
+		b.WriteString("\n")
+	}
+	if result.Truncated {
+		fmt.Fprintf(&b, "[%d more parts omitted to fit the context window]\n", result.OmittedChunks)
+	}
+	b.WriteString("</document>")
+	return b.String()
+}
+
+// ingestFiles ingests documents for a single prompt, sharing budget tokens
+// between them in order.
+func (a *App) ingestFiles(paths []string, provider string, model string, budget int) ([]IngestResult, error) {
+	var results []IngestResult
+	for _, path := range paths {
+		// MaxTokens must stay positive, as zero selects the model's budget
+		result, err := a.IngestFile(path, IngestOptions{Provider: provider, Model: model, MaxTokens: max(budget, 1)})
+		if err != nil {
+			return nil, err
+		}
+		budget -= result.IncludedTokens
+		results = append(results, result)
+	}
+	return results, nil
+}
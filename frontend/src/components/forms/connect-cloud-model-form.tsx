@@ -53,22 +53,6 @@ export const ConnectCloudModelsForm = () => {
     loadKey();
   }, [selectedProvider]);
 
-  // Debounced save of API key
-  useEffect(() => {
-    if (!selectedProvider) return;
-
-    const handler = setTimeout(() => {
-      if (apiKey && window.go?.main?.App) {
-        // @ts-ignore
-        window.go.main.App.SaveAPIKey(selectedProvider, apiKey);
-      }
-    }, 500); // 500ms debounce
-
-    return () => {
-      clearTimeout(handler);
-    };
-  }, [apiKey, selectedProvider]);
-
   const handleProviderChange = (providerId: string) => {
     setSelectedProvider(providerId);
     setStatus(null); // Clear status on provider change
@@ -79,6 +63,9 @@ export const ConnectCloudModelsForm = () => {
     setIsTesting(true);
     setStatus(null);
     try {
+      // Validates the key against the provider before saving it
+      // @ts-ignore
+      await window.go.main.App.ConnectCloudModel(selectedProvider, apiKey);
       // @ts-ignore
       const models = await window.go.main.App.ListCloudModels(
         selectedProvider,
@@ -190,7 +177,7 @@ export const ConnectCloudModelsForm = () => {
                 Testing Connection...
               </span>
             ) : (
-              <span>Test Connection</span>
+              <span>Save and Test Connection</span>
             )}
           </button>
 
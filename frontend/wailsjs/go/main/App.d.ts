@@ -1,20 +1,142 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
+import {conversations} from '../models';
 import {connectors} from '../models';
+import {rag} from '../models';
+
+export function AddCustomProvider(arg1:string,arg2:main.CustomProviderConfig,arg3:string):Promise<main.CustomProvider>;
+
+export function AddKnowledgeFolder(arg1:string):Promise<string>;
+
+export function AddMCPServer(arg1:string,arg2:main.MCPServerConfig):Promise<main.MCPServerStatus>;
+
+export function AppendMessage(arg1:string,arg2:conversations.Message):Promise<conversations.Message>;
+
+export function AttachImage(arg1:string):Promise<connectors.ImagePart>;
+
+export function CancelGeneration(arg1:string):Promise<void>;
+
+export function ChatWithHistory(arg1:main.ChatRequest):Promise<string>;
 
 export function ChatWithModel(arg1:string,arg2:string,arg3:string):Promise<string>;
 
+export function CloseWorkspace():Promise<void>;
+
+export function ConfirmWorkspaceWrite(arg1:string,arg2:boolean):Promise<void>;
+
 export function ConnectCloudModel(arg1:string,arg2:string):Promise<void>;
 
+export function CopyOllamaModel(arg1:string,arg2:string):Promise<void>;
+
+export function CreateConversation(arg1:string):Promise<conversations.Conversation>;
+
+export function CreatePreset(arg1:string,arg2:string):Promise<main.Preset>;
+
+export function DeleteConversation(arg1:string):Promise<void>;
+
+export function DeleteOllamaModel(arg1:string):Promise<void>;
+
+export function DeletePreset(arg1:string):Promise<void>;
+
+export function ExportConfig(arg1:string,arg2:main.ExportOptions):Promise<string>;
+
 export function GetAPIKey(arg1:string):Promise<string>;
 
+export function GetAPIServerStatus():Promise<main.APIServerStatus>;
+
+export function GetCustomProviders():Promise<Array<main.CustomProvider>>;
+
+export function GetKnowledgeConfig():Promise<main.KnowledgeConfig>;
+
+export function GetKnowledgeFolders():Promise<Array<main.KnowledgeFolder>>;
+
+export function GetMCPServers():Promise<Array<main.MCPServerStatus>>;
+
 export function GetModelConfig(arg1:string,arg2:string):Promise<main.ModelConfig>;
 
+export function GetModelInfo(arg1:string,arg2:string):Promise<connectors.Model>;
+
+export function GetModelPresets():Promise<Record<string, string>>;
+
+export function GetProviderEndpoints():Promise<Record<string, string>>;
+
+export function GetProviderStatus():Promise<Array<main.ProviderStatus>>;
+
+export function GetProviders():Promise<Array<connectors.ProviderInfo>>;
+
+export function GetSecretStoreStatus():Promise<main.SecretStoreStatus>;
+
+export function GetTools():Promise<Array<connectors.Tool>>;
+
+export function GetWorkspace():Promise<string>;
+
+export function ImportConfig(arg1:string,arg2:main.ImportOptions):Promise<main.ImportReport>;
+
+export function IndexKnowledgeFolder(arg1:string):Promise<string>;
+
+export function IngestFile(arg1:string,arg2:main.IngestOptions):Promise<main.IngestResult>;
+
+export function InspectMCPServer(arg1:string):Promise<main.MCPServerDetails>;
+
 export function ListCloudModels(arg1:string,arg2:string):Promise<Array<connectors.Model>>;
 
-export function SaveAPIKey(arg1:string,arg2:string):Promise<void>;
+export function ListConversations():Promise<Array<conversations.Conversation>>;
+
+export function ListPresets():Promise<Array<main.Preset>>;
+
+export function LoadConversation(arg1:string):Promise<conversations.Conversation>;
+
+export function PrepareImage(arg1:string,arg2:string):Promise<connectors.ImagePart>;
+
+export function PullOllamaModel(arg1:string):Promise<string>;
+
+export function RegenerateAPIServerToken():Promise<main.APIServerStatus>;
+
+export function RemoveCustomProvider(arg1:string):Promise<void>;
+
+export function RemoveKnowledgeFolder(arg1:string):Promise<void>;
+
+export function RemoveMCPServer(arg1:string):Promise<void>;
+
+export function RenameConversation(arg1:string,arg2:string):Promise<conversations.Conversation>;
+
+export function ResetProviderEndpoint(arg1:string):Promise<void>;
 
 export function SaveModelConfig(arg1:string,arg2:string,arg3:main.ModelConfig):Promise<string>;
 
 export function ScanLocalModels(arg1:string):Promise<connectors.ScanResult>;
+
+export function SearchConversations(arg1:string):Promise<Array<conversations.SearchHit>>;
+
+export function SearchKnowledge(arg1:string,arg2:number):Promise<Array<rag.Hit>>;
+
+export function SetConversationPreset(arg1:string,arg2:string):Promise<conversations.Conversation>;
+
+export function SetKnowledgeSettings(arg1:string,arg2:string,arg3:number):Promise<void>;
+
+export function SetMCPServerEnabled(arg1:string,arg2:boolean):Promise<main.MCPServerStatus>;
+
+export function SetModelPreset(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function SetProviderEndpoint(arg1:string,arg2:string):Promise<void>;
+
+export function SetSecretStore(arg1:string,arg2:string):Promise<void>;
+
+export function SetWorkspace(arg1:string):Promise<string>;
+
+export function ShowOllamaModel(arg1:string):Promise<connectors.OllamaModelInfo>;
+
+export function StartAPIServer(arg1:number):Promise<main.APIServerStatus>;
+
+export function StopAPIServer():Promise<void>;
+
+export function StreamChat(arg1:main.ChatRequest):Promise<string>;
+
+export function TestProviderEndpoint(arg1:string,arg2:string):Promise<void>;
+
+export function UnlockSecretStore(arg1:string):Promise<void>;
+
+export function UpdatePreset(arg1:string,arg2:string,arg3:string):Promise<main.Preset>;
+
+export function ValidateAPIKey(arg1:string):Promise<main.ProviderStatus>;
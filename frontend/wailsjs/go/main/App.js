@@ -2,28 +2,200 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddCustomProvider(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddCustomProvider'](arg1, arg2, arg3);
+}
+
+export function AddKnowledgeFolder(arg1) {
+  return window['go']['main']['App']['AddKnowledgeFolder'](arg1);
+}
+
+export function AddMCPServer(arg1, arg2) {
+  return window['go']['main']['App']['AddMCPServer'](arg1, arg2);
+}
+
+export function AppendMessage(arg1, arg2) {
+  return window['go']['main']['App']['AppendMessage'](arg1, arg2);
+}
+
+export function AttachImage(arg1) {
+  return window['go']['main']['App']['AttachImage'](arg1);
+}
+
+export function CancelGeneration(arg1) {
+  return window['go']['main']['App']['CancelGeneration'](arg1);
+}
+
+export function ChatWithHistory(arg1) {
+  return window['go']['main']['App']['ChatWithHistory'](arg1);
+}
+
 export function ChatWithModel(arg1, arg2, arg3) {
   return window['go']['main']['App']['ChatWithModel'](arg1, arg2, arg3);
 }
 
+export function CloseWorkspace() {
+  return window['go']['main']['App']['CloseWorkspace']();
+}
+
+export function ConfirmWorkspaceWrite(arg1, arg2) {
+  return window['go']['main']['App']['ConfirmWorkspaceWrite'](arg1, arg2);
+}
+
 export function ConnectCloudModel(arg1, arg2) {
   return window['go']['main']['App']['ConnectCloudModel'](arg1, arg2);
 }
 
+export function CopyOllamaModel(arg1, arg2) {
+  return window['go']['main']['App']['CopyOllamaModel'](arg1, arg2);
+}
+
+export function CreateConversation(arg1) {
+  return window['go']['main']['App']['CreateConversation'](arg1);
+}
+
+export function CreatePreset(arg1, arg2) {
+  return window['go']['main']['App']['CreatePreset'](arg1, arg2);
+}
+
+export function DeleteConversation(arg1) {
+  return window['go']['main']['App']['DeleteConversation'](arg1);
+}
+
+export function DeleteOllamaModel(arg1) {
+  return window['go']['main']['App']['DeleteOllamaModel'](arg1);
+}
+
+export function DeletePreset(arg1) {
+  return window['go']['main']['App']['DeletePreset'](arg1);
+}
+
+export function ExportConfig(arg1, arg2) {
+  return window['go']['main']['App']['ExportConfig'](arg1, arg2);
+}
+
 export function GetAPIKey(arg1) {
   return window['go']['main']['App']['GetAPIKey'](arg1);
 }
 
+export function GetAPIServerStatus() {
+  return window['go']['main']['App']['GetAPIServerStatus']();
+}
+
+export function GetCustomProviders() {
+  return window['go']['main']['App']['GetCustomProviders']();
+}
+
+export function GetKnowledgeConfig() {
+  return window['go']['main']['App']['GetKnowledgeConfig']();
+}
+
+export function GetKnowledgeFolders() {
+  return window['go']['main']['App']['GetKnowledgeFolders']();
+}
+
+export function GetMCPServers() {
+  return window['go']['main']['App']['GetMCPServers']();
+}
+
 export function GetModelConfig(arg1, arg2) {
   return window['go']['main']['App']['GetModelConfig'](arg1, arg2);
 }
 
+export function GetModelInfo(arg1, arg2) {
+  return window['go']['main']['App']['GetModelInfo'](arg1, arg2);
+}
+
+export function GetModelPresets() {
+  return window['go']['main']['App']['GetModelPresets']();
+}
+
+export function GetProviderEndpoints() {
+  return window['go']['main']['App']['GetProviderEndpoints']();
+}
+
+export function GetProviderStatus() {
+  return window['go']['main']['App']['GetProviderStatus']();
+}
+
+export function GetProviders() {
+  return window['go']['main']['App']['GetProviders']();
+}
+
+export function GetSecretStoreStatus() {
+  return window['go']['main']['App']['GetSecretStoreStatus']();
+}
+
+export function GetTools() {
+  return window['go']['main']['App']['GetTools']();
+}
+
+export function GetWorkspace() {
+  return window['go']['main']['App']['GetWorkspace']();
+}
+
+export function ImportConfig(arg1, arg2) {
+  return window['go']['main']['App']['ImportConfig'](arg1, arg2);
+}
+
+export function IndexKnowledgeFolder(arg1) {
+  return window['go']['main']['App']['IndexKnowledgeFolder'](arg1);
+}
+
+export function IngestFile(arg1, arg2) {
+  return window['go']['main']['App']['IngestFile'](arg1, arg2);
+}
+
+export function InspectMCPServer(arg1) {
+  return window['go']['main']['App']['InspectMCPServer'](arg1);
+}
+
 export function ListCloudModels(arg1, arg2) {
   return window['go']['main']['App']['ListCloudModels'](arg1, arg2);
 }
 
-export function SaveAPIKey(arg1, arg2) {
-  return window['go']['main']['App']['SaveAPIKey'](arg1, arg2);
+export function ListConversations() {
+  return window['go']['main']['App']['ListConversations']();
+}
+
+export function ListPresets() {
+  return window['go']['main']['App']['ListPresets']();
+}
+
+export function LoadConversation(arg1) {
+  return window['go']['main']['App']['LoadConversation'](arg1);
+}
+
+export function PrepareImage(arg1, arg2) {
+  return window['go']['main']['App']['PrepareImage'](arg1, arg2);
+}
+
+export function PullOllamaModel(arg1) {
+  return window['go']['main']['App']['PullOllamaModel'](arg1);
+}
+
+export function RegenerateAPIServerToken() {
+  return window['go']['main']['App']['RegenerateAPIServerToken']();
+}
+
+export function RemoveCustomProvider(arg1) {
+  return window['go']['main']['App']['RemoveCustomProvider'](arg1);
+}
+
+export function RemoveKnowledgeFolder(arg1) {
+  return window['go']['main']['App']['RemoveKnowledgeFolder'](arg1);
+}
+
+export function RemoveMCPServer(arg1) {
+  return window['go']['main']['App']['RemoveMCPServer'](arg1);
+}
+
+export function RenameConversation(arg1, arg2) {
+  return window['go']['main']['App']['RenameConversation'](arg1, arg2);
+}
+
+export function ResetProviderEndpoint(arg1) {
+  return window['go']['main']['App']['ResetProviderEndpoint'](arg1);
 }
 
 export function SaveModelConfig(arg1, arg2, arg3) {
@@ -33,3 +205,71 @@ export function SaveModelConfig(arg1, arg2, arg3) {
 export function ScanLocalModels(arg1) {
   return window['go']['main']['App']['ScanLocalModels'](arg1);
 }
+
+export function SearchConversations(arg1) {
+  return window['go']['main']['App']['SearchConversations'](arg1);
+}
+
+export function SearchKnowledge(arg1, arg2) {
+  return window['go']['main']['App']['SearchKnowledge'](arg1, arg2);
+}
+
+export function SetConversationPreset(arg1, arg2) {
+  return window['go']['main']['App']['SetConversationPreset'](arg1, arg2);
+}
+
+export function SetKnowledgeSettings(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetKnowledgeSettings'](arg1, arg2, arg3);
+}
+
+export function SetMCPServerEnabled(arg1, arg2) {
+  return window['go']['main']['App']['SetMCPServerEnabled'](arg1, arg2);
+}
+
+export function SetModelPreset(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetModelPreset'](arg1, arg2, arg3);
+}
+
+export function SetProviderEndpoint(arg1, arg2) {
+  return window['go']['main']['App']['SetProviderEndpoint'](arg1, arg2);
+}
+
+export function SetSecretStore(arg1, arg2) {
+  return window['go']['main']['App']['SetSecretStore'](arg1, arg2);
+}
+
+export function SetWorkspace(arg1) {
+  return window['go']['main']['App']['SetWorkspace'](arg1);
+}
+
+export function ShowOllamaModel(arg1) {
+  return window['go']['main']['App']['ShowOllamaModel'](arg1);
+}
+
+export function StartAPIServer(arg1) {
+  return window['go']['main']['App']['StartAPIServer'](arg1);
+}
+
+export function StopAPIServer() {
+  return window['go']['main']['App']['StopAPIServer']();
+}
+
+export function StreamChat(arg1) {
+  return window['go']['main']['App']['StreamChat'](arg1);
+}
+
+export function TestProviderEndpoint(arg1, arg2) {
+  return window['go']['main']['App']['TestProviderEndpoint'](arg1, arg2);
+}
+
+export function UnlockSecretStore(arg1) {
+  return window['go']['main']['App']['UnlockSecretStore'](arg1);
+}
+
+export function UpdatePreset(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UpdatePreset'](arg1, arg2, arg3);
+}
+
+export function ValidateAPIKey(arg1) {
+  return window['go']['main']['App']['ValidateAPIKey'](arg1);
+}
@@ -1,9 +1,120 @@
 export namespace connectors {
 	
+	export class Capabilities {
+	    local: boolean;
+	    requires_api_key: boolean;
+	    chat: boolean;
+	    streaming: boolean;
+	    images: boolean;
+	    embeddings: boolean;
+	    tools: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Capabilities(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.local = source["local"];
+	        this.requires_api_key = source["requires_api_key"];
+	        this.chat = source["chat"];
+	        this.streaming = source["streaming"];
+	        this.images = source["images"];
+	        this.embeddings = source["embeddings"];
+	        this.tools = source["tools"];
+	    }
+	}
+	export class ToolCall {
+	    id: string;
+	    name: string;
+	    arguments: any;
+	    signature?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ToolCall(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.arguments = source["arguments"];
+	        this.signature = source["signature"];
+	    }
+	}
+	export class ImagePart {
+	    mime_type: string;
+	    data: string;
+	    name?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImagePart(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mime_type = source["mime_type"];
+	        this.data = source["data"];
+	        this.name = source["name"];
+	    }
+	}
+	export class ChatMessage {
+	    role: string;
+	    content: string;
+	    images?: ImagePart[];
+	    tool_calls?: ToolCall[];
+	    tool_call_id?: string;
+	    tool_name?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChatMessage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.role = source["role"];
+	        this.content = source["content"];
+	        this.images = this.convertValues(source["images"], ImagePart);
+	        this.tool_calls = this.convertValues(source["tool_calls"], ToolCall);
+	        this.tool_call_id = source["tool_call_id"];
+	        this.tool_name = source["tool_name"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
 	export class Model {
 	    name: string;
 	    size?: string;
 	    modified?: string;
+	    display_name?: string;
+	    size_bytes?: number;
+	    digest?: string;
+	    family?: string;
+	    parameter_size?: string;
+	    quantization?: string;
+	    format?: string;
+	    context_length?: number;
+	    max_output_tokens?: number;
+	    modalities?: string[];
+	    supports_tools: boolean;
+	    supports_vision: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new Model(source);
@@ -14,7 +125,145 @@ export namespace connectors {
 	        this.name = source["name"];
 	        this.size = source["size"];
 	        this.modified = source["modified"];
+	        this.display_name = source["display_name"];
+	        this.size_bytes = source["size_bytes"];
+	        this.digest = source["digest"];
+	        this.family = source["family"];
+	        this.parameter_size = source["parameter_size"];
+	        this.quantization = source["quantization"];
+	        this.format = source["format"];
+	        this.context_length = source["context_length"];
+	        this.max_output_tokens = source["max_output_tokens"];
+	        this.modalities = source["modalities"];
+	        this.supports_tools = source["supports_tools"];
+	        this.supports_vision = source["supports_vision"];
+	    }
+	}
+	export class OllamaModelDetails {
+	    parent_model?: string;
+	    format?: string;
+	    family?: string;
+	    families?: string[];
+	    parameter_size?: string;
+	    quantization_level?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OllamaModelDetails(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.parent_model = source["parent_model"];
+	        this.format = source["format"];
+	        this.family = source["family"];
+	        this.families = source["families"];
+	        this.parameter_size = source["parameter_size"];
+	        this.quantization_level = source["quantization_level"];
+	    }
+	}
+	export class OllamaModelInfo {
+	    modelfile: string;
+	    parameters: string;
+	    template: string;
+	    system?: string;
+	    license: string;
+	    details: OllamaModelDetails;
+	    model_info?: Record<string, any>;
+	    capabilities?: string[];
+	    modified_at?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OllamaModelInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.modelfile = source["modelfile"];
+	        this.parameters = source["parameters"];
+	        this.template = source["template"];
+	        this.system = source["system"];
+	        this.license = source["license"];
+	        this.details = this.convertValues(source["details"], OllamaModelDetails);
+	        this.model_info = source["model_info"];
+	        this.capabilities = source["capabilities"];
+	        this.modified_at = source["modified_at"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ParamSpec {
+	    name: string;
+	    type: string;
+	    min?: number;
+	    max?: number;
+	    max_items?: number;
+	    default?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ParamSpec(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	        this.max_items = source["max_items"];
+	        this.default = source["default"];
+	    }
+	}
+	export class ProviderInfo {
+	    name: string;
+	    default_endpoint?: string;
+	    capabilities: Capabilities;
+	    parameters: ParamSpec[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.default_endpoint = source["default_endpoint"];
+	        this.capabilities = this.convertValues(source["capabilities"], Capabilities);
+	        this.parameters = this.convertValues(source["parameters"], ParamSpec);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class ScanResult {
 	    models: Model[];
@@ -50,31 +299,929 @@ export namespace connectors {
 		    return a;
 		}
 	}
+	export class Tool {
+	    name: string;
+	    description: string;
+	    parameters: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Tool(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.parameters = source["parameters"];
+	    }
+	}
+
+}
+
+export namespace conversations {
+	
+	export class Message {
+	    id: string;
+	    role: string;
+	    content: string;
+	    provider?: string;
+	    model?: string;
+	    // Go type: time
+	    created_at: any;
+	    images?: connectors.ImagePart[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Message(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.role = source["role"];
+	        this.content = source["content"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.images = this.convertValues(source["images"], connectors.ImagePart);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Conversation {
+	    id: string;
+	    title: string;
+	    provider?: string;
+	    model?: string;
+	    preset_id?: string;
+	    message_count: number;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	    messages?: Message[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Conversation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.preset_id = source["preset_id"];
+	        this.message_count = source["message_count"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.messages = this.convertValues(source["messages"], Message);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Highlight {
+	    start: number;
+	    end: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Highlight(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	    }
+	}
+	
+	export class SearchHit {
+	    conversation_id: string;
+	    conversation_title: string;
+	    message_id?: string;
+	    role?: string;
+	    field: string;
+	    score: number;
+	    snippet: string;
+	    highlights: Highlight[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchHit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.conversation_id = source["conversation_id"];
+	        this.conversation_title = source["conversation_title"];
+	        this.message_id = source["message_id"];
+	        this.role = source["role"];
+	        this.field = source["field"];
+	        this.score = source["score"];
+	        this.snippet = source["snippet"];
+	        this.highlights = this.convertValues(source["highlights"], Highlight);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace ingest {
+	
+	export class Chunk {
+	    source: string;
+	    index: number;
+	    text: string;
+	    tokens: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Chunk(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.index = source["index"];
+	        this.text = source["text"];
+	        this.tokens = source["tokens"];
+	    }
+	}
 
 }
 
 export namespace main {
 	
-	export class ModelConfig {
-	    temperature: number;
-	    top_p: number;
-	    top_k: number;
-	    repeat_penalty: number;
-	    num_ctx: number;
-	    stop: string[];
+	export class APIServerStatus {
+	    enabled: boolean;
+	    running: boolean;
+	    port: number;
+	    base_url?: string;
+	    token?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new ModelConfig(source);
+	        return new APIServerStatus(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.temperature = source["temperature"];
-	        this.top_p = source["top_p"];
-	        this.top_k = source["top_k"];
-	        this.repeat_penalty = source["repeat_penalty"];
-	        this.num_ctx = source["num_ctx"];
-	        this.stop = source["stop"];
+	        this.enabled = source["enabled"];
+	        this.running = source["running"];
+	        this.port = source["port"];
+	        this.base_url = source["base_url"];
+	        this.token = source["token"];
+	    }
+	}
+	export class ChatRequest {
+	    provider: string;
+	    model: string;
+	    messages: connectors.ChatMessage[];
+	    generation_id?: string;
+	    conversation_id?: string;
+	    preset_id?: string;
+	    use_knowledge?: boolean;
+	    use_tools?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChatRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.messages = this.convertValues(source["messages"], connectors.ChatMessage);
+	        this.generation_id = source["generation_id"];
+	        this.conversation_id = source["conversation_id"];
+	        this.preset_id = source["preset_id"];
+	        this.use_knowledge = source["use_knowledge"];
+	        this.use_tools = source["use_tools"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CustomProviderConfig {
+	    base_url: string;
+	    headers?: Record<string, string>;
+	    model_source: string;
+	    models?: string[];
+	    has_api_key: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CustomProviderConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.base_url = source["base_url"];
+	        this.headers = source["headers"];
+	        this.model_source = source["model_source"];
+	        this.models = source["models"];
+	        this.has_api_key = source["has_api_key"];
+	    }
+	}
+	export class CustomProvider {
+	    name: string;
+	    config: CustomProviderConfig;
+	
+	    static createFrom(source: any = {}) {
+	        return new CustomProvider(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.config = this.convertValues(source["config"], CustomProviderConfig);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class ExportOptions {
+	    sections: string[];
+	    passphrase: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sections = source["sections"];
+	        this.passphrase = source["passphrase"];
+	    }
+	}
+	export class ImportOptions {
+	    modes: Record<string, string>;
+	    passphrase: string;
+	    dry_run: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.modes = source["modes"];
+	        this.passphrase = source["passphrase"];
+	        this.dry_run = source["dry_run"];
+	    }
+	}
+	export class ImportSectionReport {
+	    section: string;
+	    mode: string;
+	    added: string[];
+	    conflicts: string[];
+	    unchanged: number;
+	    skipped: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportSectionReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.section = source["section"];
+	        this.mode = source["mode"];
+	        this.added = source["added"];
+	        this.conflicts = source["conflicts"];
+	        this.unchanged = source["unchanged"];
+	        this.skipped = source["skipped"];
+	    }
+	}
+	export class ImportReport {
+	    dry_run: boolean;
+	    sections: ImportSectionReport[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dry_run = source["dry_run"];
+	        this.sections = this.convertValues(source["sections"], ImportSectionReport);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class IngestOptions {
+	    provider: string;
+	    model: string;
+	    max_tokens?: number;
+	    chunk_tokens?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IngestOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.max_tokens = source["max_tokens"];
+	        this.chunk_tokens = source["chunk_tokens"];
+	    }
+	}
+	export class IngestResult {
+	    name: string;
+	    format: string;
+	    chunks: ingest.Chunk[];
+	    skipped?: string[];
+	    total_tokens: number;
+	    included_tokens: number;
+	    token_budget: number;
+	    truncated: boolean;
+	    omitted_chunks?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IngestResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.format = source["format"];
+	        this.chunks = this.convertValues(source["chunks"], ingest.Chunk);
+	        this.skipped = source["skipped"];
+	        this.total_tokens = source["total_tokens"];
+	        this.included_tokens = source["included_tokens"];
+	        this.token_budget = source["token_budget"];
+	        this.truncated = source["truncated"];
+	        this.omitted_chunks = source["omitted_chunks"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class KnowledgeConfig {
+	    folders?: string[];
+	    provider?: string;
+	    model?: string;
+	    top_k?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new KnowledgeConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folders = source["folders"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.top_k = source["top_k"];
+	    }
+	}
+	export class KnowledgeFolder {
+	    root: string;
+	    provider?: string;
+	    model?: string;
+	    files: number;
+	    chunks: number;
+	    // Go type: time
+	    updated_at: any;
+	    indexing: boolean;
+	    stale: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new KnowledgeFolder(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.root = source["root"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.files = source["files"];
+	        this.chunks = source["chunks"];
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.indexing = source["indexing"];
+	        this.stale = source["stale"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MCPServerConfig {
+	    transport: string;
+	    command?: string;
+	    args?: string[];
+	    dir?: string;
+	    env?: Record<string, string>;
+	    url?: string;
+	    headers?: Record<string, string>;
+	    enabled: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new MCPServerConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.transport = source["transport"];
+	        this.command = source["command"];
+	        this.args = source["args"];
+	        this.dir = source["dir"];
+	        this.env = source["env"];
+	        this.url = source["url"];
+	        this.headers = source["headers"];
+	        this.enabled = source["enabled"];
+	    }
+	}
+	export class MCPServerDetails {
+	    name: string;
+	    config: MCPServerConfig;
+	    state: string;
+	    error?: string;
+	    server?: mcp.ServerInfo;
+	    tool_names?: string[];
+	    tools: mcp.Tool[];
+	    resources: mcp.Resource[];
+	    prompts: mcp.Prompt[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MCPServerDetails(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.config = this.convertValues(source["config"], MCPServerConfig);
+	        this.state = source["state"];
+	        this.error = source["error"];
+	        this.server = this.convertValues(source["server"], mcp.ServerInfo);
+	        this.tool_names = source["tool_names"];
+	        this.tools = this.convertValues(source["tools"], mcp.Tool);
+	        this.resources = this.convertValues(source["resources"], mcp.Resource);
+	        this.prompts = this.convertValues(source["prompts"], mcp.Prompt);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MCPServerStatus {
+	    name: string;
+	    config: MCPServerConfig;
+	    state: string;
+	    error?: string;
+	    server?: mcp.ServerInfo;
+	    tool_names?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MCPServerStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.config = this.convertValues(source["config"], MCPServerConfig);
+	        this.state = source["state"];
+	        this.error = source["error"];
+	        this.server = this.convertValues(source["server"], mcp.ServerInfo);
+	        this.tool_names = source["tool_names"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ModelConfig {
+	    temperature: number;
+	    top_p: number;
+	    top_k: number;
+	    repeat_penalty: number;
+	    num_ctx: number;
+	    stop: string[];
+	    max_output_tokens?: number;
+	    seed?: number;
+	    presence_penalty?: number;
+	    frequency_penalty?: number;
+	    min_p?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.temperature = source["temperature"];
+	        this.top_p = source["top_p"];
+	        this.top_k = source["top_k"];
+	        this.repeat_penalty = source["repeat_penalty"];
+	        this.num_ctx = source["num_ctx"];
+	        this.stop = source["stop"];
+	        this.max_output_tokens = source["max_output_tokens"];
+	        this.seed = source["seed"];
+	        this.presence_penalty = source["presence_penalty"];
+	        this.frequency_penalty = source["frequency_penalty"];
+	        this.min_p = source["min_p"];
+	    }
+	}
+	export class Preset {
+	    id: string;
+	    name: string;
+	    system_prompt: string;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Preset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.system_prompt = source["system_prompt"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProviderStatus {
+	    provider: string;
+	    has_key: boolean;
+	    state: string;
+	    // Go type: time
+	    validated_at?: any;
+	    last_error?: string;
+	    // Go type: time
+	    last_error_at?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.has_key = source["has_key"];
+	        this.state = source["state"];
+	        this.validated_at = this.convertValues(source["validated_at"], null);
+	        this.last_error = source["last_error"];
+	        this.last_error_at = this.convertValues(source["last_error_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SecretStoreStatus {
+	    backend: string;
+	    locked: boolean;
+	    available: string[];
+	    pending: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SecretStoreStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.backend = source["backend"];
+	        this.locked = source["locked"];
+	        this.available = source["available"];
+	        this.pending = source["pending"];
+	    }
+	}
+
+}
+
+export namespace mcp {
+	
+	export class PromptArgument {
+	    name: string;
+	    description?: string;
+	    required?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new PromptArgument(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.required = source["required"];
+	    }
+	}
+	export class Prompt {
+	    name: string;
+	    title?: string;
+	    description?: string;
+	    arguments?: PromptArgument[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Prompt(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.title = source["title"];
+	        this.description = source["description"];
+	        this.arguments = this.convertValues(source["arguments"], PromptArgument);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Resource {
+	    uri: string;
+	    name: string;
+	    title?: string;
+	    description?: string;
+	    mimeType?: string;
+	    size?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Resource(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.uri = source["uri"];
+	        this.name = source["name"];
+	        this.title = source["title"];
+	        this.description = source["description"];
+	        this.mimeType = source["mimeType"];
+	        this.size = source["size"];
+	    }
+	}
+	export class ServerInfo {
+	    name: string;
+	    version: string;
+	    protocolVersion: string;
+	    instructions?: string;
+	    tools: boolean;
+	    resources: boolean;
+	    prompts: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServerInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.version = source["version"];
+	        this.protocolVersion = source["protocolVersion"];
+	        this.instructions = source["instructions"];
+	        this.tools = source["tools"];
+	        this.resources = source["resources"];
+	        this.prompts = source["prompts"];
+	    }
+	}
+	export class Tool {
+	    name: string;
+	    title?: string;
+	    description?: string;
+	    inputSchema?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Tool(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.title = source["title"];
+	        this.description = source["description"];
+	        this.inputSchema = source["inputSchema"];
+	    }
+	}
+
+}
+
+export namespace rag {
+	
+	export class Hit {
+	    citation: string;
+	    root: string;
+	    path: string;
+	    source?: string;
+	    start_line?: number;
+	    end_line?: number;
+	    text: string;
+	    score: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Hit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.citation = source["citation"];
+	        this.root = source["root"];
+	        this.path = source["path"];
+	        this.source = source["source"];
+	        this.start_line = source["start_line"];
+	        this.end_line = source["end_line"];
+	        this.text = source["text"];
+	        this.score = source["score"];
 	    }
 	}
 
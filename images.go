@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"myproject/connectors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// AttachImage reads an image file for a chat message, asking for a file when
+// path is empty. Large images are downscaled to what providers accept. A
+// cancelled dialog returns an empty part.
+func (a *App) AttachImage(path string) (connectors.ImagePart, error) {
+	if path == "" {
+		if a.ctx == nil {
+			return connectors.ImagePart{}, fmt.Errorf("an image path is required")
+		}
+		var err error
+		path, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+			Title:   "Attach image",
+			Filters: []runtime.FileFilter{{DisplayName: "Images (*.png, *.jpg, *.gif, *.webp)", Pattern: "*.png;*.jpg;*.jpeg;*.gif;*.webp"}},
+		})
+		if err != nil || path == "" {
+			return connectors.ImagePart{}, err
+		}
+	}
+	return loadImage(path)
+}
+
+// PrepareImage checks and downscales an image the frontend already holds,
+// such as a pasted screenshot. data is base64, optionally as a data: URL.
+func (a *App) PrepareImage(name string, data string) (connectors.ImagePart, error) {
+	var part connectors.ImagePart
+	var err error
+	if strings.HasPrefix(data, "data:") {
+		part, err = connectors.ImagePartFromDataURL(data)
+	} else {
+		part, err = connectors.PrepareImagePart(data)
+	}
+	if err != nil {
+		return connectors.ImagePart{}, err
+	}
+	part.Name = name
+	return part, nil
+}
+
+// loadImage reads an image file into a checked, downscaled image part.
+func loadImage(path string) (connectors.ImagePart, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return connectors.ImagePart{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	if info.Size() > connectors.MaxImageInputBytes {
+		return connectors.ImagePart{}, fmt.Errorf("%s is too large to attach", filepath.Base(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return connectors.ImagePart{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	part, err := connectors.NewImagePart(data)
+	if err != nil {
+		return connectors.ImagePart{}, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	part.Name = filepath.Base(path)
+	return part, nil
+}
+
+// hasImages reports whether any message carries an image.
+func hasImages(messages []connectors.ChatMessage) bool {
+	for _, msg := range messages {
+		if len(msg.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
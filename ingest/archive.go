@@ -0,0 +1,187 @@
+package ingest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Archive limits, so a small archive cannot expand into unbounded work.
+const (
+	maxArchiveEntries   = 10000
+	maxArchiveFileBytes = 2 << 20
+)
+
+// archiveSkipDirs are directories of dependencies, build output and tool
+// state that would crowd out the sources themselves.
+var archiveSkipDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	".idea":        true,
+	".vscode":      true,
+	".venv":        true,
+	"__MACOSX":     true,
+	"__pycache__":  true,
+	"node_modules": true,
+}
+
+// IgnoredDir reports whether a directory holds dependencies, build output or
+// tool state rather than sources, and is best left out of ingestion.
+func IgnoredDir(name string) bool {
+	return archiveSkipDirs[name]
+}
+
+// extractZip extracts every readable file in a zip archive as its own section.
+func extractZip(data []byte) ([]Section, []string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a valid zip archive: %v", err)
+	}
+	if len(archive.File) > maxArchiveEntries {
+		return nil, nil, fmt.Errorf("archive has more than %d entries", maxArchiveEntries)
+	}
+
+	var entries archiveEntries
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || entries.skipPath(file.Name) {
+			continue
+		}
+		if file.UncompressedSize64 > maxArchiveFileBytes {
+			entries.skip(file.Name, "too large")
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			entries.skip(file.Name, err.Error())
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(reader, maxArchiveFileBytes+1))
+		reader.Close()
+		if err != nil {
+			entries.skip(file.Name, err.Error())
+			continue
+		}
+		entries.add(file.Name, content)
+		if entries.full() {
+			break
+		}
+	}
+	return entries.sections, entries.skipped, nil
+}
+
+// extractTar extracts every readable file in a tar archive, optionally
+// gzip-compressed, as its own section.
+func extractTar(data []byte, compressed bool) ([]Section, []string, error) {
+	var source io.Reader = bytes.NewReader(data)
+	if compressed {
+		gz, err := gzip.NewReader(source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("not a valid gzip file: %v", err)
+		}
+		defer gz.Close()
+		source = gz
+	}
+
+	var entries archiveEntries
+	archive := tar.NewReader(source)
+	for count := 0; ; count++ {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("not a valid tar archive: %v", err)
+		}
+		if count >= maxArchiveEntries {
+			return nil, nil, fmt.Errorf("archive has more than %d entries", maxArchiveEntries)
+		}
+		if header.Typeflag != tar.TypeReg || entries.skipPath(header.Name) {
+			continue
+		}
+		if header.Size > maxArchiveFileBytes {
+			entries.skip(header.Name, "too large")
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(archive, maxArchiveFileBytes+1))
+		if err != nil {
+			return nil, nil, fmt.Errorf("not a valid tar archive: %v", err)
+		}
+		entries.add(header.Name, content)
+		if entries.full() {
+			break
+		}
+	}
+	return entries.sections, entries.skipped, nil
+}
+
+// archiveEntries collects the sections and skipped entries of an archive.
+type archiveEntries struct {
+	sections []Section
+	skipped  []string
+	size     int
+}
+
+func (e *archiveEntries) skip(name string, reason string) {
+	e.skipped = append(e.skipped, name+": "+reason)
+}
+
+// skipPath reports whether an entry lies in a directory that is never useful.
+func (e *archiveEntries) skipPath(name string) bool {
+	for _, dir := range strings.Split(path.Dir(path.Clean(name)), "/") {
+		if IgnoredDir(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// add extracts an entry with the format its name implies. Binary files and
+// nested archives are skipped.
+func (e *archiveEntries) add(name string, content []byte) {
+	if len(content) > maxArchiveFileBytes {
+		e.skip(name, "too large")
+		return
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, suffix) {
+			e.skip(name, "nested archive")
+			return
+		}
+	}
+
+	doc, err := Extract(path.Base(name), content)
+	if errors.Is(err, ErrUnsupported) {
+		e.skip(name, "binary file")
+		return
+	}
+	if err != nil {
+		// Report the cause without repeating the entry name
+		reason := "no text"
+		if cause := errors.Unwrap(err); cause != nil {
+			reason = cause.Error()
+		}
+		e.skip(name, reason)
+		return
+	}
+	for _, section := range doc.Sections {
+		source := name
+		if len(doc.Sections) > 1 {
+			source = name + " " + section.Source
+		}
+		e.sections = append(e.sections, Section{Source: source, Text: section.Text})
+		e.size += len(section.Text)
+	}
+}
+
+// full reports whether the archive has yielded as much text as is kept.
+func (e *archiveEntries) full() bool {
+	return e.size >= maxExtractedBytes
+}
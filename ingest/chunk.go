@@ -0,0 +1,86 @@
+package ingest
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultChunkTokens is the chunk size used when none is given.
+const DefaultChunkTokens = 1000
+
+// Chunk is a piece of a document small enough to place in a prompt on its
+// own. Index counts chunks across the whole document.
+type Chunk struct {
+	Source string `json:"source"`
+	Index  int    `json:"index"`
+	Text   string `json:"text"`
+	Tokens int    `json:"tokens"`
+}
+
+// EstimateTokens approximates the number of tokens text uses. Without the
+// model's tokenizer, four characters per token is close for English prose and
+// errs on the large side for code.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Split cuts a document into chunks of about chunkTokens tokens. Chunks
+// never span sections and break at blank lines, then line ends, then spaces
+// where possible.
+func Split(doc Document, chunkTokens int) []Chunk {
+	if chunkTokens <= 0 {
+		chunkTokens = DefaultChunkTokens
+	}
+	maxChars := chunkTokens * 4
+
+	var chunks []Chunk
+	for _, section := range doc.Sections {
+		for _, text := range splitText(section.Text, maxChars) {
+			chunks = append(chunks, Chunk{
+				Source: section.Source,
+				Index:  len(chunks),
+				Text:   text,
+				Tokens: EstimateTokens(text),
+			})
+		}
+	}
+	return chunks
+}
+
+// splitText cuts text into pieces of at most maxChars runes.
+func splitText(text string, maxChars int) []string {
+	var pieces []string
+	for {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return pieces
+		}
+		limit := runeOffset(text, maxChars)
+		if limit == len(text) {
+			return append(pieces, text)
+		}
+
+		cut := limit
+		for _, sep := range []string{"\n\n", "\n", " "} {
+			// Only break early if it keeps at least half a chunk
+			if i := strings.LastIndex(text[:limit], sep); i > limit/2 {
+				cut = i
+				break
+			}
+		}
+		pieces = append(pieces, text[:cut])
+		text = text[cut:]
+	}
+}
+
+// runeOffset returns the byte offset of the nth rune of s, or len(s) when s
+// has no more than n runes.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
@@ -0,0 +1,86 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// extractDOCX reads the body text of a Word document: paragraphs become
+// lines, table rows become lines and their cells are separated by tabs.
+func extractDOCX(data []byte) ([]Section, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid DOCX file: %v", err)
+	}
+
+	var document *zip.File
+	for _, file := range archive.File {
+		if file.Name == "word/document.xml" {
+			document = file
+			break
+		}
+	}
+	if document == nil {
+		return nil, fmt.Errorf("not a valid DOCX file: word/document.xml is missing")
+	}
+
+	reader, err := document.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var text strings.Builder
+	decoder := xml.NewDecoder(io.LimitReader(reader, maxExtractedBytes*4))
+	inText := false
+	cellDepth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid document.xml: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tc":
+				cellDepth++
+			case "tab":
+				text.WriteByte('\t')
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				// Paragraphs inside a table cell stay on the row's line
+				if cellDepth > 0 {
+					text.WriteByte(' ')
+				} else {
+					text.WriteByte('\n')
+				}
+			case "tc":
+				cellDepth--
+				text.WriteByte('\t')
+			case "tr":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+	return []Section{{Source: "document", Text: text.String()}}, nil
+}
@@ -0,0 +1,35 @@
+package ingest
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Elements whose content is never readable text
+	htmlHiddenPattern  = regexp.MustCompile(`(?is)<(script|style|noscript|template|svg|head)\b.*?</(script|style|noscript|template|svg|head)\s*>`)
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	// Tags that start a new line in rendered text
+	htmlBreakPattern = regexp.MustCompile(`(?i)<(br|/?p|/?div|/?h[1-6]|/?li|/?ul|/?ol|/?tr|/?table|/?section|/?article|/?header|/?footer|/?blockquote|/?pre|hr)\b[^>]*>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlSpacePattern = regexp.MustCompile(`[ \t\r\f\v]+`)
+	htmlBlankPattern = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// htmlToText renders HTML as plain text: hidden elements and tags are
+// dropped, block elements become line breaks and entities are decoded.
+func htmlToText(source string) string {
+	text := htmlCommentPattern.ReplaceAllString(source, "")
+	text = htmlHiddenPattern.ReplaceAllString(text, "")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = htmlSpacePattern.ReplaceAllString(text, " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(htmlBlankPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,160 @@
+// Package ingest extracts plain text from attached files (PDF, DOCX,
+// Markdown, HTML, source code and archives of it) and splits it into chunks
+// that can be fitted into a model's context window.
+package ingest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits that keep ingestion bounded whatever the input.
+const (
+	// MaxFileBytes is the largest file accepted.
+	MaxFileBytes = 100 << 20
+	// maxExtractedBytes caps the text kept from a single document.
+	maxExtractedBytes = 32 << 20
+)
+
+// Document formats reported in Document.Format.
+const (
+	FormatPDF      = "pdf"
+	FormatDOCX     = "docx"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatArchive  = "archive"
+	FormatText     = "text"
+)
+
+// ErrUnsupported is returned for files that are neither a known document
+// format nor text.
+var ErrUnsupported = errors.New("unsupported file type")
+
+// Section is a contiguous piece of a document: a PDF page, a file inside an
+// archive, or the whole text of a simple document.
+type Section struct {
+	Source string `json:"source"`
+	Text   string `json:"text"`
+}
+
+// Document is the text extracted from a file.
+type Document struct {
+	Name     string    `json:"name"`
+	Format   string    `json:"format"`
+	Sections []Section `json:"sections"`
+	// Skipped lists parts that could not be used, with the reason, such as
+	// binary files in an archive.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// ExtractFile reads a file and extracts its text based on its extension.
+func ExtractFile(path string) (Document, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Document{}, err
+	}
+	if info.IsDir() {
+		return Document{}, fmt.Errorf("%s is a directory", filepath.Base(path))
+	}
+	if info.Size() > MaxFileBytes {
+		return Document{}, fmt.Errorf("%s is larger than %d MB", filepath.Base(path), MaxFileBytes>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, err
+	}
+	return Extract(filepath.Base(path), data)
+}
+
+// Extract extracts the text of a file's contents, using name to pick the format.
+func Extract(name string, data []byte) (Document, error) {
+	doc := Document{Name: name}
+	lower := strings.ToLower(name)
+	var err error
+	switch {
+	case strings.HasSuffix(lower, ".pdf"):
+		doc.Format = FormatPDF
+		doc.Sections, err = extractPDF(data)
+	case strings.HasSuffix(lower, ".docx"):
+		doc.Format = FormatDOCX
+		doc.Sections, err = extractDOCX(data)
+	case strings.HasSuffix(lower, ".md"), strings.HasSuffix(lower, ".markdown"):
+		doc.Format = FormatMarkdown
+		doc.Sections, err = extractText(name, data)
+	case strings.HasSuffix(lower, ".html"), strings.HasSuffix(lower, ".htm"):
+		doc.Format = FormatHTML
+		doc.Sections = []Section{{Source: name, Text: htmlToText(string(data))}}
+	case strings.HasSuffix(lower, ".zip"):
+		doc.Format = FormatArchive
+		doc.Sections, doc.Skipped, err = extractZip(data)
+	case strings.HasSuffix(lower, ".tar"), strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		doc.Format = FormatArchive
+		doc.Sections, doc.Skipped, err = extractTar(data, !strings.HasSuffix(lower, ".tar"))
+	default:
+		doc.Format = FormatText
+		doc.Sections, err = extractText(name, data)
+	}
+	if err != nil {
+		return Document{}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	var capped bool
+	doc.Sections, capped = capSections(doc.Sections)
+	if capped {
+		doc.Skipped = append(doc.Skipped, fmt.Sprintf("text after the first %d MB", maxExtractedBytes>>20))
+	}
+	if len(doc.Sections) == 0 {
+		return Document{}, fmt.Errorf("no text found in %s", name)
+	}
+	return doc, nil
+}
+
+// extractText accepts data that looks like text and rejects binary files.
+func extractText(name string, data []byte) ([]Section, error) {
+	if !isText(data) {
+		return nil, ErrUnsupported
+	}
+	text := strings.ToValidUTF8(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "\uFFFD")
+	return []Section{{Source: name, Text: text}}, nil
+}
+
+// isText reports whether data is UTF-8 without NUL bytes, judged from its start.
+func isText(data []byte) bool {
+	sample := data
+	if len(sample) > 8192 {
+		sample = sample[:8192]
+		// Do not judge a multi-byte rune cut off by the sample
+		for i := 0; i < utf8.UTFMax && !utf8.Valid(sample); i++ {
+			sample = sample[:len(sample)-1]
+		}
+	}
+	return bytes.IndexByte(sample, 0) < 0 && utf8.Valid(sample)
+}
+
+// capSections drops empty sections and stops once maxExtractedBytes of text
+// have been collected, reporting whether any text was cut.
+func capSections(sections []Section) ([]Section, bool) {
+	kept := make([]Section, 0, len(sections))
+	total := 0
+	for _, section := range sections {
+		section.Text = strings.TrimSpace(section.Text)
+		if section.Text == "" {
+			continue
+		}
+		if total+len(section.Text) > maxExtractedBytes {
+			section.Text = strings.ToValidUTF8(section.Text[:maxExtractedBytes-total], "")
+			if section.Text != "" {
+				kept = append(kept, section)
+			}
+			return kept, true
+		}
+		total += len(section.Text)
+		kept = append(kept, section)
+	}
+	return kept, false
+}
@@ -0,0 +1,963 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// The PDF reader below handles what text extraction needs and no more: it
+// locates objects by scanning for "N G obj" rather than trusting the xref
+// table, so damaged and incrementally updated files still read. Encrypted
+// files are rejected.
+
+// Limits on PDF structures, so a crafted file cannot loop or balloon.
+const (
+	maxPDFDepth        = 32
+	maxPDFStreamBytes  = 64 << 20
+	maxPDFCMapRange    = 1 << 16
+	maxPDFFormNesting  = 8
+	pdfWordGap         = -180 // TJ adjustment, in thousandths of an em, that reads as a space
+	pdfInlineImageScan = 1 << 20
+)
+
+var (
+	pdfObjectPattern  = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfEncryptPattern = regexp.MustCompile(`/Encrypt\s*(\d+\s+\d+\s+R|<<)`)
+)
+
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfDict    map[pdfName]interface{}
+	pdfArray   []interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfFile holds every object found in a file, keyed by object number.
+type pdfFile struct {
+	objects  map[int]interface{}
+	catalogs []int
+	fonts    map[pdfRef]*pdfFont
+}
+
+// extractPDF extracts the text of each page as its own section. A file that
+// trips up the reader is reported as invalid rather than crashing the caller.
+func extractPDF(data []byte) (sections []Section, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			sections, err = nil, fmt.Errorf("not a valid PDF file: %v", r)
+		}
+	}()
+	return readPDF(data)
+}
+
+// readPDF does the work of extractPDF.
+func readPDF(data []byte) ([]Section, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a valid PDF file")
+	}
+	if pdfEncryptPattern.Match(data) {
+		return nil, fmt.Errorf("encrypted PDFs are not supported")
+	}
+
+	file := parsePDF(data)
+	pages := file.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("not a valid PDF file: no pages found")
+	}
+
+	sections := make([]Section, 0, len(pages))
+	size := 0
+	for i, page := range pages {
+		var text strings.Builder
+		file.pageText(&text, file.contents(page.dict["Contents"]), page.resources, 0)
+		sections = append(sections, Section{
+			Source: fmt.Sprintf("page %d", i+1),
+			Text:   cleanPDFText(text.String()),
+		})
+		size += text.Len()
+		if size > maxExtractedBytes {
+			break
+		}
+	}
+	return sections, nil
+}
+
+// parsePDF collects the objects of a file, including those packed into
+// object streams. Later definitions replace earlier ones, as incremental
+// updates require.
+func parsePDF(data []byte) *pdfFile {
+	file := &pdfFile{objects: map[int]interface{}{}, fonts: map[pdfRef]*pdfFont{}}
+	end := 0
+	var objectStreams []int
+	for _, match := range pdfObjectPattern.FindAllSubmatchIndex(data, -1) {
+		// Skip matches inside the previous object, such as stream data
+		if match[0] < end {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		lexer := &pdfLexer{data: data, pos: match[1]}
+		value, err := lexer.object(0)
+		if err != nil {
+			continue
+		}
+		if dict, ok := value.(pdfDict); ok {
+			if raw, ok := lexer.stream(dict); ok {
+				value = &pdfStream{dict: dict, raw: raw}
+				if dict["Type"] == pdfName("ObjStm") {
+					objectStreams = append(objectStreams, num)
+				}
+			} else if dict["Type"] == pdfName("Catalog") {
+				file.catalogs = append(file.catalogs, num)
+			}
+		}
+		file.objects[num] = value
+		end = lexer.pos
+	}
+
+	for _, num := range objectStreams {
+		file.unpackObjectStream(num)
+	}
+	return file
+}
+
+// unpackObjectStream adds the objects stored in an object stream, unless the
+// file also defines them directly.
+func (f *pdfFile) unpackObjectStream(num int) {
+	stream, ok := f.objects[num].(*pdfStream)
+	if !ok {
+		return
+	}
+	data, err := f.decode(stream)
+	if err != nil {
+		return
+	}
+	count, _ := f.resolve(stream.dict["N"]).(float64)
+	first, _ := f.resolve(stream.dict["First"]).(float64)
+	// Compare as floats: a huge value would overflow int
+	if first < 0 || first > float64(len(data)) {
+		return
+	}
+
+	header := &pdfLexer{data: data[:int(first)]}
+	for i := 0; i < int(count); i++ {
+		objNum, err1 := header.object(0)
+		offset, err2 := header.object(0)
+		n, ok1 := objNum.(float64)
+		o, ok2 := offset.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		if _, exists := f.objects[int(n)]; exists {
+			continue
+		}
+		if o < 0 || first+o > float64(len(data)) {
+			continue
+		}
+		start := int(first) + int(o)
+		value, err := (&pdfLexer{data: data, pos: start}).object(0)
+		if err != nil {
+			continue
+		}
+		if dict, ok := value.(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+			f.catalogs = append(f.catalogs, int(n))
+		}
+		f.objects[int(n)] = value
+	}
+}
+
+// resolve follows indirect references to the object they name.
+func (f *pdfFile) resolve(value interface{}) interface{} {
+	for i := 0; i < maxPDFDepth; i++ {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = f.objects[ref.num]
+	}
+	return nil
+}
+
+func (f *pdfFile) dict(value interface{}) pdfDict {
+	switch v := f.resolve(value).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode applies a stream's filters to its data.
+func (f *pdfFile) decode(stream *pdfStream) ([]byte, error) {
+	var filters []interface{}
+	switch filter := f.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []interface{}{filter}
+	case pdfArray:
+		filters = filter
+	}
+
+	data := stream.raw
+	for _, filter := range filters {
+		var err error
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			var reader io.ReadCloser
+			reader, err = zlib.NewReader(bytes.NewReader(data))
+			if err == nil {
+				// Keep what decompressed before any corruption
+				data, err = io.ReadAll(io.LimitReader(reader, maxPDFStreamBytes))
+				reader.Close()
+				if len(data) > 0 {
+					err = nil
+				}
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			hexData := bytes.Map(func(r rune) rune {
+				if strings.ContainsRune(" \t\r\n\f\x00", r) {
+					return -1
+				}
+				return r
+			}, data)
+			hexData, _, _ = bytes.Cut(hexData, []byte(">"))
+			if len(hexData)%2 == 1 {
+				hexData = append(hexData, '0')
+			}
+			data = make([]byte, len(hexData)/2)
+			_, err = hex.Decode(data, hexData)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			encoded, _, _ := bytes.Cut(bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~")), []byte("~>"))
+			decoded := make([]byte, len(encoded)*4/5+4)
+			var n int
+			n, _, err = ascii85.Decode(decoded, encoded, true)
+			data = decoded[:n]
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// pdfPage is a page with the resources it inherits from its ancestors.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages lists the pages in document order by walking the page tree. Files
+// without a usable catalog fall back to every page object in number order.
+func (f *pdfFile) pages() []pdfPage {
+	var pages []pdfPage
+	visited := map[interface{}]bool{}
+	for i := len(f.catalogs) - 1; i >= 0 && len(pages) == 0; i-- {
+		catalog := f.dict(f.objects[f.catalogs[i]])
+		f.walkPages(catalog["Pages"], nil, visited, &pages, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	nums := make([]int, 0, len(f.objects))
+	for num := range f.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if dict, ok := f.objects[num].(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: f.dict(dict["Resources"])})
+		}
+	}
+	return pages
+}
+
+func (f *pdfFile) walkPages(node interface{}, resources pdfDict, visited map[interface{}]bool, pages *[]pdfPage, depth int) {
+	if ref, ok := node.(pdfRef); ok {
+		if visited[ref] {
+			return
+		}
+		visited[ref] = true
+	}
+	dict := f.dict(node)
+	if dict == nil || depth > maxPDFDepth {
+		return
+	}
+	if own := f.dict(dict["Resources"]); own != nil {
+		resources = own
+	}
+
+	kids, isTree := f.resolve(dict["Kids"]).(pdfArray)
+	if !isTree {
+		*pages = append(*pages, pdfPage{dict: dict, resources: resources})
+		return
+	}
+	for _, kid := range kids {
+		f.walkPages(kid, resources, visited, pages, depth+1)
+	}
+}
+
+// contents concatenates the content streams of a page, given as one stream
+// or an array of them.
+func (f *pdfFile) contents(value interface{}) []byte {
+	var streams []interface{}
+	switch v := f.resolve(value).(type) {
+	case *pdfStream:
+		streams = []interface{}{v}
+	case pdfArray:
+		streams = v
+	}
+
+	var data []byte
+	for _, item := range streams {
+		stream, ok := f.resolve(item).(*pdfStream)
+		if !ok {
+			continue
+		}
+		decoded, err := f.decode(stream)
+		if err != nil {
+			continue
+		}
+		data = append(data, decoded...)
+		data = append(data, '\n')
+	}
+	return data
+}
+
+// pageText runs the text operators of a content stream, writing the text it
+// shows. Line breaks are inferred from text positioning.
+func (f *pdfFile) pageText(out *strings.Builder, content []byte, resources pdfDict, depth int) {
+	lexer := &pdfLexer{data: content}
+	var operands []interface{}
+	var font *pdfFont
+	var lineY float64
+
+	newline := func() {
+		if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+			out.WriteByte('\n')
+		}
+	}
+	space := func() {
+		s := out.String()
+		if len(s) > 0 && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+			out.WriteByte(' ')
+		}
+	}
+	show := func(value interface{}) {
+		if s, ok := value.(pdfString); ok {
+			out.WriteString(font.decode(s))
+		}
+	}
+	number := func(i int) float64 {
+		if i < 0 || i >= len(operands) {
+			return 0
+		}
+		n, _ := operands[i].(float64)
+		return n
+	}
+
+	for {
+		value, err := lexer.object(0)
+		if err != nil {
+			return
+		}
+		op, ok := value.(pdfKeyword)
+		if !ok {
+			operands = append(operands, value)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = f.font(resources, name)
+				}
+			}
+		case "Tj":
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "'", "\"":
+			newline()
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				items, _ := operands[len(operands)-1].(pdfArray)
+				for _, item := range items {
+					if n, ok := item.(float64); ok {
+						if n < pdfWordGap {
+							space()
+						}
+						continue
+					}
+					show(item)
+				}
+			}
+		case "Td", "TD":
+			if ty := number(len(operands) - 1); ty != 0 {
+				newline()
+				lineY += ty
+			} else if number(len(operands)-2) != 0 {
+				space()
+			}
+		case "T*":
+			newline()
+		case "Tm":
+			if y := number(len(operands) - 1); y != lineY {
+				newline()
+				lineY = y
+			} else {
+				space()
+			}
+		case "ET":
+			space()
+		case "Do":
+			if len(operands) > 0 && depth < maxPDFFormNesting {
+				if name, ok := operands[len(operands)-1].(pdfName); ok {
+					f.formText(out, resources, name, depth)
+				}
+			}
+		case "ID":
+			lexer.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// formText extracts the text of a form XObject drawn with Do.
+func (f *pdfFile) formText(out *strings.Builder, resources pdfDict, name pdfName, depth int) {
+	stream, ok := f.resolve(f.dict(resources["XObject"])[name]).(*pdfStream)
+	if !ok || stream.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := f.decode(stream)
+	if err != nil {
+		return
+	}
+	if own := f.dict(stream.dict["Resources"]); own != nil {
+		resources = own
+	}
+	f.pageText(out, data, resources, depth+1)
+}
+
+// cleanPDFText collapses runs of spaces and trims each line.
+func cleanPDFText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// pdfFont maps the character codes of a font to text.
+type pdfFont struct {
+	// Composite fonts use two-byte codes
+	composite bool
+	toUnicode map[uint32]string
+	// differences holds codes the font's /Encoding renames
+	differences map[byte]string
+}
+
+// font looks up a font by its resource name, caching it by reference.
+func (f *pdfFile) font(resources pdfDict, name pdfName) *pdfFont {
+	value := f.dict(resources["Font"])[name]
+	ref, isRef := value.(pdfRef)
+	if isRef {
+		if font, ok := f.fonts[ref]; ok {
+			return font
+		}
+	}
+
+	font := &pdfFont{}
+	if dict := f.dict(value); dict != nil {
+		font.composite = dict["Subtype"] == pdfName("Type0")
+		if stream, ok := f.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+			if data, err := f.decode(stream); err == nil {
+				font.toUnicode = parseCMap(data)
+			}
+		}
+		font.differences = f.differences(dict["Encoding"])
+	}
+	if isRef {
+		f.fonts[ref] = font
+	}
+	return font
+}
+
+// decode converts the codes in a shown string to text. Without a ToUnicode
+// map, simple fonts are read as WinAnsi and composite fonts yield nothing.
+func (font *pdfFont) decode(s pdfString) string {
+	if font == nil {
+		font = &pdfFont{}
+	}
+	var text strings.Builder
+	if font.composite {
+		for i := 0; i+1 < len(s); i += 2 {
+			text.WriteString(font.toUnicode[uint32(s[i])<<8|uint32(s[i+1])])
+		}
+		return text.String()
+	}
+	for _, b := range s {
+		if mapped, ok := font.toUnicode[uint32(b)]; ok {
+			text.WriteString(mapped)
+		} else if mapped, ok := font.differences[b]; ok {
+			text.WriteString(mapped)
+		} else {
+			text.WriteRune(winAnsiRune(b))
+		}
+	}
+	return text.String()
+}
+
+// differences reads the /Differences array of a simple font's encoding,
+// keeping the glyphs whose names give their text.
+func (f *pdfFile) differences(encoding interface{}) map[byte]string {
+	items, _ := f.resolve(f.dict(encoding)["Differences"]).(pdfArray)
+	differences := map[byte]string{}
+	code := 0
+	for _, item := range items {
+		switch v := item.(type) {
+		case float64:
+			code = int(v)
+		case pdfName:
+			if text, ok := glyphText(string(v)); ok && code >= 0 && code < 256 {
+				differences[byte(code)] = text
+			}
+			code++
+		}
+	}
+	return differences
+}
+
+// glyphText returns the text of a glyph name: single letters and digits,
+// uniXXXX names and the ligatures and punctuation that differ from WinAnsi.
+func glyphText(name string) (string, bool) {
+	if text, ok := pdfGlyphNames[name]; ok {
+		return text, true
+	}
+	if len(name) == 1 && (name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z') {
+		return name, true
+	}
+	if len(name) == 7 && strings.HasPrefix(name, "uni") {
+		if code, err := strconv.ParseUint(name[3:], 16, 16); err == nil {
+			return string(rune(code)), true
+		}
+	}
+	return "", false
+}
+
+var pdfGlyphNames = map[string]string{
+	"ff": "ff", "fi": "fi", "fl": "fl", "ffi": "ffi", "ffl": "ffl",
+	"space": " ", "quoteright": "’", "quoteleft": "‘", "quotedblleft": "“", "quotedblright": "”",
+	"quotesingle": "'", "quotedbl": "\"", "endash": "–", "emdash": "—", "bullet": "•",
+	"hyphen": "-", "minus": "−", "periodcentered": "·", "ellipsis": "…",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+}
+
+// winAnsiRune maps a WinAnsiEncoding code to its character. Codes outside
+// 0x80-0x9F coincide with Latin-1.
+func winAnsiRune(b byte) rune {
+	if b >= 0x80 && b <= 0x9f {
+		if r := winAnsiHigh[b-0x80]; r != 0 {
+			return r
+		}
+		return ' '
+	}
+	if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+		return ' '
+	}
+	return rune(b)
+}
+
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap.
+func parseCMap(data []byte) map[uint32]string {
+	mapping := map[uint32]string{}
+	lexer := &pdfLexer{data: data}
+	var operands []interface{}
+	for {
+		value, err := lexer.object(0)
+		if err != nil {
+			return mapping
+		}
+		op, ok := value.(pdfKeyword)
+		if !ok {
+			operands = append(operands, value)
+			continue
+		}
+		switch op {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					mapping[cmapCode(src)] = utf16Text(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, stop := cmapCode(lo), cmapCode(hi)
+				if stop < start || stop-start >= maxPDFCMapRange {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					runes := []rune(utf16Text(dst))
+					if len(runes) == 0 {
+						continue
+					}
+					for code := start; code <= stop; code++ {
+						mapping[code] = string(runes)
+						runes[len(runes)-1]++
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && start+uint32(j) <= stop {
+							mapping[start+uint32(j)] = utf16Text(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func cmapCode(s pdfString) uint32 {
+	var code uint32
+	for _, b := range s {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+// utf16Text decodes the UTF-16BE text a CMap maps codes to.
+func utf16Text(s pdfString) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfLexer reads PDF objects and content stream operators.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f' || b == 0
+}
+
+func isPDFDelimiter(b byte) bool {
+	return strings.IndexByte("()<>[]{}/%", b) >= 0
+}
+
+// skipSpace skips whitespace and comments.
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch b := l.data[l.pos]; {
+		case isPDFSpace(b):
+			l.pos++
+		case b == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// object reads the next complete object: a dictionary, array, string, name,
+// number, reference, boolean, null or bare keyword.
+func (l *pdfLexer) object(depth int) (interface{}, error) {
+	if depth > maxPDFDepth {
+		return nil, fmt.Errorf("objects nested too deeply")
+	}
+	token, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case pdfKeyword:
+		switch t {
+		case "<<":
+			dict := pdfDict{}
+			for {
+				key, err := l.object(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				if key == pdfKeyword(">>") {
+					return dict, nil
+				}
+				name, ok := key.(pdfName)
+				if !ok {
+					continue
+				}
+				value, err := l.object(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				dict[name] = value
+			}
+		case "[":
+			array := pdfArray{}
+			for {
+				item, err := l.object(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				if item == pdfKeyword("]") {
+					return array, nil
+				}
+				array = append(array, item)
+			}
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	case float64:
+		// "num gen R" is a reference
+		if t >= 0 && t == float64(int(t)) {
+			save := l.pos
+			gen, err1 := l.token()
+			keyword, err2 := l.token()
+			if g, ok := gen.(float64); ok && err1 == nil && err2 == nil && keyword == pdfKeyword("R") {
+				return pdfRef{num: int(t), gen: int(g)}, nil
+			}
+			l.pos = save
+		}
+	}
+	return token, nil
+}
+
+// token reads a single token. Dictionary and array delimiters are returned
+// as keywords.
+func (l *pdfLexer) token() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	b := l.data[l.pos]
+	switch {
+	case b == '(':
+		return l.literalString(), nil
+	case b == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		return l.hexString(), nil
+	case b == '>':
+		l.pos++
+		if l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+			return pdfKeyword(">>"), nil
+		}
+		return pdfKeyword(">"), nil
+	case b == '[' || b == ']' || b == '{' || b == '}' || b == ')':
+		l.pos++
+		return pdfKeyword(string(b)), nil
+	case b == '/':
+		l.pos++
+		return l.name(), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil && strings.IndexFunc(word, func(r rune) bool {
+		return !strings.ContainsRune("+-.0123456789", r)
+	}) < 0 {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	var name []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		b := l.data[l.pos]
+		if b == '#' && l.pos+2 < len(l.data) {
+			if decoded, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+				name = append(name, decoded[0])
+				l.pos += 3
+				continue
+			}
+		}
+		name = append(name, b)
+		l.pos++
+	}
+	return pdfName(name)
+}
+
+// literalString reads a parenthesised string, resolving escapes.
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var s []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		l.pos++
+		switch b {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s
+			}
+		case '\r':
+			// End-of-line sequences read as a single newline
+			if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+				l.pos++
+			}
+			b = '\n'
+		case '\\':
+			if l.pos >= len(l.data) {
+				return s
+			}
+			b = l.data[l.pos]
+			l.pos++
+			switch b {
+			case 'n':
+				b = '\n'
+			case 'r':
+				b = '\r'
+			case 't':
+				b = '\t'
+			case 'b':
+				b = '\b'
+			case 'f':
+				b = '\f'
+			case '\r', '\n':
+				// A backslash at the end of a line continues the string
+				if b == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			default:
+				if b >= '0' && b <= '7' {
+					code := int(b - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						code = code*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					b = byte(code)
+				}
+			}
+		}
+		s = append(s, b)
+	}
+	return s
+}
+
+// hexString reads a string written as hex digits between angle brackets.
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if b := l.data[l.pos]; !isPDFSpace(b) {
+			digits = append(digits, b)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make([]byte, len(digits)/2)
+	hex.Decode(s, digits)
+	return s
+}
+
+// stream reads the data following a stream dictionary, if there is any. A
+// direct /Length is used when it checks out; otherwise the data runs to the
+// next "endstream".
+func (l *pdfLexer) stream(dict pdfDict) ([]byte, bool) {
+	save := l.pos
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		l.pos = save
+		return nil, false
+	}
+	start := l.pos + len("stream")
+	if start < len(l.data) && l.data[start] == '\r' {
+		start++
+	}
+	if start < len(l.data) && l.data[start] == '\n' {
+		start++
+	}
+
+	if length, ok := dict["Length"].(float64); ok && length >= 0 && length <= float64(len(l.data)-start) {
+		end := start + int(length)
+		rest := bytes.TrimLeft(l.data[end:], " \t\r\n")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = len(l.data) - len(rest) + len("endstream")
+			return l.data[start:end], true
+		}
+	}
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		l.pos = len(l.data)
+		return l.data[start:], true
+	}
+	l.pos = start + end + len("endstream")
+	return bytes.TrimRight(l.data[start:start+end], "\r\n"), true
+}
+
+// skipInlineImage skips the binary data of an inline image, which runs from
+// after ID to an EI surrounded by whitespace.
+func (l *pdfLexer) skipInlineImage() {
+	limit := len(l.data)
+	if l.pos+pdfInlineImageScan < limit {
+		limit = l.pos + pdfInlineImageScan
+	}
+	for i := l.pos + 1; i+2 <= limit; i++ {
+		if l.data[i] == 'E' && l.data[i+1] == 'I' && isPDFSpace(l.data[i-1]) &&
+			(i+2 == len(l.data) || isPDFSpace(l.data[i+2]) || isPDFDelimiter(l.data[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = limit
+}
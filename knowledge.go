@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"myproject/connectors"
+	"myproject/ingest"
+	"myproject/rag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Events emitted while a knowledge folder is indexed, and with the excerpts
+// a chat drew on.
+const (
+	KnowledgeProgressEvent = "knowledge:progress"
+	KnowledgeDoneEvent     = "knowledge:done"
+	ChatSourcesEvent       = "chat:sources"
+)
+
+// Number of excerpts added to a chat when no top_k is configured, and the most allowed.
+const (
+	defaultKnowledgeTopK = 5
+	maxKnowledgeTopK     = 50
+)
+
+// KnowledgeConfig is the knowledge base: project folders whose files chats
+// can draw on, and the embedding model used to index them.
+type KnowledgeConfig struct {
+	Folders  []string `json:"folders,omitempty"`
+	Provider string   `json:"provider,omitempty"`
+	Model    string   `json:"model,omitempty"`
+	// TopK is how many excerpts a chat receives, defaultKnowledgeTopK if unset.
+	TopK int `json:"top_k,omitempty"`
+}
+
+// KnowledgeFolder is a knowledge folder with the state of its index.
+type KnowledgeFolder struct {
+	rag.Status
+	// Indexing is set while the folder is being indexed in the background.
+	Indexing bool `json:"indexing"`
+	// Stale is set when the index was built with another embedding model
+	// and will be rebuilt on the next update.
+	Stale bool `json:"stale"`
+}
+
+// KnowledgeProgress reports the progress of a background indexing job.
+type KnowledgeProgress struct {
+	IndexID string `json:"index_id"`
+	rag.Progress
+}
+
+// KnowledgeDone is emitted once per indexing job with its outcome.
+type KnowledgeDone struct {
+	IndexID   string            `json:"index_id"`
+	Root      string            `json:"root"`
+	Result    *rag.UpdateResult `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Cancelled bool              `json:"cancelled,omitempty"`
+}
+
+// ChatSources lists the knowledge excerpts added to a generation's prompt,
+// numbered as the model was asked to cite them.
+type ChatSources struct {
+	GenerationID string    `json:"generation_id"`
+	Sources      []rag.Hit `json:"sources"`
+}
+
+// GetKnowledgeConfig returns the knowledge folders and embedding settings.
+func (a *App) GetKnowledgeConfig() KnowledgeConfig {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	config := a.knowledgeConfig
+	config.Folders = slices.Clone(config.Folders)
+	return config
+}
+
+// SetKnowledgeSettings chooses the embedding model and how many excerpts a
+// chat receives. Changing the model re-embeds every folder on its next update.
+func (a *App) SetKnowledgeSettings(provider string, model string, topK int) error {
+	if topK < 0 || topK > maxKnowledgeTopK {
+		return fmt.Errorf("top_k must be from 1 to %d, or 0 for the default", maxKnowledgeTopK)
+	}
+	info, ok := a.registry.Info(provider)
+	if !ok {
+		return fmt.Errorf("unsupported provider: %s", provider)
+	}
+	if !info.Capabilities.Embeddings {
+		return fmt.Errorf("%s cannot create embeddings", provider)
+	}
+	if strings.TrimSpace(model) == "" {
+		return fmt.Errorf("an embedding model is required")
+	}
+
+	a.configMutex.Lock()
+	a.knowledgeConfig.Provider = provider
+	a.knowledgeConfig.Model = strings.TrimSpace(model)
+	a.knowledgeConfig.TopK = topK
+	a.configMutex.Unlock()
+	return a.saveConfig()
+}
+
+// AddKnowledgeFolder adds a project folder to the knowledge base, asking for
+// one when path is empty, and starts indexing it. It returns the indexing
+// job's ID, or an empty ID for a cancelled dialog; see IndexKnowledgeFolder.
+func (a *App) AddKnowledgeFolder(path string) (string, error) {
+	if path == "" {
+		if a.ctx == nil {
+			return "", fmt.Errorf("a folder path is required")
+		}
+		var err error
+		path, err = runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{Title: "Add knowledge folder"})
+		if err != nil || path == "" {
+			return "", err
+		}
+	}
+	root, err := a.addKnowledgeFolder(path)
+	if err != nil {
+		return "", err
+	}
+	return a.IndexKnowledgeFolder(root)
+}
+
+// addKnowledgeFolder saves a folder in the knowledge base without indexing
+// it, returning its absolute path.
+func (a *App) addKnowledgeFolder(path string) (string, error) {
+	if a.knowledgeStore == nil {
+		return "", fmt.Errorf("knowledge folders are unavailable without a home directory")
+	}
+	root, err := knowledgeRoot(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := a.knowledgeEmbedding(); err != nil {
+		return "", err
+	}
+
+	a.configMutex.Lock()
+	if !slices.Contains(a.knowledgeConfig.Folders, root) {
+		a.knowledgeConfig.Folders = append(a.knowledgeConfig.Folders, root)
+	}
+	a.configMutex.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return "", err
+	}
+	return root, nil
+}
+
+// RemoveKnowledgeFolder takes a folder out of the knowledge base and deletes
+// its index. A running indexing job for it is cancelled.
+func (a *App) RemoveKnowledgeFolder(path string) error {
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid folder path: %w", err)
+	}
+	a.knowledgeMutex.Lock()
+	jobID, indexing := a.knowledgeJobs[root]
+	a.knowledgeMutex.Unlock()
+	if indexing {
+		a.CancelGeneration(jobID)
+	}
+
+	a.configMutex.Lock()
+	folders := slices.DeleteFunc(slices.Clone(a.knowledgeConfig.Folders), func(folder string) bool { return folder == root })
+	removed := len(folders) < len(a.knowledgeConfig.Folders)
+	a.knowledgeConfig.Folders = folders
+	a.configMutex.Unlock()
+	if !removed {
+		return fmt.Errorf("%s is not a knowledge folder", root)
+	}
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	if a.knowledgeStore != nil {
+		return a.knowledgeStore.Remove(root)
+	}
+	return nil
+}
+
+// GetKnowledgeFolders lists the knowledge folders with the state of their indexes.
+func (a *App) GetKnowledgeFolders() ([]KnowledgeFolder, error) {
+	config := a.GetKnowledgeConfig()
+	folders := make([]KnowledgeFolder, 0, len(config.Folders))
+	for _, root := range config.Folders {
+		folder := KnowledgeFolder{Status: rag.Status{Root: root}}
+		if a.knowledgeStore != nil {
+			status, err := a.knowledgeStore.Status(root)
+			if err != nil {
+				return nil, err
+			}
+			folder.Status = status
+		}
+		a.knowledgeMutex.Lock()
+		_, folder.Indexing = a.knowledgeJobs[root]
+		a.knowledgeMutex.Unlock()
+		folder.Stale = folder.Files > 0 && (folder.Provider != config.Provider || folder.Model != config.Model)
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+// IndexKnowledgeFolder starts bringing a knowledge folder's index up to date
+// and returns a job ID at once. Only new and modified files are embedded
+// again. Progress arrives through KnowledgeProgressEvent and the outcome
+// through KnowledgeDoneEvent; CancelGeneration with the job ID stops it and
+// keeps the files indexed so far.
+func (a *App) IndexKnowledgeFolder(path string) (string, error) {
+	root, err := a.knowledgeFolder(path)
+	if err != nil {
+		return "", err
+	}
+	embedding, err := a.knowledgeEmbedding()
+	if err != nil {
+		return "", err
+	}
+
+	a.knowledgeMutex.Lock()
+	if jobID, running := a.knowledgeJobs[root]; running {
+		a.knowledgeMutex.Unlock()
+		return jobID, nil
+	}
+	jobID, err := newGenerationID()
+	if err != nil {
+		a.knowledgeMutex.Unlock()
+		return "", err
+	}
+	ctx, finish, err := a.beginGeneration(jobID)
+	if err != nil {
+		a.knowledgeMutex.Unlock()
+		return "", err
+	}
+	a.knowledgeJobs[root] = jobID
+	a.knowledgeMutex.Unlock()
+
+	go func() {
+		defer finish()
+		result, err := a.knowledgeStore.Update(ctx, root, embedding, func(progress rag.Progress) {
+			if a.ctx != nil {
+				runtime.EventsEmit(a.ctx, KnowledgeProgressEvent, KnowledgeProgress{IndexID: jobID, Progress: progress})
+			}
+		})
+
+		a.knowledgeMutex.Lock()
+		delete(a.knowledgeJobs, root)
+		a.knowledgeMutex.Unlock()
+
+		done := KnowledgeDone{IndexID: jobID, Root: root, Result: &result}
+		if ctx.Err() != nil {
+			done.Cancelled = true
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Indexing %s failed: %v\n", root, err)
+			done.Error = err.Error()
+		}
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, KnowledgeDoneEvent, done)
+		}
+	}()
+
+	return jobID, nil
+}
+
+// SearchKnowledge returns the knowledge excerpts most relevant to a query,
+// best first. topK defaults to the configured number of excerpts.
+func (a *App) SearchKnowledge(query string, topK int) ([]rag.Hit, error) {
+	return a.searchKnowledge(context.Background(), query, topK)
+}
+
+func (a *App) searchKnowledge(ctx context.Context, query string, topK int) ([]rag.Hit, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("a query is required")
+	}
+	config := a.GetKnowledgeConfig()
+	if len(config.Folders) == 0 || a.knowledgeStore == nil {
+		return nil, fmt.Errorf("no knowledge folders have been added")
+	}
+	if topK <= 0 {
+		topK = config.TopK
+	}
+	if topK <= 0 {
+		topK = defaultKnowledgeTopK
+	}
+	embedding, err := a.knowledgeEmbedding()
+	if err != nil {
+		return nil, err
+	}
+
+	vectors, err := embedding.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return a.knowledgeStore.Search(config.Folders, embedding.Provider, embedding.Model, vectors[0], min(topK, maxKnowledgeTopK))
+}
+
+// applyKnowledge adds the knowledge excerpts most relevant to the last user
+// message as a system message, when the request asks for them. Folders that
+// are not being indexed in the background are brought up to date first,
+// which only re-embeds files modified since the last update. Excerpts are
+// kept within what is left of the model's context window.
+func (a *App) applyKnowledge(ctx context.Context, request ChatRequest) (ChatRequest, []rag.Hit, error) {
+	if !request.UseKnowledge {
+		return request, nil, nil
+	}
+	embedding, err := a.knowledgeEmbedding()
+	if err != nil {
+		return request, nil, err
+	}
+	for _, root := range a.GetKnowledgeConfig().Folders {
+		a.knowledgeMutex.Lock()
+		_, indexing := a.knowledgeJobs[root]
+		a.knowledgeMutex.Unlock()
+		if indexing || a.knowledgeStore == nil {
+			continue
+		}
+		if _, err := a.knowledgeStore.Update(ctx, root, embedding, nil); err != nil {
+			if ctx.Err() != nil {
+				return request, nil, ctx.Err()
+			}
+			fmt.Fprintf(os.Stderr, "Warning: could not update the index of %s: %v\n", root, err)
+		}
+	}
+
+	var query string
+	used := 0
+	for _, msg := range request.Messages {
+		if msg.Role == connectors.RoleUser {
+			query = msg.Content
+		}
+		used += ingest.EstimateTokens(msg.Content)
+	}
+	if strings.TrimSpace(query) == "" {
+		return request, nil, nil
+	}
+	hits, err := a.searchKnowledge(ctx, query, 0)
+	if err != nil {
+		return request, nil, err
+	}
+
+	budget := a.documentTokenBudget(request.Provider, request.Model) - used
+	var excerpts strings.Builder
+	excerpts.WriteString("Excerpts from the user's files follow. Use them where they help answer, and cite each one you use by its number, e.g. [1].\n")
+	var kept []rag.Hit
+	for _, hit := range hits {
+		excerpt := fmt.Sprintf("\n[%d] %s\n%s\n", len(kept)+1, hit.Citation, hit.Text)
+		if ingest.EstimateTokens(excerpts.String()+excerpt) > budget {
+			break
+		}
+		excerpts.WriteString(excerpt)
+		kept = append(kept, hit)
+	}
+	if len(kept) == 0 {
+		return request, nil, nil
+	}
+
+	// Place the excerpts after any system prompt and before the conversation
+	system := connectors.ChatMessage{Role: connectors.RoleSystem, Content: excerpts.String()}
+	at := 0
+	for at < len(request.Messages) && request.Messages[at].Role == connectors.RoleSystem {
+		at++
+	}
+	request.Messages = slices.Insert(slices.Clone(request.Messages), at, system)
+	return request, kept, nil
+}
+
+// emitChatSources reports which excerpts a generation drew on, to the
+// request's own handler or else to the frontend.
+func (a *App) emitChatSources(request ChatRequest, hits []rag.Hit) {
+	if len(hits) == 0 {
+		return
+	}
+	if request.onSources != nil {
+		request.onSources(hits)
+	} else if a.ctx != nil && request.GenerationID != "" {
+		runtime.EventsEmit(a.ctx, ChatSourcesEvent, ChatSources{GenerationID: request.GenerationID, Sources: hits})
+	}
+}
+
+// knowledgeEmbedding returns the configured embedding model.
+func (a *App) knowledgeEmbedding() (rag.Embedding, error) {
+	config := a.GetKnowledgeConfig()
+	if config.Provider == "" || config.Model == "" {
+		return rag.Embedding{}, fmt.Errorf("no embedding model is set; choose one in the knowledge settings")
+	}
+	p, err := a.newProvider(config.Provider)
+	if err != nil {
+		return rag.Embedding{}, err
+	}
+	embedder, ok := p.(connectors.Embedder)
+	if !ok {
+		return rag.Embedding{}, fmt.Errorf("%s cannot create embeddings", config.Provider)
+	}
+	return rag.Embedding{
+		Provider: config.Provider,
+		Model:    config.Model,
+		Embed: func(ctx context.Context, inputs []string) ([][]float32, error) {
+			return embedder.Embed(ctx, config.Model, inputs)
+		},
+	}, nil
+}
+
+// knowledgeFolder resolves path to a folder in the knowledge base.
+func (a *App) knowledgeFolder(path string) (string, error) {
+	if a.knowledgeStore == nil {
+		return "", fmt.Errorf("knowledge folders are unavailable without a home directory")
+	}
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid folder path: %w", err)
+	}
+	if !slices.Contains(a.GetKnowledgeConfig().Folders, root) {
+		return "", fmt.Errorf("%s is not a knowledge folder", root)
+	}
+	return root, nil
+}
+
+// knowledgeRoot checks that path is a folder and returns its absolute path.
+func knowledgeRoot(path string) (string, error) {
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid folder path: %w", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a folder", root)
+	}
+	return root, nil
+}
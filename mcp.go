@@ -0,0 +1,576 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"myproject/connectors"
+	"myproject/mcp"
+	"myproject/secrets"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// MCPStatusEvent is emitted with an MCPServerStatus whenever an MCP server
+// connects, disconnects or fails.
+const MCPStatusEvent = "mcp:status"
+
+// Transports an MCP server can be reached over.
+const (
+	MCPTransportStdio = "stdio"
+	MCPTransportHTTP  = "http"
+)
+
+// Connection states of an MCP server.
+const (
+	MCPStateDisabled   = "disabled"
+	MCPStateConnecting = "connecting"
+	MCPStateConnected  = "connected"
+	MCPStateError      = "error"
+)
+
+// How long connecting to an MCP server, and listing what it offers, may take.
+const (
+	mcpConnectTimeout = 30 * time.Second
+	mcpListTimeout    = 30 * time.Second
+)
+
+var mcpServerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// mcpInvalidToolChars matches what tool names may not contain.
+var mcpInvalidToolChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// MCPServerConfig defines an MCP server: a command launched over stdio or a
+// streamable HTTP URL. The values of Env and Headers are kept in the secret
+// store, so config.json and GetMCPServers only carry their names.
+type MCPServerConfig struct {
+	Transport string            `json:"transport"`
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Dir       string            `json:"dir,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Enabled   bool              `json:"enabled"`
+}
+
+// MCPServerStatus describes a configured MCP server and its connection.
+type MCPServerStatus struct {
+	Name   string          `json:"name"`
+	Config MCPServerConfig `json:"config"`
+	State  string          `json:"state"`
+	Error  string          `json:"error,omitempty"`
+	Server *mcp.ServerInfo `json:"server,omitempty"`
+	// ToolNames are the names the server's tools are offered to models under.
+	ToolNames []string `json:"tool_names,omitempty"`
+}
+
+// MCPServerDetails lists what a connected MCP server offers.
+type MCPServerDetails struct {
+	MCPServerStatus
+	Tools     []mcp.Tool     `json:"tools"`
+	Resources []mcp.Resource `json:"resources"`
+	Prompts   []mcp.Prompt   `json:"prompts"`
+}
+
+// mcpConnection is the live state of an enabled MCP server. A reconnect
+// replaces it, so callbacks check that their connection is still current.
+type mcpConnection struct {
+	client *mcp.Client
+	state  string
+	err    string
+	tools  []string
+}
+
+// mcpSecretValues holds the values of a server's Env and Headers.
+type mcpSecretValues struct {
+	Env     map[string]string `json:"env,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// GetMCPServers lists the configured MCP servers by name.
+func (a *App) GetMCPServers() []MCPServerStatus {
+	a.configMutex.RLock()
+	names := slices.Sorted(maps.Keys(a.mcpServerConfigs))
+	a.configMutex.RUnlock()
+
+	servers := make([]MCPServerStatus, 0, len(names))
+	for _, name := range names {
+		servers = append(servers, a.mcpServerStatus(name))
+	}
+	return servers
+}
+
+// AddMCPServer adds an MCP server, or replaces the one with that name, and
+// connects to it when enabled. An empty Env or Headers value keeps the value
+// saved before. Connection failures are reported in the returned status.
+func (a *App) AddMCPServer(name string, config MCPServerConfig) (MCPServerStatus, error) {
+	if !mcpServerNamePattern.MatchString(name) {
+		return MCPServerStatus{}, fmt.Errorf("invalid server name %q; use up to 32 letters, digits, _ or -", name)
+	}
+	switch config.Transport {
+	case MCPTransportStdio:
+		if strings.TrimSpace(config.Command) == "" {
+			return MCPServerStatus{}, fmt.Errorf("a command is required for a stdio server")
+		}
+		config.URL, config.Headers = "", nil
+	case MCPTransportHTTP:
+		if !strings.HasPrefix(config.URL, "http://") && !strings.HasPrefix(config.URL, "https://") {
+			return MCPServerStatus{}, fmt.Errorf("an http:// or https:// URL is required for an HTTP server")
+		}
+		config.Command, config.Args, config.Dir, config.Env = "", nil, "", nil
+	default:
+		return MCPServerStatus{}, fmt.Errorf("unsupported transport %q; use %s or %s", config.Transport, MCPTransportStdio, MCPTransportHTTP)
+	}
+
+	if len(config.Env) == 0 && len(config.Headers) == 0 {
+		a.deleteMCPSecrets(name)
+	} else {
+		saved, err := a.mcpSecrets(name)
+		if err != nil {
+			return MCPServerStatus{}, err
+		}
+		values := mcpSecretValues{Env: mergeSecretValues(config.Env, saved.Env), Headers: mergeSecretValues(config.Headers, saved.Headers)}
+		if err := a.storeMCPSecrets(name, values); err != nil {
+			return MCPServerStatus{}, err
+		}
+	}
+	config.Env = secretNames(config.Env)
+	config.Headers = secretNames(config.Headers)
+
+	a.configMutex.Lock()
+	a.mcpServerConfigs[name] = config
+	a.configMutex.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return MCPServerStatus{}, err
+	}
+	return a.connectMCPServer(name), nil
+}
+
+// RemoveMCPServer disconnects an MCP server and deletes it with its secrets.
+func (a *App) RemoveMCPServer(name string) error {
+	a.configMutex.Lock()
+	_, ok := a.mcpServerConfigs[name]
+	delete(a.mcpServerConfigs, name)
+	a.configMutex.Unlock()
+	if !ok {
+		return fmt.Errorf("MCP server %s not found", name)
+	}
+	a.disconnectMCPServer(name)
+	a.deleteMCPSecrets(name)
+	return a.saveConfig()
+}
+
+// SetMCPServerEnabled connects to or disconnects from an MCP server and
+// remembers the choice. Enabling an enabled server reconnects it.
+func (a *App) SetMCPServerEnabled(name string, enabled bool) (MCPServerStatus, error) {
+	a.configMutex.Lock()
+	config, ok := a.mcpServerConfigs[name]
+	if ok {
+		config.Enabled = enabled
+		a.mcpServerConfigs[name] = config
+	}
+	a.configMutex.Unlock()
+	if !ok {
+		return MCPServerStatus{}, fmt.Errorf("MCP server %s not found", name)
+	}
+	if err := a.saveConfig(); err != nil {
+		return MCPServerStatus{}, err
+	}
+	return a.connectMCPServer(name), nil
+}
+
+// InspectMCPServer lists the tools, resources and prompts of a connected
+// MCP server.
+func (a *App) InspectMCPServer(name string) (MCPServerDetails, error) {
+	details := MCPServerDetails{MCPServerStatus: a.mcpServerStatus(name)}
+	var client *mcp.Client
+	a.mcpMutex.Lock()
+	if conn := a.mcpServers[name]; conn != nil {
+		client = conn.client
+	}
+	a.mcpMutex.Unlock()
+	if client == nil || details.State != MCPStateConnected {
+		if details.Error != "" {
+			return details, fmt.Errorf("MCP server %s is not connected: %s", name, details.Error)
+		}
+		return details, fmt.Errorf("MCP server %s is not connected", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpListTimeout)
+	defer cancel()
+	info := client.Info()
+	var err error
+	if info.Tools {
+		if details.Tools, err = client.ListTools(ctx); err != nil {
+			return details, err
+		}
+	}
+	if info.Resources {
+		if details.Resources, err = client.ListResources(ctx); err != nil {
+			return details, err
+		}
+	}
+	if info.Prompts {
+		if details.Prompts, err = client.ListPrompts(ctx); err != nil {
+			return details, err
+		}
+	}
+	return details, nil
+}
+
+// mcpServerStatus reports on a configured server.
+func (a *App) mcpServerStatus(name string) MCPServerStatus {
+	a.configMutex.RLock()
+	config := a.mcpServerConfigs[name]
+	a.configMutex.RUnlock()
+	config.Args = slices.Clone(config.Args)
+	config.Env = maps.Clone(config.Env)
+	config.Headers = maps.Clone(config.Headers)
+
+	status := MCPServerStatus{Name: name, Config: config, State: MCPStateDisabled}
+	a.mcpMutex.Lock()
+	defer a.mcpMutex.Unlock()
+	if conn := a.mcpServers[name]; conn != nil {
+		status.State = conn.state
+		status.Error = conn.err
+		status.ToolNames = slices.Clone(conn.tools)
+		if conn.client != nil {
+			info := conn.client.Info()
+			status.Server = &info
+		}
+	}
+	return status
+}
+
+// connectMCPServers connects to every enabled MCP server, in parallel, and
+// returns once each has connected or failed.
+func (a *App) connectMCPServers() {
+	a.configMutex.RLock()
+	var names []string
+	for name, config := range a.mcpServerConfigs {
+		if config.Enabled {
+			names = append(names, name)
+		}
+	}
+	a.configMutex.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status := a.connectMCPServer(name); status.State == MCPStateError {
+				fmt.Fprintf(os.Stderr, "Warning: MCP server %s: %s\n", name, status.Error)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// closeMCPServers disconnects every MCP server.
+func (a *App) closeMCPServers() {
+	a.mcpMutex.Lock()
+	names := slices.Collect(maps.Keys(a.mcpServers))
+	a.mcpMutex.Unlock()
+	for _, name := range names {
+		a.disconnectMCPServer(name)
+	}
+}
+
+// connectMCPServer (re)connects to a server if it is enabled and registers
+// its tools, returning its status.
+func (a *App) connectMCPServer(name string) MCPServerStatus {
+	a.configMutex.RLock()
+	config, ok := a.mcpServerConfigs[name]
+	a.configMutex.RUnlock()
+	a.disconnectMCPServer(name)
+	if !ok || !config.Enabled {
+		a.emitMCPStatus(name)
+		return a.mcpServerStatus(name)
+	}
+
+	conn := &mcpConnection{state: MCPStateConnecting}
+	a.mcpMutex.Lock()
+	a.mcpServers[name] = conn
+	a.mcpMutex.Unlock()
+	a.emitMCPStatus(name)
+
+	client, err := a.openMCPClient(name, config, conn)
+	if err == nil {
+		a.mcpMutex.Lock()
+		current := a.mcpServers[name] == conn
+		if current {
+			conn.client = client
+		}
+		a.mcpMutex.Unlock()
+		if !current {
+			client.Close()
+			return a.mcpServerStatus(name)
+		}
+		err = a.registerMCPTools(name, conn)
+	}
+
+	a.mcpMutex.Lock()
+	if a.mcpServers[name] == conn {
+		if err != nil {
+			conn.state = MCPStateError
+			conn.err = err.Error()
+		} else {
+			conn.state = MCPStateConnected
+		}
+	}
+	a.mcpMutex.Unlock()
+	if err == nil {
+		go a.watchMCPServer(name, conn)
+	}
+	a.emitMCPStatus(name)
+	return a.mcpServerStatus(name)
+}
+
+// openMCPClient starts or connects to a server with its secrets.
+func (a *App) openMCPClient(name string, config MCPServerConfig, conn *mcpConnection) (*mcp.Client, error) {
+	var values mcpSecretValues
+	if len(config.Env) > 0 || len(config.Headers) > 0 {
+		var err error
+		if values, err = a.mcpSecrets(name); err != nil {
+			return nil, err
+		}
+	}
+	a.configMutex.RLock()
+	options := mcp.Options{
+		ClientInfo: mcp.Implementation{Name: "lumen", Version: a.appInfo.Version},
+		OnToolsChanged: func() {
+			if err := a.registerMCPTools(name, conn); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to update the tools of MCP server %s: %v\n", name, err)
+			}
+			a.emitMCPStatus(name)
+		},
+	}
+	a.configMutex.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpConnectTimeout)
+	defer cancel()
+	if config.Transport == MCPTransportHTTP {
+		return mcp.ConnectHTTP(ctx, mcp.HTTPServer{URL: config.URL, Headers: values.Headers}, options)
+	}
+	server := mcp.StdioServer{Command: config.Command, Args: config.Args, Dir: config.Dir}
+	for _, key := range slices.Sorted(maps.Keys(values.Env)) {
+		server.Env = append(server.Env, key+"="+values.Env[key])
+	}
+	return mcp.ConnectStdio(ctx, server, options)
+}
+
+// registerMCPTools offers a connected server's tools to models, replacing
+// those it offered before.
+func (a *App) registerMCPTools(name string, conn *mcpConnection) error {
+	a.mcpMutex.Lock()
+	client := conn.client
+	a.mcpMutex.Unlock()
+	if client == nil || !client.Info().Tools {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mcpListTimeout)
+	defer cancel()
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.mcpMutex.Lock()
+	defer a.mcpMutex.Unlock()
+	if a.mcpServers[name] != conn {
+		return nil
+	}
+	for _, registered := range conn.tools {
+		a.withdrawMCPTool(registered)
+	}
+	conn.tools = nil
+	for _, tool := range tools {
+		description := tool.Description
+		if description == "" {
+			description = tool.Title
+		}
+		registered := connectors.Tool{
+			Name:        mcpToolName(name, tool.Name),
+			Description: strings.TrimSpace(fmt.Sprintf("[%s] %s", name, description)),
+			Parameters:  tool.InputSchema,
+		}
+		if err := a.offerMCPTool(registered, mcpToolHandler(client, tool.Name)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipped tool %s of MCP server %s: %v\n", tool.Name, name, err)
+			continue
+		}
+		conn.tools = append(conn.tools, registered.Name)
+	}
+	return nil
+}
+
+// watchMCPServer marks a server failed and withdraws its tools when its
+// connection ends without being closed, e.g. because the process exited.
+func (a *App) watchMCPServer(name string, conn *mcpConnection) {
+	<-conn.client.Done()
+	err := conn.client.Err()
+	if errors.Is(err, mcp.ErrClosed) {
+		return
+	}
+	a.mcpMutex.Lock()
+	if a.mcpServers[name] == conn {
+		for _, registered := range conn.tools {
+			a.withdrawMCPTool(registered)
+		}
+		conn.tools = nil
+		conn.state = MCPStateError
+		conn.err = err.Error()
+	}
+	a.mcpMutex.Unlock()
+	a.emitMCPStatus(name)
+}
+
+// disconnectMCPServer withdraws a server's tools and closes its connection.
+func (a *App) disconnectMCPServer(name string) {
+	a.mcpMutex.Lock()
+	conn := a.mcpServers[name]
+	delete(a.mcpServers, name)
+	if conn != nil {
+		for _, registered := range conn.tools {
+			a.withdrawMCPTool(registered)
+		}
+	}
+	a.mcpMutex.Unlock()
+	if conn != nil && conn.client != nil {
+		conn.client.Close()
+	}
+}
+
+// offerMCPTool registers a server's tool with the other tools and with the
+// MCP tools offered by ChatWithModel.
+func (a *App) offerMCPTool(tool connectors.Tool, handler connectors.ToolHandler) error {
+	if err := a.tools.Register(tool, handler); err != nil {
+		return err
+	}
+	if err := a.mcpTools.Register(tool, handler); err != nil {
+		a.tools.Unregister(tool.Name)
+		return err
+	}
+	return nil
+}
+
+// withdrawMCPTool removes a tool registered by offerMCPTool.
+func (a *App) withdrawMCPTool(name string) {
+	a.tools.Unregister(name)
+	a.mcpTools.Unregister(name)
+}
+
+func (a *App) emitMCPStatus(name string) {
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, MCPStatusEvent, a.mcpServerStatus(name))
+	}
+}
+
+// mcpToolName is the name a server's tool is offered to models under:
+// server__tool, cut to the 64 characters providers allow.
+func mcpToolName(server string, tool string) string {
+	name := server + "__" + mcpInvalidToolChars.ReplaceAllString(tool, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// mcpToolHandler calls a server's tool. Failures the tool reports are
+// returned to the model as errors.
+func mcpToolHandler(client *mcp.Client, tool string) connectors.ToolHandler {
+	return func(ctx context.Context, arguments json.RawMessage) (string, error) {
+		result, err := client.CallTool(ctx, tool, arguments)
+		if err != nil {
+			return "", err
+		}
+		if result.IsError {
+			return "", fmt.Errorf("%s", result.Text())
+		}
+		return result.Text(), nil
+	}
+}
+
+// mcpSecrets reads the Env and Headers values of a server.
+func (a *App) mcpSecrets(name string) (mcpSecretValues, error) {
+	var values mcpSecretValues
+	store, err := a.secretStore()
+	if err != nil {
+		return values, err
+	}
+	data, err := store.Get(mcpServerSecret(name))
+	if errors.Is(err, secrets.ErrNotFound) {
+		return values, nil
+	}
+	if err != nil {
+		return values, fmt.Errorf("failed to read the secrets of MCP server %s: %w", name, err)
+	}
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		return values, fmt.Errorf("invalid secrets for MCP server %s: %v", name, err)
+	}
+	return values, nil
+}
+
+// storeMCPSecrets saves the Env and Headers values of a server.
+func (a *App) storeMCPSecrets(name string, values mcpSecretValues) error {
+	store, err := a.secretStore()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if err := store.Set(mcpServerSecret(name), string(data)); err != nil {
+		return fmt.Errorf("failed to save the secrets of MCP server %s: %w", name, err)
+	}
+	return nil
+}
+
+// deleteMCPSecrets removes the saved values of a server, if there are any.
+func (a *App) deleteMCPSecrets(name string) {
+	store, err := a.secretStore()
+	if err != nil {
+		return
+	}
+	if err := store.Delete(mcpServerSecret(name)); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "Warning: could not remove the secrets of MCP server %s: %v\n", name, err)
+	}
+}
+
+// mergeSecretValues takes the values given, keeping the saved value of a
+// name given with an empty one.
+func mergeSecretValues(given map[string]string, saved map[string]string) map[string]string {
+	if len(given) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(given))
+	for name, value := range given {
+		if value == "" {
+			value = saved[name]
+		}
+		merged[name] = value
+	}
+	return merged
+}
+
+// secretNames keeps the names of a map of secret values.
+func secretNames(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	names := make(map[string]string, len(values))
+	for name := range values {
+		names[name] = ""
+	}
+	return names
+}
@@ -0,0 +1,277 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by calls on a closed client.
+var ErrClosed = errors.New("connection closed")
+
+// maxListPages bounds the pages read from a paginated list.
+const maxListPages = 100
+
+// replyTimeout bounds how long answering a server's request may take.
+const replyTimeout = 10 * time.Second
+
+// Options configure a client.
+type Options struct {
+	// ClientInfo identifies the client to servers.
+	ClientInfo Implementation
+	// OnToolsChanged is called when the server reports that its tools changed.
+	OnToolsChanged func()
+}
+
+// transport carries messages to a server. Messages from the server are
+// handed to the client's receive method.
+type transport interface {
+	send(ctx context.Context, msg *message) error
+	close() error
+}
+
+// Client is a connection to an MCP server. It is safe for concurrent use.
+type Client struct {
+	transport transport
+	options   Options
+	info      ServerInfo
+	nextID    atomic.Int64
+
+	mu      sync.Mutex
+	pending map[string]chan *message
+	err     error
+	done    chan struct{}
+
+	closeOnce sync.Once
+}
+
+func newClient(t transport, options Options) *Client {
+	return &Client{
+		transport: t,
+		options:   options,
+		pending:   make(map[string]chan *message),
+		done:      make(chan struct{}),
+	}
+}
+
+// initialize performs the protocol handshake.
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      c.options.ClientInfo,
+	}
+	var result initializeResult
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	c.info = ServerInfo{
+		Name:            result.ServerInfo.Name,
+		Version:         result.ServerInfo.Version,
+		ProtocolVersion: result.ProtocolVersion,
+		Instructions:    result.Instructions,
+	}
+	_, c.info.Tools = result.Capabilities["tools"]
+	_, c.info.Resources = result.Capabilities["resources"]
+	_, c.info.Prompts = result.Capabilities["prompts"]
+	if t, ok := c.transport.(*httpTransport); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// Info describes the server.
+func (c *Client) Info() ServerInfo {
+	return c.info
+}
+
+// Done is closed when the connection ends; Err then tells why.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, or nil while it is open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close ends the connection, stopping a stdio server.
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.fail(ErrClosed)
+		err = c.transport.close()
+	})
+	return err
+}
+
+// fail ends the connection with err, failing pending calls.
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+// ListTools lists the server's tools.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	return listAll[Tool](ctx, c, "tools/list", "tools")
+}
+
+// ListResources lists the server's resources.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	return listAll[Resource](ctx, c, "resources/list", "resources")
+}
+
+// ListPrompts lists the server's prompts.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	return listAll[Prompt](ctx, c, "prompts/list", "prompts")
+}
+
+// CallTool calls a tool with a JSON object of arguments.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	params := map[string]interface{}{"name": name, "arguments": arguments}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// listAll reads every page of a paginated list.
+func listAll[T any](ctx context.Context, c *Client, method string, field string) ([]T, error) {
+	var items []T
+	cursor := ""
+	for page := 0; page < maxListPages; page++ {
+		var params interface{}
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		var result map[string]json.RawMessage
+		if err := c.call(ctx, method, params, &result); err != nil {
+			return nil, err
+		}
+		var pageItems []T
+		if raw, ok := result[field]; ok {
+			if err := json.Unmarshal(raw, &pageItems); err != nil {
+				return nil, fmt.Errorf("invalid %s result: %v", method, err)
+			}
+		}
+		items = append(items, pageItems...)
+
+		cursor = ""
+		if raw, ok := result["nextCursor"]; ok {
+			json.Unmarshal(raw, &cursor)
+		}
+		if cursor == "" {
+			return items, nil
+		}
+	}
+	return items, nil
+}
+
+// call sends a request and decodes its result into result.
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	msg := &message{JSONRPC: "2.0", ID: json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10)), Method: method}
+	if params != nil {
+		var err error
+		if msg.Params, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+
+	reply := make(chan *message, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.pending[string(msg.ID)] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, string(msg.ID))
+		c.mu.Unlock()
+	}()
+
+	if err := c.transport.send(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	select {
+	case response := <-reply:
+		if response.Error != nil {
+			return fmt.Errorf("%s: %w", method, response.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf("invalid %s result: %v", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		cancelCtx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+		defer cancel()
+		c.notify(cancelCtx, "notifications/cancelled", map[string]interface{}{"requestId": msg.ID, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	case <-c.done:
+		return c.Err()
+	}
+}
+
+// notify sends a notification.
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	msg := &message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		var err error
+		if msg.Params, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+	return c.transport.send(ctx, msg)
+}
+
+// receive handles a message from the server: a response to a pending call,
+// a request to answer, or a notification.
+func (c *Client) receive(msg *message) {
+	switch {
+	case msg.Method == "" && len(msg.ID) > 0:
+		c.mu.Lock()
+		reply, ok := c.pending[string(msg.ID)]
+		c.mu.Unlock()
+		if ok {
+			reply <- msg
+		}
+	case len(msg.ID) > 0:
+		go c.reply(msg)
+	case msg.Method == "notifications/tools/list_changed":
+		if c.options.OnToolsChanged != nil {
+			go c.options.OnToolsChanged()
+		}
+	}
+}
+
+// reply answers a request from the server. Only ping is supported, since the
+// client declares no capabilities.
+func (c *Client) reply(request *message) {
+	response := &message{JSONRPC: "2.0", ID: request.ID}
+	if request.Method == "ping" {
+		response.Result = json.RawMessage("{}")
+	} else {
+		response.Error = &RPCError{Code: codeMethodNotFound, Message: "method not found: " + request.Method}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+	c.transport.send(ctx, response)
+}
@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits on responses from HTTP servers.
+const (
+	maxHTTPResponseBytes = 16 << 20
+	maxHTTPErrorBytes    = 1 << 10
+)
+
+// sessionCloseTimeout bounds the request that ends an HTTP session.
+const sessionCloseTimeout = 5 * time.Second
+
+// HTTPServer is a server reached over streamable HTTP.
+type HTTPServer struct {
+	URL string
+	// Headers are added to every request, e.g. Authorization.
+	Headers map[string]string
+}
+
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	c       *Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+// ConnectHTTP connects to a server and performs the handshake.
+func ConnectHTTP(ctx context.Context, server HTTPServer, options Options) (*Client, error) {
+	parsed, err := url.Parse(server.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", server.URL)
+	}
+	t := &httpTransport{url: server.URL, headers: server.Headers, client: &http.Client{}}
+	c := newClient(t, options)
+	t.c = c
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
+// setHeaders adds the configured headers and those of the session.
+func (t *httpTransport) setHeaders(req *http.Request) {
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+}
+
+// send posts a message. The server answers a request in the response body,
+// either as JSON or as an event stream that may carry its own requests and
+// notifications first.
+func (t *httpTransport) send(ctx context.Context, msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHTTPErrorBytes))
+		if resp.StatusCode == http.StatusNotFound && req.Header.Get("Mcp-Session-Id") != "" {
+			return fmt.Errorf("the server ended the session; reconnect to start a new one")
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	isRequest := msg.Method != "" && len(msg.ID) > 0
+	answered := false
+	deliver := func(data []byte) {
+		var received message
+		if json.Unmarshal(data, &received) != nil {
+			return
+		}
+		if received.Method == "" && string(received.ID) == string(msg.ID) {
+			answered = true
+		}
+		t.c.receive(&received)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		err = readEvents(io.LimitReader(resp.Body, maxHTTPResponseBytes), func(data []byte) bool {
+			deliver(data)
+			return !answered
+		})
+	case "application/json":
+		var body []byte
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseBytes))
+		if err == nil {
+			deliver(body)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if isRequest && !answered {
+		return fmt.Errorf("the server sent no response")
+	}
+	return nil
+}
+
+// readEvents reads server-sent events, passing the data of each to handle
+// until it returns false.
+func readEvents(body io.Reader, handle func(data []byte) bool) error {
+	reader := bufio.NewReader(body)
+	var data []byte
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "" && len(data) > 0:
+			if !handle(data) {
+				return nil
+			}
+			data = nil
+		case strings.HasPrefix(line, "data:"):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+		if err == io.EOF {
+			if len(data) > 0 {
+				handle(data)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// close ends the session, if the server started one.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionCloseTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+	if resp, err := t.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a stdio server gets to exit after its stdin is closed, and then
+// after it is killed.
+const (
+	stdioExitTimeout = 2 * time.Second
+	stdioKillTimeout = 2 * time.Second
+)
+
+// maxStdioLineBytes bounds a line of a stdio server's output, and so the
+// size of one message.
+const maxStdioLineBytes = maxHTTPResponseBytes
+
+// maxStderrTail is how much of a stdio server's stderr is kept to explain
+// why it exited.
+const maxStderrTail = 4 << 10
+
+// StdioServer is a server launched as a subprocess that speaks the protocol
+// over its stdin and stdout.
+type StdioServer struct {
+	Command string
+	Args    []string
+	// Env holds KEY=VALUE entries added to Lumen's environment.
+	Env []string
+	Dir string
+}
+
+type stdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	stderr  *tailBuffer
+	exited  chan struct{}
+}
+
+// ConnectStdio launches a server and performs the handshake. The server runs
+// until the client is closed.
+func ConnectStdio(ctx context.Context, server StdioServer, options Options) (*Client, error) {
+	if strings.TrimSpace(server.Command) == "" {
+		return nil, fmt.Errorf("a command is required")
+	}
+	cmd := exec.Command(server.Command, server.Args...)
+	cmd.Env = append(os.Environ(), server.Env...)
+	cmd.Dir = server.Dir
+	cmd.WaitDelay = stdioKillTimeout
+	t := &stdioTransport{cmd: cmd, stderr: &tailBuffer{}, exited: make(chan struct{})}
+	cmd.Stderr = t.stderr
+	var err error
+	if t.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", server.Command, err)
+	}
+
+	c := newClient(t, options)
+	go t.read(c, stdout)
+	if err := c.initialize(ctx); err != nil {
+		if exitErr := c.Err(); exitErr != nil && exitErr != ErrClosed {
+			err = exitErr
+		}
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// read passes the server's messages to the client until it exits. Lines
+// that are not JSON, such as stray logging, are skipped. A line longer than
+// maxStdioLineBytes stops the server.
+func (t *stdioTransport) read(c *Client, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxStdioLineBytes)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 && line[0] == '{' {
+			var msg message
+			if json.Unmarshal(line, &msg) == nil {
+				c.receive(&msg)
+			}
+		}
+	}
+	tooLong := errors.Is(scanner.Err(), bufio.ErrTooLong)
+	if tooLong {
+		t.cmd.Process.Kill()
+	}
+	waitErr := t.cmd.Wait()
+	close(t.exited)
+	if tooLong {
+		c.fail(fmt.Errorf("server sent a line longer than %d bytes", maxStdioLineBytes))
+		return
+	}
+
+	reason := "server exited"
+	if waitErr != nil {
+		reason += ": " + waitErr.Error()
+	}
+	if tail := t.stderr.lastLine(); tail != "" {
+		reason += ": " + tail
+	}
+	c.fail(fmt.Errorf("%s", reason))
+}
+
+func (t *stdioTransport) send(ctx context.Context, msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	select {
+	case <-t.exited:
+		return fmt.Errorf("server exited")
+	default:
+	}
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// close closes the server's stdin and waits for it to exit, killing it if
+// it does not.
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.exited:
+		return nil
+	case <-time.After(stdioExitTimeout):
+	}
+	t.cmd.Process.Kill()
+	select {
+	case <-t.exited:
+	case <-time.After(stdioKillTimeout):
+	}
+	return nil
+}
+
+// tailBuffer keeps the end of what is written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > maxStderrTail {
+		b.buf = b.buf[len(b.buf)-maxStderrTail:]
+	}
+	return len(p), nil
+}
+
+// lastLine returns the last non-empty line written.
+func (b *tailBuffer) lastLine() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := strings.Split(strings.TrimSpace(string(b.buf)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"myproject/connectors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Events emitted to the frontend while an Ollama model is being pulled.
+const (
+	OllamaPullProgressEvent = "ollama:pull:progress"
+	OllamaPullDoneEvent     = "ollama:pull:done"
+)
+
+// pullProgressInterval throttles progress events; Ollama reports far more often.
+const pullProgressInterval = 250 * time.Millisecond
+
+// OllamaPullProgress reports the state of a running pull.
+type OllamaPullProgress struct {
+	PullID    string `json:"pull_id"`
+	Model     string `json:"model"`
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	// Percent of the current layer, or -1 when Ollama reports no byte counts.
+	Percent float64 `json:"percent"`
+}
+
+// OllamaPullDone is emitted once per pull with its outcome.
+type OllamaPullDone struct {
+	PullID    string `json:"pull_id"`
+	Model     string `json:"model"`
+	Error     string `json:"error,omitempty"`
+	Cancelled bool   `json:"cancelled,omitempty"`
+}
+
+// PullOllamaModel starts downloading a model and returns a pull ID at once.
+// Progress arrives through OllamaPullProgressEvent and the outcome through
+// OllamaPullDoneEvent; CancelGeneration with the pull ID stops it.
+func (a *App) PullOllamaModel(model string) (string, error) {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return "", fmt.Errorf("model name is required")
+	}
+	ollama, err := a.ollamaConnector()
+	if err != nil {
+		return "", err
+	}
+
+	pullID, err := newGenerationID()
+	if err != nil {
+		return "", err
+	}
+	ctx, finish, err := a.beginGeneration(pullID)
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		defer finish()
+		err := a.pullOllamaModel(ctx, ollama, model, func(progress OllamaPullProgress) {
+			progress.PullID = pullID
+			runtime.EventsEmit(a.ctx, OllamaPullProgressEvent, progress)
+		})
+
+		done := OllamaPullDone{PullID: pullID, Model: model}
+		if ctx.Err() != nil {
+			done.Cancelled = true
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Pulling %s failed: %v\n", model, err)
+			done.Error = err.Error()
+		}
+		runtime.EventsEmit(a.ctx, OllamaPullDoneEvent, done)
+	}()
+
+	return pullID, nil
+}
+
+// DeleteOllamaModel removes a model from Ollama to free disk space.
+func (a *App) DeleteOllamaModel(model string) error {
+	if strings.TrimSpace(model) == "" {
+		return fmt.Errorf("model name is required")
+	}
+	ollama, err := a.ollamaConnector()
+	if err != nil {
+		return err
+	}
+	defer a.modelCache.Invalidate("ollama")
+	return ollama.DeleteModel(context.Background(), model)
+}
+
+// CopyOllamaModel copies a model under a new name.
+func (a *App) CopyOllamaModel(source string, destination string) error {
+	if strings.TrimSpace(source) == "" || strings.TrimSpace(destination) == "" {
+		return fmt.Errorf("source and destination are required")
+	}
+	ollama, err := a.ollamaConnector()
+	if err != nil {
+		return err
+	}
+	defer a.modelCache.Invalidate("ollama")
+	return ollama.CopyModel(context.Background(), source, strings.TrimSpace(destination))
+}
+
+// ShowOllamaModel returns a model's modelfile, default parameters, prompt
+// template and license.
+func (a *App) ShowOllamaModel(model string) (connectors.OllamaModelInfo, error) {
+	if strings.TrimSpace(model) == "" {
+		return connectors.OllamaModelInfo{}, fmt.Errorf("model name is required")
+	}
+	ollama, err := a.ollamaConnector()
+	if err != nil {
+		return connectors.OllamaModelInfo{}, err
+	}
+	return ollama.ShowModel(context.Background(), model)
+}
+
+// pullOllamaModel runs a pull, passing throttled progress to onProgress. The
+// first update, every status change and the final update always get through.
+func (a *App) pullOllamaModel(ctx context.Context, ollama *connectors.OllamaConnector, model string, onProgress func(OllamaPullProgress)) error {
+	defer a.modelCache.Invalidate("ollama")
+
+	var lastStatus string
+	var lastSent time.Time
+	return ollama.PullModel(ctx, model, func(p connectors.OllamaPullProgress) {
+		finished := p.Total > 0 && p.Completed >= p.Total
+		if p.Status == lastStatus && !finished && time.Since(lastSent) < pullProgressInterval {
+			return
+		}
+		lastStatus, lastSent = p.Status, time.Now()
+
+		progress := OllamaPullProgress{
+			Model:     model,
+			Status:    p.Status,
+			Digest:    p.Digest,
+			Total:     p.Total,
+			Completed: p.Completed,
+			Percent:   -1,
+		}
+		if p.Total > 0 {
+			progress.Percent = float64(p.Completed) * 100 / float64(p.Total)
+		}
+		onProgress(progress)
+	})
+}
+
+// ollamaConnector returns the Ollama provider at its configured endpoint.
+func (a *App) ollamaConnector() (*connectors.OllamaConnector, error) {
+	p, err := a.newProvider("ollama")
+	if err != nil {
+		return nil, err
+	}
+	ollama, ok := p.(*connectors.OllamaConnector)
+	if !ok {
+		return nil, fmt.Errorf("model management is only available for Ollama")
+	}
+	return ollama, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"myproject/connectors"
+	"myproject/conversations"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Preset is a named, reusable system prompt.
+type Preset struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	SystemPrompt string    `json:"system_prompt"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ListPresets returns all system-prompt presets sorted by name.
+func (a *App) ListPresets() []Preset {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+
+	list := make([]Preset, 0, len(a.presets))
+	for _, preset := range a.presets {
+		list = append(list, preset)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list
+}
+
+// CreatePreset saves a new system-prompt preset.
+func (a *App) CreatePreset(name string, systemPrompt string) (Preset, error) {
+	id, err := newGenerationID()
+	if err != nil {
+		return Preset{}, err
+	}
+	now := time.Now()
+	preset := Preset{
+		ID:           id,
+		Name:         strings.TrimSpace(name),
+		SystemPrompt: strings.TrimSpace(systemPrompt),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := a.putPreset(preset); err != nil {
+		return Preset{}, err
+	}
+	return preset, nil
+}
+
+// UpdatePreset changes a preset's name and system prompt.
+func (a *App) UpdatePreset(id string, name string, systemPrompt string) (Preset, error) {
+	a.configMutex.RLock()
+	preset, ok := a.presets[id]
+	a.configMutex.RUnlock()
+	if !ok {
+		return Preset{}, fmt.Errorf("preset %s not found", id)
+	}
+
+	preset.Name = strings.TrimSpace(name)
+	preset.SystemPrompt = strings.TrimSpace(systemPrompt)
+	preset.UpdatedAt = time.Now()
+	if err := a.putPreset(preset); err != nil {
+		return Preset{}, err
+	}
+	return preset, nil
+}
+
+// DeletePreset removes a preset and detaches it from any models. Conversations
+// still referring to it fall back to their model's preset.
+func (a *App) DeletePreset(id string) error {
+	a.configMutex.Lock()
+	if _, ok := a.presets[id]; !ok {
+		a.configMutex.Unlock()
+		return fmt.Errorf("preset %s not found", id)
+	}
+	delete(a.presets, id)
+	for key, presetID := range a.modelPresets {
+		if presetID == id {
+			delete(a.modelPresets, key)
+		}
+	}
+	a.configMutex.Unlock()
+
+	return a.saveConfig()
+}
+
+// GetModelPresets returns the preset attached to each model, keyed by "provider/model".
+func (a *App) GetModelPresets() map[string]string {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+
+	attached := make(map[string]string, len(a.modelPresets))
+	for key, presetID := range a.modelPresets {
+		attached[key] = presetID
+	}
+	return attached
+}
+
+// SetModelPreset attaches a preset to a model. An empty presetID detaches it.
+func (a *App) SetModelPreset(provider string, model string, presetID string) error {
+	if provider == "" || model == "" {
+		return fmt.Errorf("provider and model are required")
+	}
+	key := fmt.Sprintf("%s/%s", provider, model)
+
+	a.configMutex.Lock()
+	if presetID == "" {
+		delete(a.modelPresets, key)
+	} else if _, ok := a.presets[presetID]; !ok {
+		a.configMutex.Unlock()
+		return fmt.Errorf("preset %s not found", presetID)
+	} else {
+		a.modelPresets[key] = presetID
+	}
+	a.configMutex.Unlock()
+
+	return a.saveConfig()
+}
+
+// SetConversationPreset attaches a preset to a saved conversation. An empty
+// presetID detaches it.
+func (a *App) SetConversationPreset(conversationID string, presetID string) (conversations.Conversation, error) {
+	store, err := a.requireConversationStore()
+	if err != nil {
+		return conversations.Conversation{}, err
+	}
+	if presetID != "" {
+		if _, ok := a.preset(presetID); !ok {
+			return conversations.Conversation{}, fmt.Errorf("preset %s not found", presetID)
+		}
+	}
+	return store.SetPreset(conversationID, presetID)
+}
+
+// putPreset validates and saves a preset, keeping names unique.
+func (a *App) putPreset(preset Preset) error {
+	if preset.Name == "" {
+		return fmt.Errorf("preset name cannot be empty")
+	}
+	if preset.SystemPrompt == "" {
+		return fmt.Errorf("system prompt cannot be empty")
+	}
+
+	a.configMutex.Lock()
+	for id, existing := range a.presets {
+		if id != preset.ID && strings.EqualFold(existing.Name, preset.Name) {
+			a.configMutex.Unlock()
+			return fmt.Errorf("a preset named %q already exists", preset.Name)
+		}
+	}
+	a.presets[preset.ID] = preset
+	a.configMutex.Unlock()
+
+	return a.saveConfig()
+}
+
+// preset looks up a preset by ID.
+func (a *App) preset(id string) (Preset, bool) {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	preset, ok := a.presets[id]
+	return preset, ok
+}
+
+// findPreset looks up a preset by ID or, failing that, by name.
+func (a *App) findPreset(idOrName string) (Preset, bool) {
+	if preset, ok := a.preset(idOrName); ok {
+		return preset, true
+	}
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	for _, preset := range a.presets {
+		if strings.EqualFold(preset.Name, idOrName) {
+			return preset, true
+		}
+	}
+	return Preset{}, false
+}
+
+// applySystemPrompt prepends the request's preset as a system message. The
+// preset is the one named by PresetID, else the one attached to the
+// conversation, else the one attached to the model. Connectors move system
+// messages into the provider's dedicated field where there is one.
+func (a *App) applySystemPrompt(request ChatRequest) (ChatRequest, error) {
+	presetID := request.PresetID
+	if presetID != "" {
+		if _, ok := a.preset(presetID); !ok {
+			return request, fmt.Errorf("preset %s not found", presetID)
+		}
+	}
+	if presetID == "" && request.ConversationID != "" && a.conversationStore != nil {
+		if conv, err := a.conversationStore.Get(request.ConversationID); err == nil {
+			if _, ok := a.preset(conv.PresetID); ok {
+				presetID = conv.PresetID
+			}
+		}
+	}
+	if presetID == "" {
+		a.configMutex.RLock()
+		presetID = a.modelPresets[fmt.Sprintf("%s/%s", request.Provider, request.Model)]
+		a.configMutex.RUnlock()
+	}
+
+	preset, ok := a.preset(presetID)
+	if !ok {
+		return request, nil
+	}
+	messages := make([]connectors.ChatMessage, 0, len(request.Messages)+1)
+	messages = append(messages, connectors.ChatMessage{Role: connectors.RoleSystem, Content: preset.SystemPrompt})
+	request.Messages = append(messages, request.Messages...)
+	return request, nil
+}
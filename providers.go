@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"myproject/connectors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GetProviders lists every registered provider with its capabilities.
+func (a *App) GetProviders() []connectors.ProviderInfo {
+	return a.registry.List()
+}
+
+// modelCacheTTL is how long a provider's model list is reused for metadata lookups.
+const modelCacheTTL = 5 * time.Minute
+
+// GetModelInfo returns what is known about a model, such as its size,
+// context length, modalities and tool or vision support. It answers from the
+// cached model list, listing the provider's models again once it expires.
+func (a *App) GetModelInfo(provider string, model string) (connectors.Model, error) {
+	models, err := a.listModels(context.Background(), provider, false)
+	if err != nil {
+		return connectors.Model{}, err
+	}
+	for _, m := range models {
+		if m.Name == model {
+			return m, nil
+		}
+	}
+	return connectors.Model{}, fmt.Errorf("model %s not found for %s", model, provider)
+}
+
+// listModels lists a provider's models, serving a cached list unless refresh
+// is set or the list has expired. Fresh lists are cached.
+func (a *App) listModels(ctx context.Context, name string, refresh bool) ([]connectors.Model, error) {
+	if !refresh {
+		if models, ok := a.modelCache.Get(name); ok {
+			return models, nil
+		}
+	}
+	p, err := a.newProvider(name)
+	if err != nil {
+		return nil, err
+	}
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.modelCache.Put(name, models)
+	return models, nil
+}
+
+// newProvider builds a provider from the registry using the saved settings.
+func (a *App) newProvider(name string) (connectors.Provider, error) {
+	info, ok := a.registry.Info(name)
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", name)
+	}
+
+	settings := connectors.ProviderSettings{
+		Endpoint: a.providerEndpoint(name),
+	}
+	if info.Capabilities.RequiresAPIKey {
+		apiKey, err := a.GetAPIKey(name)
+		if err != nil {
+			return nil, err
+		}
+		settings.APIKey = apiKey
+	}
+	if err := a.customProviderSettings(name, &settings); err != nil {
+		return nil, err
+	}
+	return a.registry.New(name, settings)
+}
+
+// newCloudProvider builds a key-based provider with a caller-supplied key,
+// used to validate keys before they are saved.
+func (a *App) newCloudProvider(name string, apiKey string) (connectors.Provider, error) {
+	info, ok := a.registry.Info(name)
+	if !ok || !info.Capabilities.RequiresAPIKey {
+		return nil, fmt.Errorf("unsupported provider: %s", name)
+	}
+	return a.registry.New(name, connectors.ProviderSettings{APIKey: apiKey})
+}
+
+// GetProviderEndpoints returns the effective endpoint of every local or custom
+// provider, falling back to the provider's default when none is configured.
+func (a *App) GetProviderEndpoints() map[string]string {
+	endpoints := make(map[string]string)
+	for _, info := range a.registry.List() {
+		if a.hasEndpoint(info) {
+			endpoints[info.Name] = a.providerEndpoint(info.Name)
+		}
+	}
+	return endpoints
+}
+
+// TestProviderEndpoint checks that a local provider is reachable at the given
+// endpoint without saving it.
+func (a *App) TestProviderEndpoint(provider string, endpoint string) error {
+	_, err := a.checkProviderEndpoint(provider, endpoint)
+	return err
+}
+
+// SetProviderEndpoint validates, connectivity-checks and saves the endpoint
+// used for a local provider.
+func (a *App) SetProviderEndpoint(provider string, endpoint string) error {
+	normalized, err := a.checkProviderEndpoint(provider, endpoint)
+	if err != nil {
+		return err
+	}
+
+	a.configMutex.Lock()
+	a.providerEndpoints[provider] = normalized
+	a.configMutex.Unlock()
+	a.modelCache.Invalidate(provider)
+
+	return a.saveConfig()
+}
+
+// ResetProviderEndpoint reverts a local provider to its default endpoint.
+func (a *App) ResetProviderEndpoint(provider string) error {
+	if _, ok := a.registry.Info(provider); !ok {
+		return fmt.Errorf("unsupported provider: %s", provider)
+	}
+
+	a.configMutex.Lock()
+	delete(a.providerEndpoints, provider)
+	a.configMutex.Unlock()
+	a.modelCache.Invalidate(provider)
+
+	return a.saveConfig()
+}
+
+// providerEndpoint returns the configured endpoint for a provider, falling
+// back to the provider's default.
+func (a *App) providerEndpoint(name string) string {
+	a.configMutex.RLock()
+	endpoint := a.providerEndpoints[name]
+	a.configMutex.RUnlock()
+
+	if endpoint == "" {
+		if info, ok := a.registry.Info(name); ok {
+			return info.DefaultEndpoint
+		}
+	}
+	return endpoint
+}
+
+// hasEndpoint reports whether a provider's endpoint can be configured: local
+// providers and custom ones, which may be remote.
+func (a *App) hasEndpoint(info connectors.ProviderInfo) bool {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	return a.hasEndpointLocked(info)
+}
+
+// hasEndpointLocked is hasEndpoint for callers that hold configMutex.
+func (a *App) hasEndpointLocked(info connectors.ProviderInfo) bool {
+	_, custom := a.customProviderConfigs[info.Name]
+	return info.Capabilities.Local || custom
+}
+
+// checkProviderEndpoint validates an endpoint URL and runs the provider's
+// health check against it. It returns the normalized URL.
+func (a *App) checkProviderEndpoint(provider string, endpoint string) (string, error) {
+	info, ok := a.registry.Info(provider)
+	if !ok || !a.hasEndpoint(info) {
+		return "", fmt.Errorf("endpoint cannot be configured for provider: %s", provider)
+	}
+
+	normalized, err := normalizeEndpoint(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	settings := connectors.ProviderSettings{Endpoint: normalized}
+	if err := a.customProviderSettings(provider, &settings); err != nil {
+		return "", err
+	}
+	p, err := a.registry.New(provider, settings)
+	if err != nil {
+		return "", err
+	}
+	if err := p.HealthCheck(context.Background()); err != nil {
+		return "", fmt.Errorf("cannot reach %s at %s: %v", provider, normalized, err)
+	}
+	return normalized, nil
+}
+
+// normalizeEndpoint checks that an endpoint is an absolute http(s) URL and
+// strips any trailing slash so API paths can be appended directly.
+func normalizeEndpoint(endpoint string) (string, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint URL: %v", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("endpoint must start with http:// or https://")
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("endpoint must include a host")
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("endpoint must not include a query or fragment")
+	}
+	return strings.TrimRight(endpoint, "/"), nil
+}
+
+// generationConfig converts a saved ModelConfig to provider-neutral parameters.
+func (c ModelConfig) generationConfig() connectors.GenerationConfig {
+	return connectors.GenerationConfig{
+		Temperature:      c.Temperature,
+		TopP:             c.TopP,
+		TopK:             c.TopK,
+		RepeatPenalty:    c.RepeatPenalty,
+		NumCtx:           c.NumCtx,
+		Stop:             c.Stop,
+		MinP:             c.MinP,
+		PresencePenalty:  c.PresencePenalty,
+		FrequencyPenalty: c.FrequencyPenalty,
+		MaxOutputTokens:  c.MaxOutputTokens,
+		Seed:             c.Seed,
+	}
+}
+
+// validateModelConfig checks a model config against the provider's parameter
+// schema and, when the model's limits are in the model cache, against its
+// context length and output limit.
+func (a *App) validateModelConfig(provider string, model string, config ModelConfig) error {
+	info, ok := a.registry.Info(provider)
+	if !ok {
+		return fmt.Errorf("unsupported provider: %s", provider)
+	}
+	return a.checkModelConfig(info, model, config)
+}
+
+// checkModelConfig is validateModelConfig for a provider that is already
+// looked up, or not registered yet.
+func (a *App) checkModelConfig(info connectors.ProviderInfo, model string, config ModelConfig) error {
+	if err := connectors.ValidateConfig(info.Parameters, config.generationConfig()); err != nil {
+		return err
+	}
+
+	models, ok := a.modelCache.Get(info.Name)
+	if !ok {
+		return nil
+	}
+	for _, m := range models {
+		if m.Name != model {
+			continue
+		}
+		if m.MaxOutputTokens > 0 && config.MaxOutputTokens > m.MaxOutputTokens {
+			return fmt.Errorf("max_output_tokens exceeds the %d tokens %s can produce", m.MaxOutputTokens, model)
+		}
+		if m.ContextLength > 0 && config.NumCtx > m.ContextLength && supportsParam(info.Parameters, connectors.ParamNumCtx) {
+			return fmt.Errorf("num_ctx exceeds the %d token context of %s", m.ContextLength, model)
+		}
+	}
+	return nil
+}
+
+// supportsParam reports whether a parameter schema includes the named parameter.
+func supportsParam(specs []connectors.ParamSpec, name string) bool {
+	for _, spec := range specs {
+		if spec.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package rag
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Hit is an indexed chunk that matches a query. Score is the cosine
+// similarity between the chunk and the query, and Citation a short reference
+// to where the chunk came from, such as "src/main.go:10-42" or
+// "docs/spec.pdf (page 3)".
+type Hit struct {
+	Citation  string  `json:"citation"`
+	Root      string  `json:"root"`
+	Path      string  `json:"path"`
+	Source    string  `json:"source,omitempty"`
+	StartLine int     `json:"start_line,omitempty"`
+	EndLine   int     `json:"end_line,omitempty"`
+	Text      string  `json:"text"`
+	Score     float64 `json:"score"`
+}
+
+func (h Hit) cite() string {
+	switch {
+	case h.StartLine > 0 && h.EndLine > h.StartLine:
+		return fmt.Sprintf("%s:%d-%d", h.Path, h.StartLine, h.EndLine)
+	case h.StartLine > 0:
+		return fmt.Sprintf("%s:%d", h.Path, h.StartLine)
+	case h.Source != "":
+		return fmt.Sprintf("%s (%s)", h.Path, h.Source)
+	}
+	return h.Path
+}
+
+// Search returns the k chunks of the given folders most similar to the
+// query vector, best first. Only indexes built with the named embedding
+// model are searched, since vectors of other models are not comparable.
+func (s *Store) Search(roots []string, provider string, model string, query []float32, k int) ([]Hit, error) {
+	if k <= 0 || len(query) == 0 {
+		return nil, nil
+	}
+	query = normalize(query)
+
+	var hits []Hit
+	for _, root := range roots {
+		idx, err := s.open(root)
+		if err != nil {
+			return nil, err
+		}
+		idx.mu.RLock()
+		m := idx.manifest
+		if m.Provider == provider && m.Model == model && m.Dimensions == len(query) {
+			for path, file := range m.Files {
+				for _, chunk := range file.Chunks {
+					hits = append(hits, Hit{
+						Root:      m.Root,
+						Path:      path,
+						Source:    chunk.Source,
+						StartLine: chunk.StartLine,
+						EndLine:   chunk.EndLine,
+						Text:      chunk.Text,
+						Score:     dot(query, chunk.Vector),
+					})
+				}
+			}
+		}
+		idx.mu.RUnlock()
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Path != hits[j].Path {
+			return hits[i].Path < hits[j].Path
+		}
+		return hits[i].StartLine < hits[j].StartLine
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	for i := range hits {
+		hits[i].Citation = hits[i].cite()
+	}
+	return hits, nil
+}
+
+func dot(a []float32, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
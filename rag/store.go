@@ -0,0 +1,303 @@
+// Package rag keeps embedding indexes of local folders on disk so that chats
+// can draw on the files most relevant to a question.
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"myproject/internal/atomicfile"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// indexVersion is bumped when the on-disk format changes; older indexes are
+// rebuilt.
+const indexVersion = 1
+
+// vectorsMagic starts every vectors file.
+var vectorsMagic = []byte("LRAG")
+
+// EmbedFunc returns one embedding vector per input, in input order.
+type EmbedFunc func(ctx context.Context, inputs []string) ([][]float32, error)
+
+// Embedding names the model an index is built with and how to call it.
+// Vectors from different models are not comparable, so changing the model
+// rebuilds the index.
+type Embedding struct {
+	Provider string
+	Model    string
+	Embed    EmbedFunc
+}
+
+// Status describes the index of a folder.
+type Status struct {
+	Root     string `json:"root"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Files    int    `json:"files"`
+	Chunks   int    `json:"chunks"`
+	// UpdatedAt is zero for a folder that has not been indexed yet.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// manifest is the persisted description of an index. Chunk vectors are kept
+// separately in vectors.bin, in the order of the sorted file paths.
+type manifest struct {
+	Version    int                   `json:"version"`
+	Root       string                `json:"root"`
+	Provider   string                `json:"provider"`
+	Model      string                `json:"model"`
+	Dimensions int                   `json:"dimensions"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	Files      map[string]*fileEntry `json:"files"`
+}
+
+// fileEntry is an indexed file, keyed in the manifest by its slash-separated
+// path relative to the root. Files without text have no chunks but are kept
+// so they are not read again until they change.
+type fileEntry struct {
+	ModTime time.Time    `json:"mod_time"`
+	Size    int64        `json:"size"`
+	Chunks  []chunkEntry `json:"chunks,omitempty"`
+}
+
+// chunkEntry is an indexed piece of a file. Lines are set for text files and
+// Source for documents with several sections, such as PDF pages.
+type chunkEntry struct {
+	Source    string    `json:"source,omitempty"`
+	StartLine int       `json:"start_line,omitempty"`
+	EndLine   int       `json:"end_line,omitempty"`
+	Text      string    `json:"text"`
+	Vector    []float32 `json:"-"`
+}
+
+// index is a loaded folder index. updateMu serializes updates, which read
+// the current manifest and swap in a new one under mu when done.
+type index struct {
+	dir      string
+	updateMu sync.Mutex
+	mu       sync.RWMutex
+	manifest manifest
+}
+
+// Store keeps one index per folder under a directory, each in a
+// subdirectory named after a hash of the folder path.
+type Store struct {
+	dir string
+
+	mu      sync.Mutex
+	indexes map[string]*index
+}
+
+// NewStore creates a store rooted at dir. The directory is created on first write.
+func NewStore(dir string) *Store {
+	return &Store{
+		dir:     dir,
+		indexes: make(map[string]*index),
+	}
+}
+
+// Status describes a folder's index. A folder that has never been indexed
+// reports no files.
+func (s *Store) Status(root string) (Status, error) {
+	idx, err := s.open(root)
+	if err != nil {
+		return Status{}, err
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.manifest.status(), nil
+}
+
+// Remove deletes a folder's index, once any update of it has stopped.
+func (s *Store) Remove(root string) error {
+	root, err := cleanRoot(root)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	idx := s.indexes[root]
+	delete(s.indexes, root)
+	s.mu.Unlock()
+	if idx != nil {
+		// Wait for a running update, which would save the index again
+		idx.updateMu.Lock()
+		defer idx.updateMu.Unlock()
+	}
+	if err := os.RemoveAll(s.indexDir(root)); err != nil {
+		return fmt.Errorf("failed to delete index: %w", err)
+	}
+	return nil
+}
+
+// open returns a folder's index, loading it from disk on first use.
+func (s *Store) open(root string) (*index, error) {
+	root, err := cleanRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if idx, ok := s.indexes[root]; ok {
+		return idx, nil
+	}
+
+	idx := &index{dir: s.indexDir(root)}
+	idx.manifest, err = loadManifest(idx.dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: rebuilding index of %s: %v\n", root, err)
+	}
+	if err != nil || idx.manifest.Root != root {
+		idx.manifest = manifest{Version: indexVersion, Root: root, Files: map[string]*fileEntry{}}
+	}
+	s.indexes[root] = idx
+	return idx, nil
+}
+
+func (s *Store) indexDir(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8]))
+}
+
+// cleanRoot makes a folder path absolute so each folder has a single index.
+func cleanRoot(root string) (string, error) {
+	if root == "" {
+		return "", fmt.Errorf("a folder path is required")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("invalid folder path: %w", err)
+	}
+	return abs, nil
+}
+
+func (m manifest) status() Status {
+	status := Status{
+		Root:      m.Root,
+		Provider:  m.Provider,
+		Model:     m.Model,
+		Files:     len(m.Files),
+		UpdatedAt: m.UpdatedAt,
+	}
+	for _, file := range m.Files {
+		status.Chunks += len(file.Chunks)
+	}
+	return status
+}
+
+// sortedPaths lists the manifest's files in the order their vectors are stored.
+func (m manifest) sortedPaths() []string {
+	paths := make([]string, 0, len(m.Files))
+	for path := range m.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// loadManifest reads an index from disk. A missing index is empty; a
+// damaged or outdated one is an error, and the caller starts afresh.
+func loadManifest(dir string) (manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if os.IsNotExist(err) {
+		return manifest{}, nil
+	}
+	if err != nil {
+		return manifest{}, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.Version != indexVersion {
+		return manifest{}, fmt.Errorf("index format %d is outdated", m.Version)
+	}
+	if m.Files == nil {
+		m.Files = map[string]*fileEntry{}
+	}
+
+	vectors, err := readVectors(filepath.Join(dir, "vectors.bin"), m.Dimensions)
+	if err != nil {
+		return manifest{}, err
+	}
+	for _, path := range m.sortedPaths() {
+		chunks := m.Files[path].Chunks
+		for i := range chunks {
+			if len(vectors) < m.Dimensions || m.Dimensions == 0 {
+				return manifest{}, fmt.Errorf("vectors file is shorter than the manifest")
+			}
+			chunks[i].Vector = vectors[:m.Dimensions:m.Dimensions]
+			vectors = vectors[m.Dimensions:]
+		}
+	}
+	if len(vectors) != 0 {
+		return manifest{}, fmt.Errorf("vectors file is longer than the manifest")
+	}
+	return m, nil
+}
+
+// save writes the manifest and its vectors.
+func (m manifest) save(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	var vectors bytes.Buffer
+	vectors.Write(vectorsMagic)
+	binary.Write(&vectors, binary.LittleEndian, uint32(m.Dimensions))
+	for _, path := range m.sortedPaths() {
+		for _, chunk := range m.Files[path].Chunks {
+			binary.Write(&vectors, binary.LittleEndian, chunk.Vector)
+		}
+	}
+	if err := atomicfile.WriteFile(filepath.Join(dir, "vectors.bin"), vectors.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+	return atomicfile.WriteFile(filepath.Join(dir, "manifest.json"), data, 0600)
+}
+
+// readVectors reads every vector component of a vectors file.
+func readVectors(path string, dimensions int) ([]float32, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) && dimensions == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(vectorsMagic)+4)
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header[:len(vectorsMagic)], vectorsMagic) {
+		return nil, fmt.Errorf("invalid vectors file")
+	}
+	if int(binary.LittleEndian.Uint32(header[len(vectorsMagic):])) != dimensions {
+		return nil, fmt.Errorf("vectors file does not match the manifest")
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("vectors file is truncated")
+	}
+	vectors := make([]float32, len(data)/4)
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, vectors); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
@@ -0,0 +1,338 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"myproject/ingest"
+)
+
+// Indexing limits. Chunks are smaller than for attachments so a handful of
+// them can be injected into a prompt.
+const (
+	ChunkTokens      = 300
+	embedBatchSize   = 32
+	maxIndexFiles    = 20000
+	maxTextFileBytes = 1 << 20
+	maxDocumentBytes = 20 << 20
+)
+
+// skippedFiles are generated files that would crowd out the sources.
+var skippedFiles = map[string]bool{
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"go.sum":            true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+}
+
+// skippedSuffixes are extensions of archives and minified or generated files.
+var skippedSuffixes = []string{".zip", ".tar", ".tar.gz", ".tgz", ".min.js", ".min.css", ".map"}
+
+// Progress reports how far an update has got. Total counts the files that
+// are new or changed; unchanged files are not read again.
+type Progress struct {
+	Root  string `json:"root"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	File  string `json:"file,omitempty"`
+}
+
+// UpdateResult summarizes an update.
+type UpdateResult struct {
+	Status
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Removed   int `json:"removed"`
+	Unchanged int `json:"unchanged"`
+	// Skipped lists files that could not be indexed, with the reason.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// candidate is a file found while scanning a folder.
+type candidate struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// Update brings a folder's index up to date: new and modified files, judged
+// by modification time and size, are chunked and embedded, and deleted files
+// are dropped. Changing the embedding model re-embeds everything. If the
+// update stops early, the files finished so far are kept.
+func (s *Store) Update(ctx context.Context, root string, embedding Embedding, onProgress func(Progress)) (UpdateResult, error) {
+	idx, err := s.open(root)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	idx.updateMu.Lock()
+	defer idx.updateMu.Unlock()
+
+	idx.mu.RLock()
+	current := idx.manifest
+	idx.mu.RUnlock()
+	root = current.Root
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	if !info.IsDir() {
+		return UpdateResult{}, fmt.Errorf("%s is not a folder", root)
+	}
+
+	next := manifest{
+		Version:  indexVersion,
+		Root:     root,
+		Provider: embedding.Provider,
+		Model:    embedding.Model,
+		Files:    map[string]*fileEntry{},
+	}
+	previous := current.Files
+	if current.Provider != embedding.Provider || current.Model != embedding.Model {
+		previous = nil
+	} else {
+		next.Dimensions = current.Dimensions
+	}
+
+	candidates, skipped, err := scanFolder(root)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	result := UpdateResult{Skipped: skipped}
+
+	var stale []candidate
+	for _, c := range candidates {
+		if entry, ok := previous[c.path]; ok && entry.ModTime.Equal(c.modTime) && entry.Size == c.size {
+			next.Files[c.path] = entry
+			result.Unchanged++
+			continue
+		}
+		stale = append(stale, c)
+	}
+	found := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		found[c.path] = true
+	}
+	for path := range previous {
+		if !found[path] {
+			result.Removed++
+		}
+	}
+
+	for i, c := range stale {
+		if onProgress != nil {
+			onProgress(Progress{Root: root, Done: i, Total: len(stale), File: c.path})
+		}
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		var entry *fileEntry
+		var reason string
+		entry, reason, err = indexFile(ctx, root, c, embedding, &next.Dimensions)
+		if err != nil {
+			err = fmt.Errorf("failed to index %s: %w", c.path, err)
+			break
+		}
+		if reason != "" {
+			result.Skipped = append(result.Skipped, c.path+": "+reason)
+		}
+		if _, ok := previous[c.path]; ok {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+		next.Files[c.path] = entry
+	}
+	if err == nil && onProgress != nil {
+		onProgress(Progress{Root: root, Done: len(stale), Total: len(stale)})
+	}
+
+	next.UpdatedAt = time.Now()
+	idx.mu.Lock()
+	idx.manifest = next
+	idx.mu.Unlock()
+	if saveErr := next.save(idx.dir); saveErr != nil && err == nil {
+		err = fmt.Errorf("failed to save index: %w", saveErr)
+	}
+	result.Status = next.status()
+	return result, err
+}
+
+// scanFolder lists the files of a folder worth indexing. Hidden files and
+// directories, dependency and build directories, archives and lock files
+// are left out, as are files over the size limits.
+func scanFolder(root string) ([]candidate, []string, error) {
+	var candidates []candidate
+	var skipped []string
+	errTooMany := errors.New("too many files")
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			rel, _ := filepath.Rel(root, path)
+			skipped = append(skipped, filepath.ToSlash(rel)+": "+err.Error())
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || ingest.IgnoredDir(name)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") || skippedFiles[name] {
+			return nil
+		}
+		lower := strings.ToLower(name)
+		for _, suffix := range skippedSuffixes {
+			if strings.HasSuffix(lower, suffix) {
+				return nil
+			}
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		info, err := d.Info()
+		if err != nil {
+			skipped = append(skipped, rel+": "+err.Error())
+			return nil
+		}
+		limit := int64(maxTextFileBytes)
+		if strings.HasSuffix(lower, ".pdf") || strings.HasSuffix(lower, ".docx") {
+			limit = maxDocumentBytes
+		}
+		if info.Size() > limit {
+			skipped = append(skipped, rel+": too large")
+			return nil
+		}
+		if len(candidates) >= maxIndexFiles {
+			return errTooMany
+		}
+		candidates = append(candidates, candidate{path: rel, modTime: info.ModTime(), size: info.Size()})
+		return nil
+	})
+	if errors.Is(err, errTooMany) {
+		skipped = append(skipped, fmt.Sprintf("files after the first %d", maxIndexFiles))
+		err = nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read folder: %w", err)
+	}
+	return candidates, skipped, nil
+}
+
+// indexFile extracts, chunks and embeds a file. A file without usable text
+// gets an entry with no chunks; reason says why, unless it is binary or empty.
+// dimensions is set from the first vector and checked against the rest.
+func indexFile(ctx context.Context, root string, c candidate, embedding Embedding, dimensions *int) (*fileEntry, string, error) {
+	entry := &fileEntry{ModTime: c.modTime, Size: c.size}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(c.path)))
+	if err != nil {
+		return entry, err.Error(), nil
+	}
+	doc, err := ingest.Extract(filepath.Base(c.path), data)
+	if errors.Is(err, ingest.ErrUnsupported) {
+		return entry, "", nil
+	}
+	if err != nil {
+		// Only failures to read a file are worth reporting; empty files are not
+		if cause := errors.Unwrap(err); cause != nil {
+			return entry, cause.Error(), nil
+		}
+		return entry, "", nil
+	}
+
+	leadingLines := 0
+	if doc.Format == ingest.FormatText || doc.Format == ingest.FormatMarkdown {
+		text := string(data)
+		leadingLines = strings.Count(text[:len(text)-len(strings.TrimLeftFunc(text, unicode.IsSpace))], "\n")
+	}
+	entry.Chunks = chunkDocument(doc, leadingLines)
+
+	for start := 0; start < len(entry.Chunks); start += embedBatchSize {
+		batch := entry.Chunks[start:min(start+embedBatchSize, len(entry.Chunks))]
+		inputs := make([]string, len(batch))
+		for i, chunk := range batch {
+			// The path helps match questions that name a file or package
+			inputs[i] = c.path + "\n\n" + chunk.Text
+		}
+		vectors, err := embedding.Embed(ctx, inputs)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(vectors) != len(batch) {
+			return nil, "", fmt.Errorf("expected %d embeddings, got %d", len(batch), len(vectors))
+		}
+		for i, vector := range vectors {
+			if *dimensions == 0 {
+				*dimensions = len(vector)
+			}
+			if len(vector) != *dimensions {
+				return nil, "", fmt.Errorf("embedding has %d dimensions, expected %d", len(vector), *dimensions)
+			}
+			batch[i].Vector = normalize(vector)
+		}
+	}
+	return entry, "", nil
+}
+
+// chunkDocument splits a document into index chunks. Chunks of text files
+// record their line range; leadingLines counts the blank lines extraction
+// trimmed from the start of the file.
+func chunkDocument(doc ingest.Document, leadingLines int) []chunkEntry {
+	withLines := doc.Format == ingest.FormatText || doc.Format == ingest.FormatMarkdown
+	var chunks []chunkEntry
+	for _, section := range doc.Sections {
+		offset, line := 0, 1+leadingLines
+		single := ingest.Document{Sections: []ingest.Section{section}}
+		for _, c := range ingest.Split(single, ChunkTokens) {
+			chunk := chunkEntry{Text: c.Text}
+			if len(doc.Sections) > 1 {
+				chunk.Source = section.Source
+			}
+			if withLines {
+				if i := strings.Index(section.Text[offset:], c.Text); i >= 0 {
+					line += strings.Count(section.Text[offset:offset+i], "\n")
+					chunk.StartLine = line
+					chunk.EndLine = line + strings.Count(c.Text, "\n")
+					offset += i
+				}
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// normalize scales a vector to unit length, so that similarity is a dot product.
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = v * scale
+	}
+	return normalized
+}
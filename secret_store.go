@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"myproject/secrets"
+	"os"
+)
+
+// Keys under which the App keeps its secrets.
+const apiServerTokenSecret = "api_server_token"
+
+func apiKeySecret(provider string) string {
+	return "api_key/" + provider
+}
+
+func mcpServerSecret(name string) string {
+	return "mcp_server/" + name
+}
+
+func customProviderSecret(name string) string {
+	return "custom_provider/" + name
+}
+
+// SecretStoreStatus describes the active secret store for the settings UI.
+type SecretStoreStatus struct {
+	Backend   string   `json:"backend"`
+	Locked    bool     `json:"locked"`
+	Available []string `json:"available"`
+	// Pending counts legacy config entries not yet moved into the store,
+	// e.g. because the store is locked.
+	Pending int `json:"pending"`
+}
+
+// GetSecretStoreStatus reports which secret store is in use and whether it is locked.
+func (a *App) GetSecretStoreStatus() SecretStoreStatus {
+	a.configMutex.RLock()
+	store := a.secrets
+	pending := len(a.cloudAPIKeys)
+	if a.apiServerConfig.Token != "" {
+		pending++
+	}
+	a.configMutex.RUnlock()
+
+	status := SecretStoreStatus{Available: secrets.Backends(), Pending: pending}
+	if store != nil {
+		status.Backend = store.Backend()
+		if unlocker, ok := store.(secrets.Unlocker); ok {
+			status.Locked = unlocker.Locked()
+		}
+	}
+	return status
+}
+
+// UnlockSecretStore unlocks a passphrase-protected store for this session and
+// moves any pending legacy secrets into it.
+func (a *App) UnlockSecretStore(passphrase string) error {
+	store, err := a.secretStore()
+	if err != nil {
+		return err
+	}
+	unlocker, ok := store.(secrets.Unlocker)
+	if !ok {
+		return fmt.Errorf("the %s secret store does not use a passphrase", store.Backend())
+	}
+	if err := unlocker.Unlock(passphrase); err != nil {
+		return err
+	}
+	return a.migrateLegacySecrets()
+}
+
+// SetSecretStore switches to another secret store backend, moving every
+// stored secret across. passphrase is only used by the passphrase backend;
+// the first time it is used it sets the vault's passphrase.
+func (a *App) SetSecretStore(backend string, passphrase string) error {
+	if a.configPath == "" {
+		return fmt.Errorf("secret storage is unavailable: no home directory")
+	}
+	current, err := a.secretStore()
+	if err != nil {
+		return err
+	}
+	if current.Backend() == backend {
+		return nil
+	}
+
+	next, err := secrets.Open(backend, a.secretsDir())
+	if err != nil {
+		return err
+	}
+	if unlocker, ok := next.(secrets.Unlocker); ok {
+		if err := unlocker.Unlock(passphrase); err != nil {
+			return err
+		}
+	}
+
+	keys, err := current.Keys()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets in the %s store: %w", current.Backend(), err)
+	}
+	for _, key := range keys {
+		value, err := current.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		if err := next.Set(key, value); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", key, err)
+		}
+	}
+
+	a.configMutex.Lock()
+	previousBackend := a.secretStoreBackend
+	a.secrets = next
+	a.secretStoreBackend = backend
+	a.configMutex.Unlock()
+	if err := a.saveConfig(); err != nil {
+		// Keep using the store config.json still names, so that keys saved
+		// from now on are not lost on restart
+		a.configMutex.Lock()
+		a.secrets = current
+		a.secretStoreBackend = previousBackend
+		a.configMutex.Unlock()
+		return err
+	}
+
+	for _, key := range keys {
+		if err := current.Delete(key); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not remove %s from the %s store: %v\n", key, current.Backend(), err)
+		}
+	}
+	return a.migrateLegacySecrets()
+}
+
+// openSecretStore opens the configured backend, or picks a default on first run.
+func (a *App) openSecretStore() {
+	if a.configPath == "" {
+		return
+	}
+
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+
+	if a.secretStoreBackend != "" {
+		store, err := secrets.Open(a.secretStoreBackend, a.secretsDir())
+		if err == nil {
+			a.secrets = store
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s secret store unavailable, falling back to file store: %v\n", a.secretStoreBackend, err)
+		a.secrets = secrets.NewFileStore(a.secretsDir())
+		return
+	}
+	a.secrets = secrets.OpenDefault(a.secretsDir())
+	a.secretStoreBackend = a.secrets.Backend()
+}
+
+// migrateLegacySecrets moves API keys and the API server token that were
+// encrypted into config.json with the build-time key into the secret store.
+// Entries that cannot be moved yet, e.g. while the store is locked, stay in
+// the config and are retried later.
+func (a *App) migrateLegacySecrets() error {
+	store, err := a.secretStore()
+	if err != nil {
+		return err
+	}
+
+	a.configMutex.RLock()
+	legacy := make(map[string]string, len(a.cloudAPIKeys)+1)
+	for provider, encrypted := range a.cloudAPIKeys {
+		legacy[apiKeySecret(provider)] = encrypted
+	}
+	if a.apiServerConfig.Token != "" {
+		legacy[apiServerTokenSecret] = a.apiServerConfig.Token
+	}
+	a.configMutex.RUnlock()
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	var migrated []string
+	for key, encrypted := range legacy {
+		value, err := DecryptString(encrypted, a.encryptionKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not decrypt legacy secret %s: %v\n", key, err)
+			continue
+		}
+		if err := store.Set(key, value); err != nil {
+			if !errors.Is(err, secrets.ErrLocked) {
+				fmt.Fprintf(os.Stderr, "Warning: could not move %s into the %s store: %v\n", key, store.Backend(), err)
+			}
+			continue
+		}
+		migrated = append(migrated, key)
+	}
+	if len(migrated) == 0 {
+		return nil
+	}
+
+	a.configMutex.Lock()
+	for _, key := range migrated {
+		if key == apiServerTokenSecret {
+			a.apiServerConfig.Token = ""
+			continue
+		}
+		for provider := range a.cloudAPIKeys {
+			if apiKeySecret(provider) == key {
+				delete(a.cloudAPIKeys, provider)
+			}
+		}
+	}
+	a.configMutex.Unlock()
+
+	fmt.Printf("Moved %d secrets into the %s store\n", len(migrated), store.Backend())
+	return a.saveConfig()
+}
+
+// getSecret reads a secret, falling back to an entry in config.json that has
+// not been migrated yet.
+func (a *App) getSecret(key string, legacy string) (string, error) {
+	store, err := a.secretStore()
+	if err != nil {
+		return "", err
+	}
+	value, err := store.Get(key)
+	if errors.Is(err, secrets.ErrNotFound) || errors.Is(err, secrets.ErrLocked) {
+		if legacy != "" {
+			return DecryptString(legacy, a.encryptionKey)
+		}
+	}
+	return value, err
+}
+
+// secretStore returns the active secret store or an error when there is none.
+func (a *App) secretStore() (secrets.Store, error) {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	if a.secrets == nil {
+		return nil, fmt.Errorf("secret storage is unavailable: no home directory")
+	}
+	return a.secrets, nil
+}
@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"myproject/internal/atomicfile"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps secrets in secrets.json, encrypted with a random key that
+// is generated on first use and kept next to it in secrets.key. Both files are
+// readable only by the owner. It protects against leaking config.json alone,
+// not against someone who can read the user's home directory.
+type FileStore struct {
+	dir   string
+	vault vault
+}
+
+// NewFileStore creates a file store under dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		dir:   dir,
+		vault: vault{path: filepath.Join(dir, "secrets.json")},
+	}
+}
+
+func (s *FileStore) Backend() string {
+	return BackendFile
+}
+
+func (s *FileStore) Get(key string) (string, error) {
+	if err := s.ensureOpen(); err != nil {
+		return "", err
+	}
+	return s.vault.get(key)
+}
+
+func (s *FileStore) Set(key string, value string) error {
+	if err := s.ensureOpen(); err != nil {
+		return err
+	}
+	return s.vault.set(key, value)
+}
+
+func (s *FileStore) Delete(key string) error {
+	if err := s.ensureOpen(); err != nil {
+		return err
+	}
+	return s.vault.delete(key)
+}
+
+func (s *FileStore) Keys() ([]string, error) {
+	if err := s.ensureOpen(); err != nil {
+		return nil, err
+	}
+	return s.vault.keys()
+}
+
+// ensureOpen loads the key and vault on first use.
+func (s *FileStore) ensureOpen() error {
+	if s.vault.isOpen() {
+		return nil
+	}
+	key, err := s.loadKey()
+	if err != nil {
+		return err
+	}
+	err = s.vault.open(key, nil)
+	if errors.Is(err, errWrongKey) {
+		return fmt.Errorf("secrets.json does not match secrets.key")
+	}
+	return err
+}
+
+// loadKey reads secrets.key, creating it when missing.
+func (s *FileStore) loadKey() ([]byte, error) {
+	path := filepath.Join(s.dir, "secrets.key")
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("%s is corrupt", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read secret key: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret key: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	if err := atomicfile.WriteFile(path, key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
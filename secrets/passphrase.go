@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// kdfParams records how a vault key was derived from a passphrase.
+type kdfParams struct {
+	Name    string `json:"name"`
+	Salt    string `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// Argon2id parameters for new vaults, following the RFC 9106 second
+// recommended option.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+// Limits on the Argon2id parameters of vaults and sealed secrets, which may
+// come from an imported file. Memory is in KiB.
+const (
+	maxArgonTime    = 10
+	maxArgonMemory  = 1024 * 1024
+	maxArgonThreads = 16
+)
+
+// PassphraseStore keeps secrets in vault.json, encrypted with a key derived
+// from the user's passphrase with Argon2id. It starts locked and must be
+// unlocked once per session; the passphrase itself is never stored.
+type PassphraseStore struct {
+	vault vault
+}
+
+// NewPassphraseStore creates a locked passphrase store under dir.
+func NewPassphraseStore(dir string) *PassphraseStore {
+	return &PassphraseStore{
+		vault: vault{path: filepath.Join(dir, "vault.json")},
+	}
+}
+
+func (s *PassphraseStore) Backend() string {
+	return BackendPassphrase
+}
+
+// Locked reports whether Unlock still needs to be called.
+func (s *PassphraseStore) Locked() bool {
+	return !s.vault.isOpen()
+}
+
+// Unlock derives the vault key from passphrase. The first unlock creates the
+// vault, so the passphrase given then becomes the vault's passphrase.
+func (s *PassphraseStore) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase cannot be empty")
+	}
+
+	file, err := readVaultFile(s.vault.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	params := file.KDF
+	if err == nil {
+		if params == nil || params.Name != "argon2id" {
+			return fmt.Errorf("unsupported vault key derivation")
+		}
+	} else if params, err = newKDFParams(); err != nil {
+		return err
+	}
+
+	key, err := params.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	err = s.vault.open(key, params)
+	if errors.Is(err, errWrongKey) {
+		return fmt.Errorf("incorrect passphrase")
+	}
+	return err
+}
+
+// newKDFParams returns Argon2id parameters with a fresh random salt.
+func newKDFParams() (*kdfParams, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return &kdfParams{
+		Name:    "argon2id",
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Time:    argonTime,
+		Memory:  argonMemory,
+		Threads: argonThreads,
+	}, nil
+}
+
+// deriveKey derives a 32-byte AES key from passphrase.
+func (p *kdfParams) deriveKey(passphrase string) ([]byte, error) {
+	if p.Time < 1 || p.Time > maxArgonTime || p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgonMemory || p.Threads < 1 || p.Threads > maxArgonThreads {
+		return nil, fmt.Errorf("unsupported key derivation parameters")
+	}
+	salt, err := base64.StdEncoding.DecodeString(p.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("salt is corrupt")
+	}
+	return argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, 32), nil
+}
+
+func (s *PassphraseStore) Get(key string) (string, error) {
+	return s.vault.get(key)
+}
+
+func (s *PassphraseStore) Set(key string, value string) error {
+	return s.vault.set(key, value)
+}
+
+func (s *PassphraseStore) Delete(key string) error {
+	return s.vault.delete(key)
+}
+
+func (s *PassphraseStore) Keys() ([]string, error) {
+	return s.vault.keys()
+}
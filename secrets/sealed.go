@@ -0,0 +1,63 @@
+package secrets
+
+import "fmt"
+
+// Sealed is a set of secrets encrypted with a key derived from a passphrase,
+// in the same form as a passphrase vault. It carries its own key derivation
+// parameters so it can be opened on another machine, e.g. from a config export.
+type Sealed struct {
+	KDF     *kdfParams        `json:"kdf"`
+	Check   string            `json:"check"`
+	Entries map[string]string `json:"entries"`
+}
+
+// Seal encrypts values under passphrase.
+func Seal(values map[string]string, passphrase string) (*Sealed, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+	params, err := newKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	key, err := params.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := seal(key, vaultCheckValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+	sealed := &Sealed{KDF: params, Check: check, Entries: make(map[string]string, len(values))}
+	for name, value := range values {
+		if sealed.Entries[name], err = seal(key, value); err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", name, err)
+		}
+	}
+	return sealed, nil
+}
+
+// Open decrypts the secrets with passphrase.
+func (s *Sealed) Open(passphrase string) (map[string]string, error) {
+	if s.KDF == nil || s.KDF.Name != "argon2id" {
+		return nil, fmt.Errorf("unsupported key derivation")
+	}
+	key, err := s.KDF.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if value, err := unseal(key, s.Check); err != nil || value != vaultCheckValue {
+		return nil, fmt.Errorf("incorrect passphrase")
+	}
+
+	values := make(map[string]string, len(s.Entries))
+	for name, sealed := range s.Entries {
+		value, err := unseal(key, sealed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,225 @@
+//go:build linux
+
+package secrets
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus names of the freedesktop.org Secret Service API, implemented by
+// GNOME Keyring and KWallet.
+const (
+	secretServiceName  = "org.freedesktop.secrets"
+	secretServicePath  = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceIface = "org.freedesktop.Secret.Service"
+	collectionIface    = "org.freedesktop.Secret.Collection"
+	itemIface          = "org.freedesktop.Secret.Item"
+	promptIface        = "org.freedesktop.Secret.Prompt"
+	sessionIface       = "org.freedesktop.Secret.Session"
+
+	// promptTimeout bounds how long we wait for the user to answer an unlock prompt.
+	promptTimeout = 2 * time.Minute
+)
+
+// secretStruct matches the Secret Service (oayays) secret struct.
+type secretStruct struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretService stores secrets in the user's default keyring collection. Items
+// are tagged with application=lumen and key=<key>.
+type SecretService struct {
+	conn       *dbus.Conn
+	session    dbus.ObjectPath
+	collection dbus.ObjectPath
+}
+
+// OpenSecretService connects to the Secret Service on the session bus. It
+// fails when no keyring daemon is running.
+func OpenSecretService() (*SecretService, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("no D-Bus session bus: %w", err)
+	}
+	service := conn.Object(secretServiceName, secretServicePath)
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := service.Call(secretServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return nil, fmt.Errorf("secret service unavailable: %w", err)
+	}
+
+	store := &SecretService{conn: conn, session: session}
+	if err := service.Call(secretServiceIface+".ReadAlias", 0, "default").Store(&store.collection); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to find default keyring: %w", err)
+	}
+	if store.collection == "/" {
+		store.Close()
+		return nil, fmt.Errorf("no default keyring is configured")
+	}
+	return store, nil
+}
+
+// Close ends the store's Secret Service session. The shared session bus
+// connection stays open.
+func (s *SecretService) Close() error {
+	if err := s.conn.Object(secretServiceName, s.session).Call(sessionIface+".Close", 0).Err; err != nil {
+		return fmt.Errorf("failed to close secret service session: %w", err)
+	}
+	return nil
+}
+
+func (s *SecretService) Backend() string {
+	return BackendSecretService
+}
+
+func (s *SecretService) Get(key string) (string, error) {
+	items, err := s.search(map[string]string{"application": "lumen", "key": key})
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", ErrNotFound
+	}
+	if err := s.unlock(items[:1]); err != nil {
+		return "", err
+	}
+
+	var secret secretStruct
+	item := s.conn.Object(secretServiceName, items[0])
+	if err := item.Call(itemIface+".GetSecret", 0, s.session).Store(&secret); err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return string(secret.Value), nil
+}
+
+func (s *SecretService) Set(key string, value string) error {
+	if err := s.unlock([]dbus.ObjectPath{s.collection}); err != nil {
+		return err
+	}
+
+	properties := map[string]dbus.Variant{
+		itemIface + ".Label":      dbus.MakeVariant("Lumen: " + key),
+		itemIface + ".Attributes": dbus.MakeVariant(map[string]string{"application": "lumen", "key": key}),
+	}
+	secret := secretStruct{
+		Session:     s.session,
+		Parameters:  []byte{},
+		Value:       []byte(value),
+		ContentType: "text/plain; charset=utf8",
+	}
+
+	var item, prompt dbus.ObjectPath
+	collection := s.conn.Object(secretServiceName, s.collection)
+	if err := collection.Call(collectionIface+".CreateItem", 0, properties, secret, true).Store(&item, &prompt); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	return s.prompt(prompt)
+}
+
+func (s *SecretService) Delete(key string) error {
+	items, err := s.search(map[string]string{"application": "lumen", "key": key})
+	if err != nil {
+		return err
+	}
+	for _, path := range items {
+		var prompt dbus.ObjectPath
+		if err := s.conn.Object(secretServiceName, path).Call(itemIface+".Delete", 0).Store(&prompt); err != nil {
+			return fmt.Errorf("failed to delete secret: %w", err)
+		}
+		if err := s.prompt(prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SecretService) Keys() ([]string, error) {
+	items, err := s.search(map[string]string{"application": "lumen"})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(items))
+	for _, path := range items {
+		variant, err := s.conn.Object(secretServiceName, path).GetProperty(itemIface + ".Attributes")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret attributes: %w", err)
+		}
+		if attributes, ok := variant.Value().(map[string]string); ok && attributes["key"] != "" {
+			keys = append(keys, attributes["key"])
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// search returns the items in the default collection matching attributes.
+func (s *SecretService) search(attributes map[string]string) ([]dbus.ObjectPath, error) {
+	var items []dbus.ObjectPath
+	collection := s.conn.Object(secretServiceName, s.collection)
+	if err := collection.Call(collectionIface+".SearchItems", 0, attributes).Store(&items); err != nil {
+		return nil, fmt.Errorf("failed to search keyring: %w", err)
+	}
+	return items, nil
+}
+
+// unlock unlocks objects, showing the keyring's own unlock prompt if needed.
+func (s *SecretService) unlock(objects []dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	service := s.conn.Object(secretServiceName, secretServicePath)
+	if err := service.Call(secretServiceIface+".Unlock", 0, objects).Store(&unlocked, &prompt); err != nil {
+		return fmt.Errorf("failed to unlock keyring: %w", err)
+	}
+	return s.prompt(prompt)
+}
+
+// prompt runs a Secret Service prompt and waits for the user to complete it.
+// The path "/" means no prompt is needed.
+func (s *SecretService) prompt(path dbus.ObjectPath) error {
+	if path == "/" || path == "" {
+		return nil
+	}
+
+	rule := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(promptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(rule...); err != nil {
+		return fmt.Errorf("failed to watch keyring prompt: %w", err)
+	}
+	defer s.conn.RemoveMatchSignal(rule...)
+
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(secretServiceName, path).Call(promptIface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("failed to show keyring prompt: %w", err)
+	}
+
+	timeout := time.After(promptTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != path || len(signal.Body) == 0 {
+				continue
+			}
+			if dismissed, ok := signal.Body[0].(bool); ok && dismissed {
+				return ErrLocked
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for keyring prompt")
+		}
+	}
+}
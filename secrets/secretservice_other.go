@@ -0,0 +1,38 @@
+//go:build !linux
+
+package secrets
+
+import "fmt"
+
+// SecretService is only available on Linux.
+type SecretService struct{}
+
+// OpenSecretService always fails outside Linux.
+func OpenSecretService() (*SecretService, error) {
+	return nil, fmt.Errorf("secret service is only available on Linux")
+}
+
+// Close does nothing outside Linux.
+func (s *SecretService) Close() error {
+	return nil
+}
+
+func (s *SecretService) Backend() string {
+	return BackendSecretService
+}
+
+func (s *SecretService) Get(key string) (string, error) {
+	return "", ErrNotFound
+}
+
+func (s *SecretService) Set(key string, value string) error {
+	return fmt.Errorf("secret service is only available on Linux")
+}
+
+func (s *SecretService) Delete(key string) error {
+	return nil
+}
+
+func (s *SecretService) Keys() ([]string, error) {
+	return nil, nil
+}
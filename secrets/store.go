@@ -0,0 +1,77 @@
+// Package secrets stores API keys and tokens outside the config file, in the
+// OS keyring or in a locally encrypted vault.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Backend names, as saved in the config.
+const (
+	BackendSecretService = "secret-service"
+	BackendPassphrase    = "passphrase"
+	BackendFile          = "file"
+)
+
+var (
+	// ErrNotFound is returned by Get when no secret is stored under the key.
+	ErrNotFound = errors.New("secret not found")
+	// ErrLocked is returned while a passphrase-protected store is locked.
+	ErrLocked = errors.New("secret store is locked")
+)
+
+// Store persists secrets by key.
+type Store interface {
+	// Backend returns the backend name, e.g. BackendFile.
+	Backend() string
+	Get(key string) (string, error)
+	Set(key string, value string) error
+	Delete(key string) error
+	// Keys lists the keys of all stored secrets.
+	Keys() ([]string, error)
+}
+
+// Unlocker is implemented by stores that must be unlocked before use.
+type Unlocker interface {
+	Locked() bool
+	Unlock(passphrase string) error
+}
+
+// Open returns the store for a backend. dir is the directory holding the
+// vault files of the local backends.
+func Open(backend string, dir string) (Store, error) {
+	switch backend {
+	case BackendSecretService:
+		store, err := OpenSecretService()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case BackendPassphrase:
+		return NewPassphraseStore(dir), nil
+	case BackendFile:
+		return NewFileStore(dir), nil
+	}
+	return nil, fmt.Errorf("unknown secret store backend %q", backend)
+}
+
+// OpenDefault picks the OS keyring when one is running and falls back to the
+// file store otherwise.
+func OpenDefault(dir string) Store {
+	if store, err := OpenSecretService(); err == nil {
+		return store
+	}
+	return NewFileStore(dir)
+}
+
+// Backends lists the backends usable on this machine. The Secret Service is
+// probed with a session that is closed again.
+func Backends() []string {
+	backends := []string{}
+	if store, err := OpenSecretService(); err == nil {
+		store.Close()
+		backends = append(backends, BackendSecretService)
+	}
+	return append(backends, BackendPassphrase, BackendFile)
+}
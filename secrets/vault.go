@@ -0,0 +1,196 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"myproject/internal/atomicfile"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// vaultFile is the on-disk form of an encrypted vault. Each entry is sealed
+// separately with AES-256-GCM; Check seals a fixed value so a wrong key is
+// detected before any entry is touched.
+type vaultFile struct {
+	Version int               `json:"version"`
+	KDF     *kdfParams        `json:"kdf,omitempty"`
+	Check   string            `json:"check"`
+	Entries map[string]string `json:"entries"`
+}
+
+const vaultCheckValue = "lumen-vault"
+
+// errWrongKey is returned by open when the key does not match the vault.
+var errWrongKey = errors.New("wrong vault key")
+
+// vault is an encrypted JSON file of secrets shared by the file and
+// passphrase backends, which differ only in where the key comes from.
+type vault struct {
+	path string
+
+	mu   sync.Mutex
+	key  []byte
+	file vaultFile
+}
+
+// open reads the vault with key, creating an empty one when the file does
+// not exist. kdf is recorded in new vaults.
+func (v *vault) open(key []byte, kdf *kdfParams) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	file, err := readVaultFile(v.path)
+	if os.IsNotExist(err) {
+		check, err := seal(key, vaultCheckValue)
+		if err != nil {
+			return err
+		}
+		v.file = vaultFile{Version: 1, KDF: kdf, Check: check, Entries: map[string]string{}}
+		v.key = key
+		return v.write()
+	}
+	if err != nil {
+		return err
+	}
+	if value, err := unseal(key, file.Check); err != nil || value != vaultCheckValue {
+		return errWrongKey
+	}
+	if file.Entries == nil {
+		file.Entries = map[string]string{}
+	}
+	v.file = file
+	v.key = key
+	return nil
+}
+
+func (v *vault) isOpen() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.key != nil
+}
+
+func (v *vault) get(key string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return "", ErrLocked
+	}
+	sealed, ok := v.file.Entries[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return unseal(v.key, sealed)
+}
+
+func (v *vault) set(key string, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	sealed, err := seal(v.key, value)
+	if err != nil {
+		return err
+	}
+	v.file.Entries[key] = sealed
+	return v.write()
+}
+
+func (v *vault) delete(key string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	if _, ok := v.file.Entries[key]; !ok {
+		return nil
+	}
+	delete(v.file.Entries, key)
+	return v.write()
+}
+
+func (v *vault) keys() ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return nil, ErrLocked
+	}
+	keys := make([]string, 0, len(v.file.Entries))
+	for key := range v.file.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// write saves the vault through a temp file so a crash never leaves it half written.
+func (v *vault) write() error {
+	data, err := json.MarshalIndent(v.file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	return atomicfile.WriteFile(v.path, data, 0600)
+}
+
+func readVaultFile(path string) (vaultFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return vaultFile{}, err
+	}
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return vaultFile{}, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return file, nil
+}
+
+// seal encrypts value with AES-GCM and returns base64(nonce || ciphertext).
+func seal(key []byte, value string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+func unseal(key []byte, sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret")
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"myproject/connectors"
+	"os"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ChatToolCallEvent is emitted for each tool a model calls during a chat.
+const ChatToolCallEvent = "chat:tool_call"
+
+// ChatToolCall reports a tool call made during a generation and its result,
+// or the error returned to the model instead.
+type ChatToolCall struct {
+	GenerationID string              `json:"generation_id"`
+	Call         connectors.ToolCall `json:"call"`
+	Result       string              `json:"result,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// GetTools lists the tools models can call in chats with use_tools set.
+func (a *App) GetTools() []connectors.Tool {
+	return a.tools.Tools()
+}
+
+// chatWithTools lets the model call the registered tools, or the request's
+// own tool set, until it answers, and returns the answer.
+func (a *App) chatWithTools(ctx context.Context, p connectors.Provider, request ChatRequest, options map[string]interface{}) (string, error) {
+	caller, ok := p.(connectors.ToolCaller)
+	if !ok || !p.Capabilities().Tools {
+		return "", fmt.Errorf("tool calling is not supported for %s", request.Provider)
+	}
+
+	if confirm := a.writeConfirmer(request); confirm != nil {
+		ctx = context.WithValue(ctx, writeConfirmerKey{}, confirm)
+	}
+	tools := a.tools
+	if request.toolSet != nil {
+		tools = request.toolSet
+	}
+	start := time.Now()
+	messages, err := connectors.RunTools(ctx, caller, request.Model, request.Messages, options, tools, func(call connectors.ToolCall, result string, err error) {
+		event := ChatToolCall{GenerationID: request.GenerationID, Call: call, Result: result}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		a.emitToolCall(request, event)
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s chat failed after %v: %v", request.Provider, time.Since(start), err)
+	}
+	return messages[len(messages)-1].Content, nil
+}
+
+// emitToolCall reports a tool call to the request's own handler or else to
+// the frontend.
+func (a *App) emitToolCall(request ChatRequest, event ChatToolCall) {
+	if request.onToolCall != nil {
+		request.onToolCall(event)
+	} else if a.ctx != nil && request.GenerationID != "" {
+		runtime.EventsEmit(a.ctx, ChatToolCallEvent, event)
+	}
+}
+
+// registerBuiltinTools registers the tools that ship with Lumen.
+func (a *App) registerBuiltinTools() {
+	err := a.tools.Register(connectors.Tool{
+		Name:        "get_current_time",
+		Description: "Returns the current date and time in the user's time zone, or in the given IANA time zone.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"timezone":{"type":"string","description":"IANA time zone name, e.g. Europe/Paris"}}}`),
+	}, currentTimeTool)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to register built-in tool: %v\n", err)
+	}
+}
+
+// currentTimeTool implements get_current_time.
+func currentTimeTool(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	now := time.Now()
+	if args.Timezone != "" {
+		location, err := time.LoadLocation(args.Timezone)
+		if err != nil {
+			return "", fmt.Errorf("unknown time zone %q", args.Timezone)
+		}
+		now = now.In(location)
+	}
+	return now.Format("Monday, 2 January 2006 15:04:05 MST (-07:00)"), nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"myproject/connectors"
+	"myproject/workspace"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// WorkspaceWriteEvent asks the frontend to confirm changes a model wants to
+// make to the workspace; answer with ConfirmWorkspaceWrite.
+const WorkspaceWriteEvent = "workspace:write"
+
+// How long a write waits for the user before it is declined.
+const workspaceConfirmTimeout = 10 * time.Minute
+
+// WorkspaceConfig is the folder the workspace tools may read and change.
+type WorkspaceConfig struct {
+	Root string `json:"root,omitempty"`
+}
+
+// WorkspaceWrite describes the changes of one apply_patch call awaiting
+// confirmation.
+type WorkspaceWrite struct {
+	ConfirmID    string                 `json:"confirm_id"`
+	GenerationID string                 `json:"generation_id,omitempty"`
+	Root         string                 `json:"root"`
+	Changes      []workspace.FileChange `json:"changes"`
+}
+
+// writeConfirmer decides whether prepared changes may be written.
+type writeConfirmer func(ctx context.Context, write WorkspaceWrite) (bool, error)
+
+// writeConfirmerKey carries a chat's writeConfirmer to the apply_patch tool.
+type writeConfirmerKey struct{}
+
+// workspaceTools are the tools registered while a workspace is open.
+var workspaceTools = []connectors.Tool{
+	{
+		Name:        "read_file",
+		Description: "Reads a text file of the user's workspace, returning numbered lines. Read large files in ranges.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"path":{"type":"string","description":"Path relative to the workspace root"},"start_line":{"type":"integer","description":"First line to read, from 1"},"end_line":{"type":"integer","description":"Last line to read"}},"required":["path"]}`),
+	},
+	{
+		Name:        "list_dir",
+		Description: "Lists a directory of the user's workspace: subdirectories end with / and files show their size.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"path":{"type":"string","description":"Directory relative to the workspace root; the root if empty"},"recursive":{"type":"boolean","description":"Also list subdirectories, except hidden and dependency ones"}}}`),
+	},
+	{
+		Name:        "grep",
+		Description: "Searches the text files of the user's workspace for lines matching a regular expression (RE2 syntax), returning path:line: text.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"pattern":{"type":"string","description":"Regular expression"},"path":{"type":"string","description":"File or directory to search; the whole workspace if empty"},"include":{"type":"string","description":"Glob that file names must match, e.g. *.go"},"ignore_case":{"type":"boolean"}},"required":["pattern"]}`),
+	},
+	{
+		Name:        "apply_patch",
+		Description: "Changes files of the user's workspace with a unified diff (--- a/path, +++ b/path, @@ hunks with a few context lines). Use /dev/null as the old path to create a file and as the new path to delete one. The user must approve every patch; read files before changing them.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"patch":{"type":"string","description":"Unified diff of one or more files"}},"required":["patch"]}`),
+	},
+}
+
+// GetWorkspace returns the workspace folder, or an empty string when none is open.
+func (a *App) GetWorkspace() string {
+	a.workspaceMutex.Lock()
+	defer a.workspaceMutex.Unlock()
+	if a.workspace == nil {
+		return ""
+	}
+	return a.workspace.Root()
+}
+
+// SetWorkspace opens a folder as the workspace, asking for one when path is
+// empty, and offers models the workspace tools. Choosing the folder is the
+// user's approval for models to read it; writes are confirmed one by one.
+// It returns the folder, or an empty string for a cancelled dialog.
+func (a *App) SetWorkspace(path string) (string, error) {
+	if path == "" {
+		if a.ctx == nil {
+			return "", fmt.Errorf("a folder path is required")
+		}
+		var err error
+		path, err = runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{Title: "Open workspace"})
+		if err != nil || path == "" {
+			return "", err
+		}
+	}
+	ws, err := workspace.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot open workspace: %w", err)
+	}
+
+	a.configMutex.Lock()
+	a.workspaceConfig.Root = ws.Root()
+	a.configMutex.Unlock()
+	a.setWorkspace(ws)
+	return ws.Root(), a.saveConfig()
+}
+
+// CloseWorkspace closes the workspace and withdraws the workspace tools.
+func (a *App) CloseWorkspace() error {
+	a.configMutex.Lock()
+	a.workspaceConfig.Root = ""
+	a.configMutex.Unlock()
+	a.setWorkspace(nil)
+	return a.saveConfig()
+}
+
+// ConfirmWorkspaceWrite answers a WorkspaceWriteEvent.
+func (a *App) ConfirmWorkspaceWrite(confirmID string, approved bool) error {
+	a.workspaceMutex.Lock()
+	reply, ok := a.workspaceConfirms[confirmID]
+	delete(a.workspaceConfirms, confirmID)
+	a.workspaceMutex.Unlock()
+	if !ok {
+		return fmt.Errorf("no pending write %s", confirmID)
+	}
+	reply <- approved
+	return nil
+}
+
+// openConfiguredWorkspace opens the workspace saved in the config, if any.
+func (a *App) openConfiguredWorkspace(root string) {
+	if root == "" {
+		a.setWorkspace(nil)
+		return
+	}
+	ws, err := workspace.Open(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot open workspace %s: %v\n", root, err)
+		a.setWorkspace(nil)
+		return
+	}
+	a.setWorkspace(ws)
+}
+
+// setWorkspace replaces the open workspace, registering the workspace tools
+// while one is open.
+func (a *App) setWorkspace(ws *workspace.Workspace) {
+	a.workspaceMutex.Lock()
+	a.workspace = ws
+	a.workspaceMutex.Unlock()
+
+	for _, tool := range workspaceTools {
+		a.tools.Unregister(tool.Name)
+	}
+	if ws == nil {
+		return
+	}
+	handlers := map[string]connectors.ToolHandler{
+		"read_file":   a.readFileTool,
+		"list_dir":    a.listDirTool,
+		"grep":        a.grepTool,
+		"apply_patch": a.applyPatchTool,
+	}
+	for _, tool := range workspaceTools {
+		if err := a.tools.Register(tool, handlers[tool.Name]); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to register workspace tool: %v\n", err)
+		}
+	}
+}
+
+// currentWorkspace returns the open workspace for a tool call.
+func (a *App) currentWorkspace() (*workspace.Workspace, error) {
+	a.workspaceMutex.Lock()
+	defer a.workspaceMutex.Unlock()
+	if a.workspace == nil {
+		return nil, fmt.Errorf("no workspace is open")
+	}
+	return a.workspace, nil
+}
+
+// readFileTool implements read_file.
+func (a *App) readFileTool(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	ws, err := a.currentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	return ws.ReadFile(args.Path, args.StartLine, args.EndLine)
+}
+
+// listDirTool implements list_dir.
+func (a *App) listDirTool(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		Recursive bool   `json:"recursive"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	ws, err := a.currentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	return ws.ListDir(args.Path, args.Recursive)
+}
+
+// grepTool implements grep.
+func (a *App) grepTool(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Pattern    string `json:"pattern"`
+		Path       string `json:"path"`
+		Include    string `json:"include"`
+		IgnoreCase bool   `json:"ignore_case"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	ws, err := a.currentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	return ws.Grep(ctx, args.Pattern, workspace.GrepOptions{Path: args.Path, Include: args.Include, IgnoreCase: args.IgnoreCase})
+}
+
+// applyPatchTool implements apply_patch. Nothing is written unless the
+// chat's writeConfirmer approves the changes.
+func (a *App) applyPatchTool(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Patch string `json:"patch"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	ws, err := a.currentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	changes, err := ws.PreparePatch(args.Patch)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, _ := ctx.Value(writeConfirmerKey{}).(writeConfirmer)
+	if confirm == nil {
+		return "", fmt.Errorf("changes to the workspace cannot be confirmed here, so they are not allowed")
+	}
+	approved, err := confirm(ctx, WorkspaceWrite{Root: ws.Root(), Changes: changes})
+	if err != nil {
+		return "", err
+	}
+	if !approved {
+		return "", fmt.Errorf("the user declined the changes")
+	}
+	if err := ws.ApplyChanges(changes); err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		switch {
+		case change.OldPath != "":
+			lines = append(lines, fmt.Sprintf("renamed %s to %s", change.OldPath, change.Path))
+		case change.Action == workspace.ActionCreate:
+			lines = append(lines, "created "+change.Path)
+		case change.Action == workspace.ActionDelete:
+			lines = append(lines, "deleted "+change.Path)
+		default:
+			lines = append(lines, "updated "+change.Path)
+		}
+	}
+	return "Applied the patch:\n" + strings.Join(lines, "\n") + "\n", nil
+}
+
+// writeConfirmer returns how a chat confirms workspace writes: through the
+// request's own handler, or else by asking the frontend. Headless chats
+// without a handler cannot write.
+func (a *App) writeConfirmer(request ChatRequest) writeConfirmer {
+	if request.confirmWrite != nil {
+		return request.confirmWrite
+	}
+	if a.ctx == nil {
+		return nil
+	}
+	return func(ctx context.Context, write WorkspaceWrite) (bool, error) {
+		write.GenerationID = request.GenerationID
+		return a.confirmWithFrontend(ctx, write)
+	}
+}
+
+// confirmWithFrontend emits a WorkspaceWriteEvent and waits for the answer.
+// Cancelling the generation or the timeout declines the write.
+func (a *App) confirmWithFrontend(ctx context.Context, write WorkspaceWrite) (bool, error) {
+	confirmID, err := newGenerationID()
+	if err != nil {
+		return false, err
+	}
+	write.ConfirmID = confirmID
+	reply := make(chan bool, 1)
+	a.workspaceMutex.Lock()
+	a.workspaceConfirms[write.ConfirmID] = reply
+	a.workspaceMutex.Unlock()
+	defer func() {
+		a.workspaceMutex.Lock()
+		delete(a.workspaceConfirms, write.ConfirmID)
+		a.workspaceMutex.Unlock()
+	}()
+
+	runtime.EventsEmit(a.ctx, WorkspaceWriteEvent, write)
+	timer := time.NewTimer(workspaceConfirmTimeout)
+	defer timer.Stop()
+	select {
+	case approved := <-reply:
+		return approved, nil
+	case <-timer.C:
+		return false, fmt.Errorf("the user did not answer in time")
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
@@ -0,0 +1,113 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// errEnoughMatches stops a search once the match limit is reached.
+var errEnoughMatches = errors.New("enough matches")
+
+// GrepOptions narrow a search.
+type GrepOptions struct {
+	// Path is a file or directory to search; the whole workspace if empty.
+	Path string
+	// Include is a glob that file names, or workspace paths if it contains
+	// a slash, must match, e.g. "*.go".
+	Include    string
+	IgnoreCase bool
+}
+
+// Grep searches text files for lines matching a regular expression and
+// returns them as "path:line: text". Hidden and dependency directories are
+// not searched, nor are binary or very large files.
+func (w *Workspace) Grep(ctx context.Context, pattern string, options GrepOptions) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("a pattern is required")
+	}
+	if options.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %v", err)
+	}
+	if options.Include != "" {
+		if _, err := filepath.Match(options.Include, ""); err != nil {
+			return "", fmt.Errorf("invalid include glob: %v", err)
+		}
+	}
+	start, err := w.Resolve(options.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	err = filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == start {
+				return err
+			}
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() {
+			if path != start && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel := w.Rel(path)
+		if options.Include != "" {
+			name := d.Name()
+			if strings.Contains(options.Include, "/") {
+				name = rel
+			}
+			if ok, _ := filepath.Match(options.Include, name); !ok {
+				return nil
+			}
+		}
+
+		text, err := readText(path, rel)
+		if err != nil {
+			return nil
+		}
+		for i, line := range strings.Split(text, "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if len(matches) == maxGrepMatches {
+				return errEnoughMatches
+			}
+			line = strings.TrimRight(line, "\r")
+			if len(line) > maxGrepLineText {
+				line = strings.ToValidUTF8(line[:maxGrepLineText], "") + "..."
+			}
+			matches = append(matches, fmt.Sprintf("%s:%d: %s", rel, i+1, line))
+		}
+		return nil
+	})
+	truncated := errors.Is(err, errEnoughMatches)
+	if err != nil && !truncated {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "No matches.\n", nil
+	}
+	out := strings.Join(matches, "\n") + "\n"
+	if truncated {
+		out += fmt.Sprintf("[search stopped after %d matches; narrow the pattern or path]\n", maxGrepMatches)
+	}
+	return out, nil
+}
@@ -0,0 +1,420 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"myproject/internal/atomicfile"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Actions of a FileChange.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// FileChange is what a patch does to one file, worked out before anything
+// is written so that it can be shown to the user first.
+type FileChange struct {
+	Path string `json:"path"`
+	// OldPath is the previous path of a renamed file.
+	OldPath string `json:"old_path,omitempty"`
+	Action  string `json:"action"`
+	// Diff is the part of the patch for this file.
+	Diff string `json:"diff"`
+
+	full    string
+	oldFull string
+	before  string
+	after   string
+	mode    fs.FileMode
+}
+
+// filePatch is the part of a unified diff for one file. Paths are empty for
+// /dev/null.
+type filePatch struct {
+	oldPath string
+	newPath string
+	diff    []string
+	hunks   []hunk
+}
+
+// hunk is one @@ section. oldStart is -1 when the header has no line numbers.
+type hunk struct {
+	oldStart int
+	lines    []hunkLine
+	// noNewlineNew is set when the new side ends without a newline
+	noNewlineNew bool
+}
+
+type hunkLine struct {
+	op   byte
+	text string
+}
+
+// hunkHeader matches "@@ -12,5 +12,7 @@"; the counts are not relied on.
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+\d+(?:,\d+)? @@`)
+
+// PreparePatch works out the changes a unified diff makes to the workspace
+// without writing anything. Hunks are located by their context and removed
+// lines, so slightly wrong line numbers are tolerated; changes to files
+// under .git are refused.
+func (w *Workspace) PreparePatch(patch string) ([]FileChange, error) {
+	if len(patch) > maxFileBytes {
+		return nil, fmt.Errorf("patch is too large")
+	}
+	patches, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file changes found; send a unified diff with ---/+++ file headers and @@ hunks")
+	}
+
+	seen := map[string]bool{}
+	changes := make([]FileChange, 0, len(patches))
+	for _, p := range patches {
+		change, err := w.prepareFile(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range []string{change.full, change.oldFull} {
+			if path == "" {
+				continue
+			}
+			if seen[path] {
+				return nil, fmt.Errorf("%s appears more than once in the patch", w.Rel(path))
+			}
+			seen[path] = true
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// prepareFile works out the change one file patch makes.
+func (w *Workspace) prepareFile(p filePatch) (FileChange, error) {
+	change := FileChange{Diff: strings.Join(p.diff, "\n"), mode: 0644}
+	var err error
+	switch {
+	case p.oldPath == "" && p.newPath == "":
+		return FileChange{}, fmt.Errorf("a file header has no path")
+
+	case p.oldPath == "":
+		change.Action = ActionCreate
+		if change.full, err = w.writablePath(p.newPath); err != nil {
+			return FileChange{}, err
+		}
+		if _, err := os.Lstat(change.full); err == nil {
+			return FileChange{}, fmt.Errorf("%s already exists", w.Rel(change.full))
+		}
+		if change.after, err = applyHunks("", p.hunks); err != nil {
+			return FileChange{}, fmt.Errorf("%s: %v", w.Rel(change.full), err)
+		}
+
+	case p.newPath == "":
+		change.Action = ActionDelete
+		if change.full, err = w.writablePath(p.oldPath); err != nil {
+			return FileChange{}, err
+		}
+		if change.before, err = readText(change.full, w.Rel(change.full)); err != nil {
+			return FileChange{}, err
+		}
+
+	default:
+		change.Action = ActionUpdate
+		if change.full, err = w.writablePath(p.newPath); err != nil {
+			return FileChange{}, err
+		}
+		source := change.full
+		if p.oldPath != p.newPath {
+			if source, err = w.writablePath(p.oldPath); err != nil {
+				return FileChange{}, err
+			}
+			if _, err := os.Lstat(change.full); err == nil {
+				return FileChange{}, fmt.Errorf("cannot rename to %s, which already exists", w.Rel(change.full))
+			}
+			change.oldFull = source
+			change.OldPath = w.Rel(source)
+		}
+		if info, err := os.Stat(source); err == nil {
+			change.mode = info.Mode().Perm()
+		}
+		if change.before, err = readText(source, w.Rel(source)); err != nil {
+			return FileChange{}, err
+		}
+		if change.after, err = applyHunks(change.before, p.hunks); err != nil {
+			return FileChange{}, fmt.Errorf("%s: %v", w.Rel(source), err)
+		}
+	}
+	change.Path = w.Rel(change.full)
+	return change, nil
+}
+
+// writablePath resolves a path the patch may write to.
+func (w *Workspace) writablePath(path string) (string, error) {
+	full, err := w.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	rel := w.Rel(full)
+	if rel == "." {
+		return "", fmt.Errorf("the patch names the workspace folder itself")
+	}
+	if rel == ".git" || strings.HasPrefix(rel, ".git/") {
+		return "", fmt.Errorf("%s: files under .git cannot be changed", rel)
+	}
+	return full, nil
+}
+
+// ApplyChanges writes prepared changes. Files are checked first, and nothing
+// is written if any of them changed since the patch was prepared.
+func (w *Workspace) ApplyChanges(changes []FileChange) error {
+	for _, change := range changes {
+		switch change.Action {
+		case ActionCreate:
+			if _, err := os.Lstat(change.full); err == nil {
+				return fmt.Errorf("%s was created since the patch was prepared", change.Path)
+			}
+		default:
+			source := change.full
+			if change.oldFull != "" {
+				source = change.oldFull
+			}
+			current, err := readText(source, w.Rel(source))
+			if err != nil {
+				return err
+			}
+			if current != change.before {
+				return fmt.Errorf("%s changed since the patch was prepared", w.Rel(source))
+			}
+		}
+	}
+
+	for _, change := range changes {
+		var err error
+		switch change.Action {
+		case ActionDelete:
+			err = os.Remove(change.full)
+		default:
+			err = writeFileAtomic(change.full, change.after, change.mode)
+			if err == nil && change.oldFull != "" {
+				err = os.Remove(change.oldFull)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes a file through a temp file in the same directory,
+// creating missing parent directories.
+func writeFileAtomic(path string, content string, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, []byte(content), mode)
+}
+
+// parsePatch splits a unified diff into file patches. Text outside the
+// file sections, such as "diff --git" and "index" lines, is ignored.
+func parsePatch(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var patches []filePatch
+	var current *filePatch
+	var currentHunk *hunk
+	// Blank lines are context lines whose space was lost, unless they end a hunk
+	blanks := 0
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldPath, newPath := patchPaths(line[4:], lines[i+1][4:])
+			patches = append(patches, filePatch{oldPath: oldPath, newPath: newPath, diff: []string{line, lines[i+1]}})
+			current, currentHunk, blanks = &patches[len(patches)-1], nil, 0
+			i++
+
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("hunk without a ---/+++ file header")
+			}
+			h := hunk{oldStart: -1}
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				h.oldStart, _ = strconv.Atoi(m[1])
+			}
+			current.hunks = append(current.hunks, h)
+			current.diff = append(current.diff, line)
+			currentHunk, blanks = &current.hunks[len(current.hunks)-1], 0
+
+		case currentHunk != nil && line == "":
+			blanks++
+
+		case currentHunk != nil && strings.ContainsRune(" +-\\", rune(line[0])):
+			for ; blanks > 0; blanks-- {
+				currentHunk.lines = append(currentHunk.lines, hunkLine{op: ' '})
+				current.diff = append(current.diff, "")
+			}
+			current.diff = append(current.diff, line)
+			if line[0] == '\\' {
+				// "\ No newline at end of file" applies to the line before it
+				if n := len(currentHunk.lines); n > 0 && currentHunk.lines[n-1].op != '-' {
+					currentHunk.noNewlineNew = true
+				}
+				continue
+			}
+			currentHunk.lines = append(currentHunk.lines, hunkLine{op: line[0], text: line[1:]})
+
+		default:
+			currentHunk, blanks = nil, 0
+		}
+	}
+
+	for _, p := range patches {
+		if len(p.hunks) == 0 && p.oldPath != "" && p.newPath != "" && p.oldPath == p.newPath {
+			return nil, fmt.Errorf("the patch for %s has no hunks", p.newPath)
+		}
+	}
+	return patches, nil
+}
+
+// patchPaths reads the paths of a file header, dropping timestamps and the
+// a/ and b/ prefixes git adds.
+func patchPaths(oldField string, newField string) (string, string) {
+	clean := func(field string) string {
+		field, _, _ = strings.Cut(field, "\t")
+		field = strings.TrimSpace(field)
+		if field == "/dev/null" {
+			return ""
+		}
+		return field
+	}
+	oldPath, newPath := clean(oldField), clean(newField)
+	if (oldPath == "" || strings.HasPrefix(oldPath, "a/")) && (newPath == "" || strings.HasPrefix(newPath, "b/")) {
+		oldPath = strings.TrimPrefix(oldPath, "a/")
+		newPath = strings.TrimPrefix(newPath, "b/")
+	}
+	return oldPath, newPath
+}
+
+// applyHunks applies hunks to a file's content in order. Line endings and
+// the final newline of the file are kept.
+func applyHunks(content string, hunks []hunk) (string, error) {
+	crlf := strings.Contains(content, "\r\n")
+	if crlf {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	finalNewline := content == "" || strings.HasSuffix(content, "\n")
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	cursor, offset := 0, 0
+	for n, h := range hunks {
+		var old []string
+		for _, l := range h.lines {
+			if l.op != '+' {
+				old = append(old, l.text)
+			}
+		}
+
+		var pos int
+		switch {
+		case len(old) == 0 && h.oldStart < 0:
+			pos = len(lines)
+		case len(old) == 0:
+			// "-5,0" inserts after line 5
+			pos = min(max(h.oldStart+offset, cursor), len(lines))
+		default:
+			want := cursor
+			if h.oldStart > 0 {
+				want = h.oldStart - 1 + offset
+			}
+			pos = findLines(lines, old, cursor, want)
+			if pos < 0 {
+				return "", fmt.Errorf("hunk %d does not match the file; read the file again and resend the patch", n+1)
+			}
+		}
+
+		// Context lines keep the file's text, which may differ in whitespace
+		var replacement []string
+		k := pos
+		for _, l := range h.lines {
+			switch l.op {
+			case ' ':
+				replacement = append(replacement, lines[k])
+				k++
+			case '-':
+				k++
+			case '+':
+				replacement = append(replacement, l.text)
+			}
+		}
+		lines = slices.Replace(lines, pos, pos+len(old), replacement...)
+		offset += len(replacement) - len(old)
+		cursor = pos + len(replacement)
+		if cursor == len(lines) && len(h.lines) > 0 {
+			// A hunk ending the file says whether it ends with a newline
+			finalNewline = !h.noNewlineNew
+		}
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	newline := "\n"
+	if crlf {
+		newline = "\r\n"
+	}
+	result := strings.Join(lines, newline)
+	if finalNewline {
+		result += newline
+	}
+	return result, nil
+}
+
+// findLines finds old within lines at or after from, preferring the match
+// closest to want. Exact matches win over ones that differ in trailing
+// whitespace, which win over ones that differ in any surrounding whitespace.
+func findLines(lines []string, old []string, from int, want int) int {
+	matchers := []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") },
+		func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) },
+	}
+	for _, equal := range matchers {
+		best := -1
+		for pos := from; pos+len(old) <= len(lines); pos++ {
+			matched := true
+			for i := range old {
+				if !equal(lines[pos+i], old[i]) {
+					matched = false
+					break
+				}
+			}
+			if matched && (best < 0 || distance(pos, want) < distance(best, want)) {
+				best = pos
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+func distance(a int, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,268 @@
+// Package workspace gives models sandboxed access to the files of a folder
+// the user has opened: reading, listing and searching them, and changing
+// them through patches. Paths are relative to the workspace root and may not
+// lead out of it, through ".." or symbolic links.
+package workspace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"myproject/ingest"
+)
+
+// Limits on what a single tool call reads or returns.
+const (
+	maxFileBytes    = 4 << 20
+	maxReadLines    = 2000
+	maxLineLength   = 2000
+	maxListEntries  = 500
+	maxGrepMatches  = 200
+	maxGrepLineText = 300
+)
+
+// ErrOutside is returned for paths that lead out of the workspace.
+var ErrOutside = errors.New("path is outside the workspace")
+
+// Workspace is an opened workspace folder.
+type Workspace struct {
+	root string
+}
+
+// Open opens the folder at root as a workspace.
+func Open(root string) (*Workspace, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace path: %w", err)
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", abs)
+	}
+	return &Workspace{root: real}, nil
+}
+
+// Root returns the absolute path of the workspace folder.
+func (w *Workspace) Root() string {
+	return w.root
+}
+
+// Resolve turns a workspace path into an absolute one. Relative paths are
+// taken from the root; absolute paths must point inside it.
+func (w *Workspace) Resolve(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	full := w.root
+	if filepath.IsAbs(path) {
+		full = filepath.Clean(path)
+	} else if path != "" {
+		full = filepath.Join(w.root, filepath.FromSlash(path))
+	}
+	if !w.contains(full) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutside)
+	}
+	real, err := evalExisting(full)
+	if err != nil {
+		return "", err
+	}
+	if !w.contains(real) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutside)
+	}
+	return full, nil
+}
+
+// Rel returns the slash-separated workspace path of an absolute path.
+func (w *Workspace) Rel(full string) string {
+	rel, err := filepath.Rel(w.root, full)
+	if err != nil || rel == "." {
+		return "."
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (w *Workspace) contains(path string) bool {
+	return path == w.root || strings.HasPrefix(path, w.root+string(filepath.Separator))
+}
+
+// evalExisting resolves the symbolic links of the part of path that exists,
+// so that files about to be created are checked through their parent.
+func evalExisting(path string) (string, error) {
+	var rest []string
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+// skipDir reports whether listings and searches leave out a directory:
+// hidden ones such as .git, and dependency and build output.
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || ingest.IgnoredDir(name)
+}
+
+// readText reads a text file of the workspace, rejecting large and binary files.
+func readText(full string, rel string) (string, error) {
+	info, err := os.Stat(full)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", rel)
+	}
+	if info.Size() > maxFileBytes {
+		return "", fmt.Errorf("%s is too large (%d bytes)", rel, info.Size())
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", err
+	}
+	if isBinary(data) {
+		return "", fmt.Errorf("%s is a binary file", rel)
+	}
+	return string(data), nil
+}
+
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+// ReadFile returns lines start to end of a text file, numbered from 1, or
+// as many as the line limit allows. Zero start and end read from the
+// beginning.
+func (w *Workspace) ReadFile(path string, start int, end int) (string, error) {
+	full, err := w.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	rel := w.Rel(full)
+	text, err := readText(full, rel)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(text, "\n")
+	if strings.HasSuffix(text, "\n") {
+		lines = lines[:len(lines)-1]
+	}
+	if start < 1 {
+		start = 1
+	}
+	if end < start || end > len(lines) {
+		end = len(lines)
+	}
+	if end-start+1 > maxReadLines {
+		end = start + maxReadLines - 1
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf("%s is empty\n", rel), nil
+	}
+	if start > len(lines) {
+		return "", fmt.Errorf("%s has only %d lines", rel, len(lines))
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s (lines %d-%d of %d)\n", rel, start, end, len(lines))
+	for i := start; i <= end; i++ {
+		line := lines[i-1]
+		if len(line) > maxLineLength {
+			line = strings.ToValidUTF8(line[:maxLineLength], "") + " [line truncated]"
+		}
+		fmt.Fprintf(&out, "%d\t%s\n", i, line)
+	}
+	if end < len(lines) {
+		fmt.Fprintf(&out, "[%d more lines; continue with start_line %d]\n", len(lines)-end, end+1)
+	}
+	return out.String(), nil
+}
+
+// ListDir lists a directory, directories first with a trailing slash and
+// files with their size. A recursive listing descends into subdirectories
+// except hidden and dependency ones.
+func (w *Workspace) ListDir(path string, recursive bool) (string, error) {
+	full, err := w.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", w.Rel(full))
+	}
+
+	var entries []string
+	truncated := false
+	var list func(dir string) error
+	list = func(dir string) error {
+		items, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].IsDir() && !items[j].IsDir()
+		})
+		for _, item := range items {
+			if len(entries) == maxListEntries {
+				truncated = true
+				return nil
+			}
+			itemPath := filepath.Join(dir, item.Name())
+			rel := w.Rel(itemPath)
+			if item.IsDir() {
+				if recursive && skipDir(item.Name()) {
+					entries = append(entries, rel+"/ (not listed)")
+					continue
+				}
+				entries = append(entries, rel+"/")
+				if recursive {
+					if err := list(itemPath); err != nil {
+						entries = append(entries, rel+"/: "+err.Error())
+					}
+				}
+				continue
+			}
+			size := ""
+			if info, err := item.Info(); err == nil && info.Mode().IsRegular() {
+				size = fmt.Sprintf(" (%d bytes)", info.Size())
+			}
+			entries = append(entries, rel+size)
+		}
+		return nil
+	}
+	if err := list(full); err != nil {
+		return "", err
+	}
+
+	if len(entries) == 0 {
+		return fmt.Sprintf("%s is empty\n", w.Rel(full)), nil
+	}
+	out := strings.Join(entries, "\n") + "\n"
+	if truncated {
+		out += fmt.Sprintf("[listing stopped after %d entries]\n", maxListEntries)
+	}
+	return out, nil
+}